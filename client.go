@@ -89,6 +89,20 @@ func ErrorIsAlreadyExists(err error) bool {
 	return ok
 }
 
+// ErrNotImplemented is an error returned by connectors for the operations their
+// backend does not support
+type ErrNotImplemented struct{}
+
+func (*ErrNotImplemented) Error() string {
+	return "not implemented"
+}
+
+// ErrorIsNotImplemented checks if the error is caused by "ErrNotImplemented"
+func ErrorIsNotImplemented(err error) bool {
+	_, ok := errors.Cause(err).(*ErrNotImplemented)
+	return ok
+}
+
 // Client defines the methods to operate with DOSA entities
 type Client interface {
 	// Initialize must be called before any data operation
@@ -124,6 +138,12 @@ type Client interface {
 	// update can be specified. Use All() or nil for all fields.
 	// MultiUpsert(context.Context, []string, ...DomainObject) (MultiResult, error)
 
+	// Increment atomically adds delta to a counter field, creating the row
+	// if it doesn't exist. Before calling this method, fill in the DomainObject
+	// with ALL of the primary key fields; the counter field will be set to its
+	// new value. The connector must be an Incrementer.
+	Increment(ctx context.Context, objectToUpdate DomainObject, fieldName string, delta int64) error
+
 	// Remove removes a row by primary key. The passed-in entity should contain
 	// the primary key field values, all other fields are ignored.
 	Remove(ctx context.Context, objectToRemove DomainObject) error
@@ -152,6 +172,12 @@ type Client interface {
 	// To scan the next set of rows, modify the scanOp to provide
 	// the string returned as an Offset()
 	ScanEverything(ctx context.Context, scanOp *ScanOp) ([]DomainObject, string, error)
+
+	// Watch streams the changes made to entities of the same type as the one
+	// provided, until the context is done. The events hold entities of that
+	// type too. The connector must be a Watcher; other connectors can be
+	// wrapped with the watch connector to see the changes made through them.
+	Watch(ctx context.Context, entity DomainObject, opts *WatchOptions) (<-chan *ChangeEvent, error)
 }
 
 // MultiResult contains the result for each entity operation in the case of
@@ -180,6 +206,10 @@ type AdminClient interface {
 	UpsertSchema(ctx context.Context, namePrefix string) (*SchemaStatus, error)
 	// GetSchema finds entity definitions
 	GetSchema() ([]*EntityDefinition, error)
+	// GetStoredSchema returns the entity definitions of an upserted schema version, or of the latest one for LatestVersion
+	GetStoredSchema(ctx context.Context, namePrefix string, version int32) ([]*EntityDefinition, error)
+	// ListSchemaVersions returns the status of every upserted schema version, oldest first
+	ListSchemaVersions(ctx context.Context, namePrefix string) ([]*SchemaStatus, error)
 	// CreateScope creates a new scope
 	CreateScope(ctx context.Context, s string) error
 	// TruncateScope keeps the scope and the schemas, but drops the data associated with the scope
@@ -259,7 +289,10 @@ func (c *client) Read(ctx context.Context, fieldsToRead []string, entity DomainO
 
 	// translate entity field values to a map of primary key name/values pairs
 	// required to perform a read
-	fieldValues := re.KeyFieldValues(entity)
+	fieldValues, err := re.KeyFieldValues(entity)
+	if err != nil {
+		return err
+	}
 
 	// build a list of column names from a list of entities field names
 	columnsToRead, err := re.ColumnNames(fieldsToRead)
@@ -273,9 +306,7 @@ func (c *client) Read(ctx context.Context, fieldsToRead []string, entity DomainO
 	}
 
 	// map results to entity fields
-	re.SetFieldValues(entity, results, columnsToRead)
-
-	return nil
+	return re.SetFieldValues(entity, results, columnsToRead)
 }
 
 // MultiRead fetches several entities by primary key, The entities provided
@@ -309,7 +340,10 @@ func (c *client) createOrUpsert(ctx context.Context, fieldsToUpdate []string, en
 	}
 
 	// translate entity field values to a map of primary key name/values pairs
-	keyFieldValues := re.KeyFieldValues(entity)
+	keyFieldValues, err := re.KeyFieldValues(entity)
+	if err != nil {
+		return err
+	}
 
 	// translate remaining entity fields values to map of column name/value pairs
 	fieldValues, err := re.OnlyFieldValues(entity, fieldsToUpdate)
@@ -333,6 +367,40 @@ func (c *client) MultiUpsert(context.Context, []string, ...DomainObject) (MultiR
 	panic("not implemented")
 }
 
+// Increment adds delta to a counter field of an entity, The entity provided must
+// contain values for all components of its primary key for the operation to
+// succeed. The field is set to the value of the counter after the increment.
+func (c *client) Increment(ctx context.Context, entity DomainObject, fieldName string, delta int64) error {
+	if !c.initialized {
+		return &ErrNotInitialized{}
+	}
+	re, err := c.registrar.Find(entity)
+	if err != nil {
+		return errors.Wrap(err, "Increment")
+	}
+	columnNames, err := re.ColumnNames([]string{fieldName})
+	if err != nil {
+		return errors.Wrap(err, "Increment")
+	}
+	column := columnNames[0]
+	if re.info.Def.FindColumnDefinition(column).Type != Counter {
+		return errors.Errorf("Increment: %s is not a counter field of %s", fieldName, re.table.StructName)
+	}
+	incrementer, ok := c.connector.(Incrementer)
+	if !ok {
+		return errors.Errorf("Increment: connector %T cannot increment counters", c.connector)
+	}
+	keyFieldValues, err := re.KeyFieldValues(entity)
+	if err != nil {
+		return errors.Wrap(err, "Increment")
+	}
+	value, err := incrementer.Increment(ctx, re.info, keyFieldValues, column, delta)
+	if err != nil {
+		return errors.Wrap(err, "Increment")
+	}
+	return errors.Wrap(re.SetFieldValues(entity, map[string]FieldValue{column: value}, columnNames), "Increment")
+}
+
 // Remove deletes an entity by primary key, The entity provided must contain
 // values for all components of its primary key for the operation to succeed.
 func (c *client) Remove(ctx context.Context, entity DomainObject) error {
@@ -348,7 +416,10 @@ func (c *client) Remove(ctx context.Context, entity DomainObject) error {
 	}
 
 	// translate entity field values to a map of primary key name/values pairs
-	keyFieldValues := re.KeyFieldValues(entity)
+	keyFieldValues, err := re.KeyFieldValues(entity)
+	if err != nil {
+		return err
+	}
 
 	err = c.connector.Remove(ctx, re.EntityInfo(), keyFieldValues)
 	return err
@@ -390,22 +461,28 @@ func (c *client) Range(ctx context.Context, r *RangeOp) ([]DomainObject, string,
 		return nil, "", errors.Wrap(err, "Range")
 	}
 
-	objectArray := objectsFromValueArray(r.sop.object, values, re, nil)
+	objectArray, err := objectsFromValueArray(r.sop.object, values, re, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Range")
+	}
 	return objectArray, token, nil
 }
 
-func objectsFromValueArray(object DomainObject, values []map[string]FieldValue, re *RegisteredEntity, columnsToRead []string) []DomainObject {
+func objectsFromValueArray(object DomainObject, values []map[string]FieldValue, re *RegisteredEntity, columnsToRead []string) ([]DomainObject, error) {
 	goType := reflect.TypeOf(object).Elem() // get the reflect.Type of the client entity
 	doType := reflect.TypeOf((*DomainObject)(nil)).Elem()
 	slice := reflect.MakeSlice(reflect.SliceOf(doType), 0, len(values)) // make a slice of these
 	elements := reflect.New(slice.Type())
 	elements.Elem().Set(slice)
 	for _, flist := range values { // for each row returned
-		newObject := reflect.New(goType).Interface()                             // make a new entity
-		re.SetFieldValues(newObject.(DomainObject), flist, columnsToRead)        // fill it in from server values
+		newObject := reflect.New(goType).Interface() // make a new entity
+		// fill it in from server values
+		if err := re.SetFieldValues(newObject.(DomainObject), flist, columnsToRead); err != nil {
+			return nil, err
+		}
 		slice = reflect.Append(slice, reflect.ValueOf(newObject.(DomainObject))) // append to slice
 	}
-	return slice.Interface().([]DomainObject)
+	return slice.Interface().([]DomainObject), nil
 }
 
 // Search uses the connector to fetch DOSA entities by fields that have been marked "searchable".
@@ -434,11 +511,61 @@ func (c *client) ScanEverything(ctx context.Context, sop *ScanOp) ([]DomainObjec
 	if err != nil {
 		return nil, "", err
 	}
-	objectArray := objectsFromValueArray(sop.object, values, re, nil)
+	objectArray, err := objectsFromValueArray(sop.object, values, re, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "ScanEverything")
+	}
 	return objectArray, token, nil
 
 }
 
+// Watch uses the connector to stream the changes made to DOSA entities of the given type.
+func (c *client) Watch(ctx context.Context, entity DomainObject, opts *WatchOptions) (<-chan *ChangeEvent, error) {
+	if !c.initialized {
+		return nil, &ErrNotInitialized{}
+	}
+	re, err := c.registrar.Find(entity)
+	if err != nil {
+		return nil, errors.Wrap(err, "Watch")
+	}
+	watcher, ok := c.connector.(Watcher)
+	if !ok {
+		return nil, errors.Errorf("Watch: connector %T cannot watch changes", c.connector)
+	}
+	changes, err := watcher.Watch(ctx, re.info, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Watch")
+	}
+
+	events := make(chan *ChangeEvent, cap(changes))
+	toObject := func(values map[string]FieldValue, event *ChangeEvent) DomainObject {
+		objects, err := objectsFromValueArray(entity, []map[string]FieldValue{values}, re, nil)
+		if err != nil {
+			event.Err = errors.Wrap(err, "Watch")
+			return nil
+		}
+		return objects[0]
+	}
+	go func() {
+		defer close(events)
+		for change := range changes {
+			event := &ChangeEvent{Type: change.Type, Err: change.Err}
+			if change.Before != nil {
+				event.Before = toObject(change.Before, event)
+			}
+			if change.After != nil {
+				event.After = toObject(change.After, event)
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
 type adminClient struct {
 	scope     string
 	dirs      []string
@@ -556,6 +683,26 @@ func (c *adminClient) GetSchema() ([]*EntityDefinition, error) {
 	return defs, nil
 }
 
+// GetStoredSchema returns the entity definitions of a schema version as
+// stored by the connector, unlike GetSchema which finds them in the source.
+func (c *adminClient) GetStoredSchema(ctx context.Context, namePrefix string, version int32) ([]*EntityDefinition, error) {
+	defs, err := c.connector.GetSchema(ctx, c.scope, namePrefix, version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetSchema failed, scope: %s", c.scope)
+	}
+	return defs, nil
+}
+
+// ListSchemaVersions returns the status of every version of the schema
+// upserted for the name prefix in the client's scope.
+func (c *adminClient) ListSchemaVersions(ctx context.Context, namePrefix string) ([]*SchemaStatus, error) {
+	statuses, err := c.connector.ListSchemaVersions(ctx, c.scope, namePrefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "ListSchemaVersions failed, scope: %s", c.scope)
+	}
+	return statuses, nil
+}
+
 // EntityErrors is a container for parse errors/warning.
 type EntityErrors struct {
 	warns []error
@@ -73,12 +73,14 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, c)
 	assert.NoError(t, err)
 
+	// a new memory connector has no scopes, so the schema check fails
 	memoryCfg := config.NewDefaultConfig()
 	memoryCfg.Connector["name"] = "memory"
 	memoryCfg.EntityPaths = entityPathsValid
 	c, err = dosaclient.New(&memoryCfg)
-	assert.NotNil(t, c)
-	assert.NoError(t, err)
+	assert.Nil(t, c)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CheckSchema failed")
 
 	randomCfg := config.NewDefaultConfig()
 	randomCfg.Connector["name"] = "random"
@@ -35,7 +35,7 @@ import (
 
 	dosaRenamed "github.com/uber-go/dosa"
 	_ "github.com/uber-go/dosa/connectors/devnull"
-	_ "github.com/uber-go/dosa/connectors/memory"
+	"github.com/uber-go/dosa/connectors/memory"
 	"github.com/uber-go/dosa/mocks"
 )
 
@@ -54,6 +54,13 @@ type ClientTestEntity2 struct {
 	ignoreme           int32
 }
 
+type ClientTestNullable struct {
+	dosaRenamed.Entity `dosa:"primaryKey=(ID)"`
+	ID                 int64
+	Name               *string
+	Age                *int
+}
+
 var (
 	cte1          = &ClientTestEntity1{ID: int64(1), Name: "foo", Email: "foo@uber.com"}
 	cte2          = &ClientTestEntity2{UUID: "b1f23fa3-f453-45b4-a5d5-6d73078ac3bd", Color: "blue", IsActive: true}
@@ -100,8 +107,8 @@ func ExampleNewClient() {
 // your "database" before reading them
 func ExampleGetConnector() {
 	// register your entities so the engine can separate your data based on table names.
-	// Scopes and prefixes are not used by the in-memory connector, and are ignored, but
-	// your list of entities is important. In this case, we only have one, our ClientTestEntity1
+	// The in-memory connector keeps the data of each scope and prefix apart, just like
+	// a real database would. In this case, we only have one entity, our ClientTestEntity1
 	reg, err := dosaRenamed.NewRegistrar("test", "myteam.myservice", &ClientTestEntity1{})
 	if err != nil {
 		fmt.Printf("NewRegistrar error: %s", err)
@@ -113,6 +120,21 @@ func ExampleGetConnector() {
 	// when we imported memory in the import list, with an underscore to just get the side effects
 	conn, _ := dosaRenamed.GetConnector("memory", nil)
 
+	// the scope must exist, and the schema must be upserted, before any data can be written
+	if err = conn.CreateScope(context.Background(), "test"); err != nil {
+		fmt.Printf("CreateScope error: %s", err)
+		return
+	}
+	entities, _ := reg.FindAll()
+	defs := make([]*dosaRenamed.EntityDefinition, len(entities))
+	for i, re := range entities {
+		defs[i] = re.EntityDefinition()
+	}
+	if _, err = conn.UpsertSchema(context.Background(), "test", "myteam.myservice", defs); err != nil {
+		fmt.Printf("UpsertSchema error: %s", err)
+		return
+	}
+
 	// now construct a client from the registry and the connector
 	client := dosaRenamed.NewClient(reg, conn)
 
@@ -634,6 +656,38 @@ func TestAdminClient_CheckSchemaStatus(t *testing.T) {
 	}
 }
 
+func TestAdminClient_GetStoredSchema(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockConn := mocks.NewMockConnector(ctrl)
+	eds := []*dosaRenamed.EntityDefinition{{Name: "foo"}}
+	mockConn.EXPECT().GetSchema(ctx, scope, "error", dosaRenamed.LatestVersion).Return(nil, errors.New("connector error")).Times(1)
+	mockConn.EXPECT().GetSchema(ctx, scope, namePrefix, int32(2)).Return(eds, nil).Times(1)
+
+	client := dosaRenamed.NewAdminClient(mockConn).Scope(scope)
+	_, err := client.GetStoredSchema(ctx, "error", dosaRenamed.LatestVersion)
+	assert.Contains(t, err.Error(), "connector error")
+	found, err := client.GetStoredSchema(ctx, namePrefix, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, eds, found)
+}
+
+func TestAdminClient_ListSchemaVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockConn := mocks.NewMockConnector(ctrl)
+	statuses := []*dosaRenamed.SchemaStatus{{Version: 1, Status: "COMPLETED"}}
+	mockConn.EXPECT().ListSchemaVersions(ctx, scope, "error").Return(nil, errors.New("connector error")).Times(1)
+	mockConn.EXPECT().ListSchemaVersions(ctx, scope, namePrefix).Return(statuses, nil).Times(1)
+
+	client := dosaRenamed.NewAdminClient(mockConn).Scope(scope)
+	_, err := client.ListSchemaVersions(ctx, "error")
+	assert.Contains(t, err.Error(), "connector error")
+	found, err := client.ListSchemaVersions(ctx, namePrefix)
+	assert.NoError(t, err)
+	assert.Equal(t, statuses, found)
+}
+
 func TestAdminClient_UpsertSchema(t *testing.T) {
 	// write some entities to disk
 	tmpdir := ".testupsertschema"
@@ -809,3 +863,38 @@ func TestErrorIsAlreadyExists(t *testing.T) {
 	assert.True(t, dosaRenamed.ErrorIsAlreadyExists(errors.Wrap(&dosaRenamed.ErrAlreadyExists{}, "wrapped")))
 	assert.Equal(t, "already exists", (&dosaRenamed.ErrAlreadyExists{}).Error())
 }
+
+func TestErrorIsNotImplemented(t *testing.T) {
+	assert.False(t, dosaRenamed.ErrorIsNotImplemented(errors.New("not a not implemented error")))
+	assert.False(t, dosaRenamed.ErrorIsNotImplemented(&dosaRenamed.ErrNotFound{}))
+	assert.True(t, dosaRenamed.ErrorIsNotImplemented(errors.Wrap(&dosaRenamed.ErrNotImplemented{}, "wrapped")))
+	assert.Equal(t, "not implemented", (&dosaRenamed.ErrNotImplemented{}).Error())
+}
+
+func TestClient_NullableFields(t *testing.T) {
+	reg, err := dosaRenamed.NewRegistrar(scope, namePrefix, &ClientTestNullable{})
+	assert.NoError(t, err)
+	conn := memory.NewConnector()
+	assert.NoError(t, conn.CreateScope(ctx, scope))
+	re, err := reg.Find(&ClientTestNullable{})
+	assert.NoError(t, err)
+	_, err = conn.UpsertSchema(ctx, scope, namePrefix, []*dosaRenamed.EntityDefinition{re.EntityDefinition()})
+	assert.NoError(t, err)
+	c := dosaRenamed.NewClient(reg, conn)
+	assert.NoError(t, c.Initialize(ctx))
+
+	name, age := "foo", 42
+	assert.NoError(t, c.Upsert(ctx, dosaRenamed.All(), &ClientTestNullable{ID: 1, Name: &name, Age: &age}))
+	read := &ClientTestNullable{ID: 1}
+	assert.NoError(t, c.Read(ctx, dosaRenamed.All(), read))
+	assert.Equal(t, &ClientTestNullable{ID: 1, Name: &name, Age: &age}, read)
+
+	// fields that are not updated keep their values, nil pointers set nulls
+	assert.NoError(t, c.Upsert(ctx, []string{"Name"}, &ClientTestNullable{ID: 1}))
+	row, err := conn.Read(ctx, re.EntityInfo(), map[string]dosaRenamed.FieldValue{"id": int64(1)}, dosaRenamed.All())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosaRenamed.FieldValue{"id": int64(1), "name": nil, "age": int64(42)}, row)
+	read = &ClientTestNullable{ID: 1, Name: &name}
+	assert.NoError(t, c.Read(ctx, dosaRenamed.All(), read))
+	assert.Equal(t, &ClientTestNullable{ID: 1, Age: &age}, read)
+}
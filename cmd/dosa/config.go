@@ -0,0 +1,202 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/config"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// _configFileName is the name of the client config file looked up from the
+// current directory to the root
+const _configFileName = ".dosa.yaml"
+
+// _storedSchemaCommands are the schema commands whose --scope and --prefix
+// name the stored schema of the client, which the config file sets. Other
+// commands use them differently, like schema dump for the avro namespace, or
+// serve for the scopes to create.
+var _storedSchemaCommands = []string{"check", "upsert", "status", "show", "history", "plan", "diff"}
+
+// configConnector holds the connector settings of the client config file,
+// which are passed to the connector they name
+var configConnector dosa.CreationArgs
+
+// ClientConfig is the content of a client config file: the fields of
+// config.Config, and named profiles overriding some of them. JSON documents
+// are valid YAML, so the file can be written in either.
+type ClientConfig struct {
+	config.Config `yaml:",inline"`
+	Profiles      map[string]config.Config `yaml:"profiles"`
+}
+
+// findConfigFile returns the path of the first client config file found in
+// the directory or one of its parents, or "" if there is none
+func findConfigFile(dir string) string {
+	for {
+		path := filepath.Join(dir, _configFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadConfig reads a client config file and returns its settings, overridden
+// by those of the profile when one is given
+func loadConfig(path, profile string) (*config.Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cc ClientConfig
+	if err := yaml.Unmarshal(data, &cc); err != nil {
+		return nil, errors.Wrapf(err, "could not decode %s", path)
+	}
+	if profile == "" {
+		return &cc.Config, nil
+	}
+	p, ok := cc.Profiles[profile]
+	if !ok {
+		return nil, errors.Errorf("no profile %q in %s", profile, path)
+	}
+	return mergeConfig(cc.Config, p), nil
+}
+
+// mergeConfig returns the base config with the fields set in the profile
+// replaced
+func mergeConfig(base, profile config.Config) *config.Config {
+	merged := base
+	override(&merged.Scope, profile.Scope)
+	override(&merged.NamePrefix, profile.NamePrefix)
+	override(&merged.Yarpc.Transport, profile.Yarpc.Transport)
+	override(&merged.Yarpc.Host, profile.Yarpc.Host)
+	override(&merged.Yarpc.Port, profile.Yarpc.Port)
+	override(&merged.Yarpc.CallerName, profile.Yarpc.CallerName)
+	override(&merged.Yarpc.ServiceName, profile.Yarpc.ServiceName)
+	if profile.EntityPaths != nil {
+		merged.EntityPaths = profile.EntityPaths
+	}
+	if profile.Excludes != nil {
+		merged.Excludes = profile.Excludes
+	}
+	if profile.Connector != nil {
+		merged.Connector = profile.Connector
+	}
+	if profile.Yarpc.ClientConfig != nil {
+		merged.Yarpc.ClientConfig = profile.Yarpc.ClientConfig
+	}
+	if profile.Timeout != nil {
+		merged.Timeout = profile.Timeout
+	}
+	return &merged
+}
+
+// override replaces a setting when the new value is not empty
+func override(into *string, from string) {
+	if from != "" {
+		*into = from
+	}
+}
+
+// applyConfigFile makes the settings of the client config file, and of its
+// profile, the defaults of the matching options of the parser. The file and
+// profile are given by the --config and --profile flags or their environment
+// variables, which are looked up in the arguments before they are parsed.
+// Flags then take precedence over environment variables, which take
+// precedence over the config file.
+func applyConfigFile(parser *flags.Parser, args []string) error {
+	configConnector = nil
+	var pre GlobalOptions
+	// errors are reported when parsing the arguments for real
+	_, _ = flags.NewParser(&pre, flags.IgnoreUnknown).ParseArgs(args)
+
+	path := pre.Config
+	if path == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			path = findConfigFile(cwd)
+		}
+	}
+	if path == "" {
+		if pre.Profile != "" {
+			return errors.Errorf("profile %q requires a %s config file", pre.Profile, _configFileName)
+		}
+		return nil
+	}
+
+	cfg, err := loadConfig(path, pre.Profile)
+	if err != nil {
+		return err
+	}
+	defaults := map[string][]string{
+		"host":      {cfg.Yarpc.Host},
+		"port":      {cfg.Yarpc.Port},
+		"transport": {cfg.Yarpc.Transport},
+		"service":   {cfg.Yarpc.ServiceName},
+		"caller":    {cfg.Yarpc.CallerName},
+	}
+	if name, ok := cfg.Connector["name"].(string); ok {
+		defaults["connector"] = []string{name}
+		configConnector = cfg.Connector
+	}
+	// the CLI has a single timeout, which bounds its schema operations like
+	// the initialization of a client
+	if cfg.Timeout != nil && cfg.Timeout.Initialize > 0 {
+		defaults["timeout"] = []string{cfg.Timeout.Initialize.String()}
+	}
+	setDefaults(parser.Command, defaults)
+
+	schema := parser.Find("schema")
+	setDefaults(schema, map[string][]string{"exclude": cfg.Excludes})
+	for _, name := range _storedSchemaCommands {
+		setDefaults(schema.Find(name), map[string][]string{
+			"scope":  {cfg.Scope},
+			"prefix": {cfg.NamePrefix},
+		})
+	}
+	return nil
+}
+
+// setDefaults replaces the defaults of the options of a command and of its
+// subcommands, ignoring empty values
+func setDefaults(cmd *flags.Command, defaults map[string][]string) {
+	for name, values := range defaults {
+		if len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if opt := cmd.FindOptionByLongName(name); opt != nil {
+			opt.Default = values
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		setDefaults(sub, defaults)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/devnull"
+)
+
+const testClientConfig = `
+scope: base_scope
+namePrefix: foo
+connector:
+  name: configured
+yarpc:
+  host: base.host
+  port: "1111"
+  transport: http
+profiles:
+  staging:
+    scope: staging_scope
+    yarpc:
+      host: staging.host
+  prod:
+    scope: production
+    namePrefix: bar
+`
+
+// writeClientConfig writes a client config file in a new temporary directory
+func writeClientConfig(t *testing.T, content string) (string, string) {
+	dir, err := ioutil.TempDir("", "dosa-config")
+	assert.NoError(t, err)
+	path := filepath.Join(dir, _configFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return dir, path
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir, path := writeClientConfig(t, testClientConfig)
+	defer os.RemoveAll(dir)
+	nested := filepath.Join(dir, "a", "b")
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+
+	assert.Equal(t, path, findConfigFile(dir))
+	assert.Equal(t, path, findConfigFile(nested))
+
+	// a closer directory with the same name is skipped
+	assert.NoError(t, os.Mkdir(filepath.Join(nested, _configFileName), 0755))
+	assert.Equal(t, path, findConfigFile(nested))
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, path := writeClientConfig(t, testClientConfig)
+	defer os.RemoveAll(dir)
+
+	cfg, err := loadConfig(path, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "base_scope", cfg.Scope)
+	assert.Equal(t, "base.host", cfg.Yarpc.Host)
+	assert.Equal(t, "configured", cfg.Connector["name"])
+
+	// profiles only override the settings they have
+	cfg, err = loadConfig(path, "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "staging_scope", cfg.Scope)
+	assert.Equal(t, "foo", cfg.NamePrefix)
+	assert.Equal(t, "staging.host", cfg.Yarpc.Host)
+	assert.Equal(t, "1111", cfg.Yarpc.Port)
+	assert.Equal(t, "http", cfg.Yarpc.Transport)
+
+	_, err = loadConfig(path, "dev")
+	assert.Contains(t, err.Error(), `no profile "dev"`)
+	_, err = loadConfig(filepath.Join(dir, "nonexistent"), "")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLoadConfig_JSON(t *testing.T) {
+	dir, path := writeClientConfig(t, `{"scope": "base_scope", "profiles": {"dev": {"yarpc": {"port": "2222"}}}}`)
+	defer os.RemoveAll(dir)
+
+	cfg, err := loadConfig(path, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "base_scope", cfg.Scope)
+	assert.Equal(t, "2222", cfg.Yarpc.Port)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("scope: [oops"), 0644))
+	_, err = loadConfig(path, "")
+	assert.Contains(t, err.Error(), "could not decode")
+}
+
+// configuredConnector records the settings it is used with
+type configuredConnector struct {
+	devnull.Connector
+	args  dosa.CreationArgs
+	scope string
+}
+
+func (c *configuredConnector) CheckSchemaStatus(ctx context.Context, scope, namePrefix string, version int32) (*dosa.SchemaStatus, error) {
+	c.scope = scope
+	return c.Connector.CheckSchemaStatus(ctx, scope, namePrefix, version)
+}
+
+func TestConfig_Precedence(t *testing.T) {
+	dir, path := writeClientConfig(t, testClientConfig)
+	defer os.RemoveAll(dir)
+	conn := &configuredConnector{}
+	dosa.RegisterConnector("configured", func(args dosa.CreationArgs) (dosa.Connector, error) {
+		conn.args = args
+		return conn, nil
+	})
+	defer os.Unsetenv("DOSA_HOST")
+
+	cases := []struct {
+		env   string
+		args  []string
+		host  string
+		port  string
+		scope string
+	}{
+		{
+			// the settings of the file
+			args:  []string{"--config", path, "schema", "status"},
+			host:  "base.host",
+			port:  "1111",
+			scope: "base_scope",
+		},
+		{
+			// the profile overrides the file
+			args:  []string{"--config", path, "--profile", "staging", "schema", "status"},
+			host:  "staging.host",
+			port:  "1111",
+			scope: "staging_scope",
+		},
+		{
+			// the environment overrides the profile
+			env:   "env.host",
+			args:  []string{"--config", path, "--profile", "staging", "schema", "status"},
+			host:  "env.host",
+			port:  "1111",
+			scope: "staging_scope",
+		},
+		{
+			// flags override everything
+			env:   "env.host",
+			args:  []string{"--config", path, "--profile", "staging", "--host", "flag.host", "-p", "3333", "schema", "status", "-s", "flag_scope"},
+			host:  "flag.host",
+			port:  "3333",
+			scope: "flag_scope",
+		},
+	}
+	for _, tc := range cases {
+		os.Setenv("DOSA_HOST", tc.env)
+		if tc.env == "" {
+			os.Unsetenv("DOSA_HOST")
+		}
+		c := StartCapture()
+		exit = func(r int) {
+			assert.Equal(t, 0, r, "%v", tc.args)
+		}
+		os.Args = append([]string{"dosa"}, tc.args...)
+		main()
+		assert.Contains(t, c.stop(false), "Version: 1")
+		assert.Equal(t, tc.host, conn.args["host"], "%v", tc.args)
+		assert.Equal(t, tc.port, conn.args["port"], "%v", tc.args)
+		assert.Equal(t, "http", conn.args["transport"], "%v", tc.args)
+		assert.Equal(t, tc.scope, conn.scope, "%v", tc.args)
+	}
+}
+
+func TestConfig_Timeout(t *testing.T) {
+	dir, path := writeClientConfig(t, testClientConfig+`
+  slow:
+    timeout:
+      initialize: 90s
+`)
+	defer os.RemoveAll(dir)
+	dosa.RegisterConnector("configured", func(args dosa.CreationArgs) (dosa.Connector, error) {
+		return &configuredConnector{}, nil
+	})
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+
+	cases := []struct {
+		args    []string
+		timeout time.Duration
+	}{
+		{args: []string{"--config", path, "schema", "status"}, timeout: time.Minute},
+		{args: []string{"--config", path, "--profile", "slow", "schema", "status"}, timeout: 90 * time.Second},
+		{args: []string{"--config", path, "--profile", "slow", "--timeout", "5s", "schema", "status"}, timeout: 5 * time.Second},
+	}
+	for _, tc := range cases {
+		c := StartCapture()
+		os.Args = append([]string{"dosa"}, tc.args...)
+		main()
+		c.stop(false)
+		assert.Equal(t, tc.timeout, options.Timeout.Duration(), "%v", tc.args)
+	}
+}
+
+func TestConfig_ConnectorArgs(t *testing.T) {
+	dir, path := writeClientConfig(t, `
+scope: base_scope
+namePrefix: foo
+connector:
+  name: configured
+  path: /data/schemas.db
+  autoUpsert: true
+yarpc:
+  host: base.host
+`)
+	defer os.RemoveAll(dir)
+	conn := &configuredConnector{}
+	dosa.RegisterConnector("configured", func(args dosa.CreationArgs) (dosa.Connector, error) {
+		conn.args = args
+		return conn, nil
+	})
+	other := &configuredConnector{}
+	dosa.RegisterConnector("other", func(args dosa.CreationArgs) (dosa.Connector, error) {
+		other.args = args
+		return other, nil
+	})
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+
+	// the settings of the connector are passed with those of the flags
+	c := StartCapture()
+	os.Args = []string{"dosa", "--config", path, "--port", "2222", "schema", "status"}
+	main()
+	c.stop(false)
+	assert.Equal(t, "/data/schemas.db", conn.args["path"])
+	assert.Equal(t, true, conn.args["autoUpsert"])
+	assert.Equal(t, "base.host", conn.args["host"])
+	assert.Equal(t, "2222", conn.args["port"])
+	assert.NotContains(t, conn.args, "name")
+
+	// but not to another connector
+	c = StartCapture()
+	os.Args = []string{"dosa", "--config", path, "--connector", "other", "schema", "status"}
+	main()
+	c.stop(false)
+	assert.NotContains(t, other.args, "path")
+	assert.Equal(t, "base.host", other.args["host"])
+}
+
+func TestConfig_DumpScope(t *testing.T) {
+	dir, path := writeClientConfig(t, testClientConfig)
+	defer os.RemoveAll(dir)
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+
+	// the scope and prefix of the config are not the avro namespace of dumps
+	c := StartCapture()
+	os.Args = []string{"dosa", "--config", path, "schema", "dump", "-f", "avro", "-v", "-e", "_test.go", "../../testentity"}
+	main()
+	output := c.stop(false)
+	assert.NotContains(t, output, "base_scope")
+	assert.NotContains(t, output, "NamePrefix:foo")
+}
+
+func TestConfig_Errors(t *testing.T) {
+	dir, path := writeClientConfig(t, testClientConfig)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		args   []string
+		errmsg string
+	}{
+		{
+			args:   []string{"dosa", "--profile", "prod", "schema", "status"},
+			errmsg: `profile "prod" requires a .dosa.yaml config file`,
+		},
+		{
+			args:   []string{"dosa", "--config", path, "--profile", "dev", "schema", "status"},
+			errmsg: `no profile "dev"`,
+		},
+		{
+			args:   []string{"dosa", "--config", filepath.Join(dir, "nonexistent"), "schema", "status"},
+			errmsg: "no such file",
+		},
+	}
+	for _, tc := range cases {
+		c := StartCapture()
+		var code int
+		exit = func(r int) { code = r }
+		os.Args = tc.args
+		main()
+		assert.Contains(t, c.stop(true), tc.errmsg, "%v", tc.args)
+		assert.Equal(t, 1, code)
+	}
+}
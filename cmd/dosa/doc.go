@@ -41,6 +41,40 @@ Provide a custom timeout of 20 seconds for RPCs to gateway:
 	$ dosa --timeout 20s <cmd>
 
 
+Config File:
+
+The options can instead be set in a .dosa.yaml file, found in the current directory or one
+of its parents, or given with --config. It has the fields of config.Config, and named
+profiles overriding some of them:
+
+	scope: infra_dev
+	namePrefix: oss.user
+	yarpc:
+	  transport: http
+	  host: 192.168.0.2
+	  port: "8080"
+	profiles:
+	  prod:
+	    scope: production
+	    yarpc:
+	      serviceName: dosa-gateway
+
+Use the settings of the "prod" profile:
+
+	$ dosa --profile prod schema status
+
+Flags take precedence over the DOSA_HOST, DOSA_PORT, DOSA_TRANSPORT, DOSA_SERVICE, DOSA_CALLER,
+DOSA_TIMEOUT, DOSA_SCOPE and DOSA_PREFIX environment variables, which take precedence over the
+profile, which takes precedence over the rest of the file and the defaults. DOSA_CONFIG and
+DOSA_PROFILE select the file and profile.
+
+The scope and name prefix of the file are the defaults of the schema check, upsert, status,
+show, history, plan and diff commands, and timeout.initialize is the default of --timeout.
+The connector field names the connector to use, and holds its other settings, like the path
+of the file connector; the host, port, transport, service and caller settings still come from
+the flags.
+
+
 Managing Scopes:
 
 Create a new scope called "infra_dev":
@@ -62,6 +96,22 @@ Dump schema to stdout as CQL (default is Avro):
 
 	$ dosa schema dump -as cql
 
+Dump schema as proto3 messages in the "events" package, keeping the field numbers in entities.numbers.json:
+
+	$ dosa schema dump -f proto --package events --numbers entities.numbers.json > entities.proto
+
+Dump the JSON Schema of each entity:
+
+	$ dosa schema dump -f jsonschema
+
+Dump the avro schemas of the entities, in the "infra_dev.oss.user" namespace:
+
+	$ dosa schema dump -f avro -s infra_dev --prefix oss.user
+
+Dump the PostgreSQL tables of the entities:
+
+	$ dosa schema dump -f postgres
+
 Check the compatibility status of all schema in the "infra_dev" scope:
 
 	$ dosa schema status -s infra_dev
@@ -70,16 +120,69 @@ Check the compatibility status of the schema with prefix "oss.user" in the "infr
 
 	$ dosa schema status -s infra_dev -np oss.user
 
+List the stored versions of the schema with prefix "oss.user" in the "infra_dev" scope, with the entities of each one as UQL:
+
+	$ dosa schema history -s infra_dev --prefix oss.user -f uql
+
+Show version 3 of that schema as Avro; the latest version is shown without --version:
+
+	$ dosa schema show -s infra_dev --prefix oss.user --version 3 -f avro
+
+The gateway API has no call returning stored schemas, so show, history, plan, diff without
+--file and upsert --plan-file fail with the default yarpc connector. They need a connector
+keeping the schemas, such as the file connector, set in the config file:
+
+	connector:
+	  name: file
+	  path: schemas.db
+
 Upsert schema with prefix "oss.user" to the "infra_dev" scope:
 
 	$ dosa schema upsert -s infra_dev -np oss.user
 
+Review the operations an upsert of the schema with prefix "oss.user" would perform, save them, then apply exactly those:
+
+	$ dosa schema plan -s infra_dev --prefix oss.user -o plan.json ./...
+	$ dosa schema upsert -s infra_dev --prefix oss.user --plan-file plan.json
+
+List the changes from the schema with prefix "oss.user" in the "infra_dev" scope to the local entities, as JSON. Keys, columns and tags are compared, but not indexes:
+
+	$ dosa schema diff -s infra_dev --prefix oss.user -f json ./...
+
+Check the local entities for risky designs, failing on warnings too, as in a pre-commit hook:
+
+	$ dosa schema lint --strict ./...
+
+Lint without the blob-key rule, and with long names as errors; list the rules with --list:
+
+	$ dosa schema lint -r blob-key=off -r long-name=error ./...
+
+Save the local entities, then list the changes made since:
+
+	$ dosa schema dump -f json > before.json
+	$ dosa schema diff --file before.json
+
+
+Local Gateway:
+
+Serve an in-memory gateway over HTTP on port 8080, with a "infra_dev" scope ready to use:
+
+	$ dosa --transport http -p 8080 serve --scope infra_dev
+
+Other commands, and services using the yarpc connector, can then point to it:
+
+	$ dosa --transport http -p 8080 schema upsert -s infra_dev -np oss.user
+
 
 Code Generation:
 
-TODO
+Generate the Go entity structs of the tables in a CQL file, with json tags, into the "users" package:
+
+	$ dosa schema gen -f cql --package users --json -o entities.go tables.cql
+
+The input can also be UQL or Avro schemas, and is read from stdin when no file is given:
 
-	$ dosa gen ...
+	$ dosa schema gen -f uql < tables.uql
 
 
 Defining Custom Commands:
@@ -25,6 +25,7 @@ import (
 	"os"
 
 	flags "github.com/jessevdk/go-flags"
+	_ "github.com/uber-go/dosa/connectors/file"
 	_ "github.com/uber-go/dosa/connectors/yarpc"
 )
 
@@ -60,16 +61,26 @@ func (b BuildInfo) Execute(args []string) error {
 
 // GlobalOptions are options for all subcommands
 type GlobalOptions struct {
-	Host        string   `long:"host" default:"127.0.0.1" description:"The hostname or IP for the gateway."`
-	Port        string   `short:"p" long:"port" default:"21300" description:"The hostname or IP for the gateway."`
-	Transport   string   `long:"transport" default:"tchannel" description:"TCP Transport to use. Options: http, tchannel."`
-	ServiceName string   `long:"service" description:"The TChannel service name for the gateway."`
-	CallerName  string   `long:"caller" default:"dosacli-$USER" description:"Caller will override the default caller name (which is dosacli-$USER)."`
-	Timeout     timeFlag `long:"timeout" default:"60s" description:"The timeout for gateway requests. E.g., 100ms, 0.5s, 1s. If no unit is specified, milliseconds are assumed."`
+	Host        string   `long:"host" env:"DOSA_HOST" default:"127.0.0.1" description:"The hostname or IP for the gateway."`
+	Port        string   `short:"p" long:"port" env:"DOSA_PORT" default:"21300" description:"The hostname or IP for the gateway."`
+	Transport   string   `long:"transport" env:"DOSA_TRANSPORT" default:"tchannel" description:"TCP Transport to use. Options: http, tchannel."`
+	ServiceName string   `long:"service" env:"DOSA_SERVICE" description:"The TChannel service name for the gateway."`
+	CallerName  string   `long:"caller" env:"DOSA_CALLER" default:"dosacli-$USER" description:"Caller will override the default caller name (which is dosacli-$USER)."`
+	Timeout     timeFlag `long:"timeout" env:"DOSA_TIMEOUT" default:"60s" description:"The timeout for gateway requests. E.g., 100ms, 0.5s, 1s. If no unit is specified, milliseconds are assumed."`
 	Connector   string   `hidden:"true" long:"connector" default:"yarpc" description:"Name of connector to use"`
+	Config      string   `long:"config" env:"DOSA_CONFIG" description:"The client config file, by default the first .dosa.yaml found from the current directory up."`
+	Profile     string   `long:"profile" env:"DOSA_PROFILE" description:"The profile of the client config file to use, e.g. dev, staging or prod."`
 	Version     bool     `long:"version" description:"Display version info"`
 }
 
+// _storedSchemaHelp tells which connectors the commands reading stored schemas
+// work with
+const _storedSchemaHelp = `
+
+The gateway API has no call returning stored schemas, so the yarpc connector
+cannot run this command, and upsert --plan-file neither. It needs a connector
+keeping the schemas, such as file.`
+
 var (
 	options   GlobalOptions
 	buildInfo BuildInfo
@@ -95,6 +106,21 @@ dosa manages your schema both in production and development scopes`
 	_, _ = c.AddCommand("upsert", "Upsert schema", "insert or update the schema", &SchemaUpsert{})
 	_, _ = c.AddCommand("dump", "Dump schema", "display the schema in a given format", &SchemaDump{})
 	_, _ = c.AddCommand("status", "Check schema status", "Check application status of schema", &SchemaStatus{})
+	_, _ = c.AddCommand("show", "Show stored schema", "display a stored schema version in a given format"+_storedSchemaHelp, &SchemaShow{})
+	_, _ = c.AddCommand("history", "List schema versions", "list the stored versions of a schema"+_storedSchemaHelp, &SchemaHistory{})
+	_, _ = c.AddCommand("gen", "Generate entities", "generate Go entity structs from CQL, UQL or Avro tables", &SchemaGen{})
+	_, _ = c.AddCommand("plan", "Plan schema upsert", "list the operations a schema upsert would perform on keys, columns and tags, without performing them; indexes are not compared"+_storedSchemaHelp, &SchemaPlan{})
+	_, _ = c.AddCommand("lint", "Lint schema", "flag risky designs of the local entities", &SchemaLint{})
+	_, _ = c.AddCommand("diff", "Diff schema", "list the changes to keys, columns and tags from a stored or dumped schema to the local entities; indexes are not compared"+_storedSchemaHelp, &SchemaDiff{})
+
+	_, _ = OptionsParser.AddCommand("serve", "run a local gateway", "serve a connector, such as memory, over the gateway RPC", &ServeCmd{})
+
+	// the config file only changes the defaults, so it is read before the flags
+	if err := applyConfigFile(OptionsParser, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(1)
+		return
+	}
 
 	_, err := OptionsParser.Parse()
 
@@ -65,19 +65,7 @@ func (t *timeFlag) UnmarshalFlag(value string) error {
 }
 
 func getAdminClient(opts GlobalOptions) (dosa.AdminClient, error) {
-	// fix up the callername
-	if opts.CallerName == "" || opts.CallerName == "dosacli-$USER" {
-		opts.CallerName = fmt.Sprintf("dosacli-%s", os.Getenv("USER"))
-	}
-
-	// create connector
-	conn, err := dosa.GetConnector(opts.Connector, map[string]interface{}{
-		"transport":   opts.Transport,
-		"host":        opts.Host,
-		"port":        opts.Port,
-		"callername":  opts.CallerName,
-		"servicename": opts.ServiceName,
-	})
+	conn, err := getConnector(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -85,3 +73,28 @@ func getAdminClient(opts GlobalOptions) (dosa.AdminClient, error) {
 
 	return client, nil
 }
+
+// getConnector creates the connector configured by the global options
+func getConnector(opts GlobalOptions) (dosa.Connector, error) {
+	// fix up the callername
+	if opts.CallerName == "" || opts.CallerName == "dosacli-$USER" {
+		opts.CallerName = fmt.Sprintf("dosacli-%s", os.Getenv("USER"))
+	}
+
+	// the other settings of the connector named in the config file, like the
+	// path of the file connector, are passed along with those of the flags
+	args := dosa.CreationArgs{}
+	if name, _ := configConnector["name"].(string); name == opts.Connector {
+		for key, value := range configConnector {
+			if key != "name" {
+				args[key] = value
+			}
+		}
+	}
+	args["transport"] = opts.Transport
+	args["host"] = opts.Host
+	args["port"] = opts.Port
+	args["callername"] = opts.CallerName
+	args["servicename"] = opts.ServiceName
+	return dosa.GetConnector(opts.Connector, args)
+}
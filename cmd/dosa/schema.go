@@ -21,8 +21,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -32,14 +36,23 @@ import (
 	"github.com/uber-go/dosa/connectors/devnull"
 	"github.com/uber-go/dosa/schema/avro"
 	"github.com/uber-go/dosa/schema/cql"
+	"github.com/uber-go/dosa/schema/gocode"
+	"github.com/uber-go/dosa/schema/jsonschema"
+	"github.com/uber-go/dosa/schema/proto"
+	"github.com/uber-go/dosa/schema/sql"
 	"github.com/uber-go/dosa/schema/uql"
 )
 
 var (
 	schemaDumpOutputTypes = map[string]bool{
-		"cql":  true,
-		"uql":  true,
-		"avro": true,
+		"cql":        true,
+		"uql":        true,
+		"avro":       true,
+		"json":       true,
+		"proto":      true,
+		"jsonschema": true,
+		"mysql":      true,
+		"postgres":   true,
 	}
 )
 
@@ -52,8 +65,8 @@ type SchemaOptions struct {
 // SchemaCmd is a placeholder for all schema commands
 type SchemaCmd struct {
 	*SchemaOptions
-	Scope      string `short:"s" long:"scope" description:"Storage scope for the given operation."`
-	NamePrefix string `long:"prefix" description:"Name prefix for schema types." required:"true"`
+	Scope      string `short:"s" long:"scope" env:"DOSA_SCOPE" description:"Storage scope for the given operation."`
+	NamePrefix string `long:"prefix" env:"DOSA_PREFIX" description:"Name prefix for schema types." required:"true"`
 }
 
 func (c *SchemaCmd) doSchemaOp(name string, f func(dosa.AdminClient, context.Context, string) (*dosa.SchemaStatus, error), args []string) error {
@@ -63,13 +76,7 @@ func (c *SchemaCmd) doSchemaOp(name string, f func(dosa.AdminClient, context.Con
 		fmt.Printf("global options are %+v\n", options)
 	}
 
-	// if not given, set the service name dynamically based on scope
-	if options.ServiceName == "" {
-		options.ServiceName = _defServiceName
-		if c.Scope == _prodScope {
-			options.ServiceName = _prodServiceName
-		}
-	}
+	defaultServiceName(c.Scope)
 
 	client, err := getAdminClient(options)
 	if err != nil {
@@ -121,16 +128,141 @@ func (c *SchemaCheck) Execute(args []string) error {
 // SchemaUpsert contains data for executing schema upsert command.
 type SchemaUpsert struct {
 	*SchemaCmd
-	Args struct {
+	PlanFile string `long:"plan-file" description:"Upsert the entities of a plan written by 'schema plan -o', if the stored schema did not change since."`
+	Args     struct {
 		Paths []string `positional-arg-name:"paths"`
 	} `positional-args:"yes"`
 }
 
 // Execute executes a schema upsert command
 func (c *SchemaUpsert) Execute(args []string) error {
+	if c.PlanFile != "" {
+		return c.applyPlan()
+	}
 	return c.doSchemaOp("schema upsert", dosa.AdminClient.UpsertSchema, c.Args.Paths)
 }
 
+// applyPlan upserts exactly the entities of a reviewed plan, after checking
+// that the plan still describes what the upsert does
+func (c *SchemaUpsert) applyPlan() error {
+	if c.Verbose {
+		fmt.Printf("executing schema upsert with plan %s\n", c.PlanFile)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+	if len(c.Args.Paths) != 0 {
+		return errors.New("the entities of a plan cannot be changed, remove the paths or the --plan-file flag")
+	}
+
+	f, err := os.Open(c.PlanFile)
+	if err != nil {
+		return err
+	}
+	plan, err := dosa.ReadSchemaPlan(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if plan.NamePrefix != c.NamePrefix || c.Scope != "" && plan.Scope != c.Scope {
+		return errors.Errorf("the plan is for prefix %q in scope %q", plan.NamePrefix, plan.Scope)
+	}
+
+	conn, err := schemaConnector(plan.Scope)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Shutdown() }()
+	stored, err := storedSchema(conn, plan.Scope, plan.NamePrefix)
+	if err != nil && !dosa.ErrorIsNotFound(err) {
+		return err
+	}
+	if err := plan.Verify(stored); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout.Duration())
+	defer cancel()
+	status, err := conn.UpsertSchema(ctx, plan.Scope, plan.NamePrefix, plan.Entities)
+	if err != nil {
+		fmt.Println("Status: NOT OK")
+		return err
+	}
+	fmt.Printf("Version: %d\n", status.Version)
+	fmt.Printf("Status: %s\n", status.Status)
+	return nil
+}
+
+// SchemaPlan contains data for executing the schema plan command
+type SchemaPlan struct {
+	*SchemaCmd
+	AllowBreaking bool   `long:"allow-breaking" description:"Plan breaking changes too."`
+	Out           string `short:"o" long:"out" description:"Write the plan to a file, to apply it with 'schema upsert --plan-file'."`
+	Args          struct {
+		Paths []string `positional-arg-name:"paths"`
+	} `positional-args:"yes"`
+}
+
+// Execute executes a schema plan command, a dry run of schema upsert listing
+// the operations it would perform on the stored schema, in order
+func (c *SchemaPlan) Execute(args []string) error {
+	if c.Verbose {
+		fmt.Printf("executing schema plan with %v\n", args)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+
+	// the local entities are found like schema dump does
+	client := dosa.NewAdminClient(&devnull.Connector{})
+	if len(c.Args.Paths) != 0 {
+		dirs, err := expandDirectories(c.Args.Paths)
+		if err != nil {
+			return errors.Wrap(err, "could not expand directories")
+		}
+		client.Directories(dirs)
+	}
+	if len(c.Excludes) != 0 {
+		client.Excludes(c.Excludes)
+	}
+	local, err := client.GetSchema()
+	if err != nil {
+		return err
+	}
+
+	scope := defaultScope(c.Scope)
+	conn, err := schemaConnector(scope)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Shutdown() }()
+	// nothing stored yet means all the entities are new
+	stored, err := storedSchema(conn, scope, c.NamePrefix)
+	if err != nil && !dosa.ErrorIsNotFound(err) {
+		return err
+	}
+
+	plan := dosa.PlanSchema(scope, c.NamePrefix, stored, local)
+	plan.AllowBreaking = c.AllowBreaking
+	if err := plan.WriteText(os.Stdout); err != nil {
+		return err
+	}
+	if plan.Breaking() && !c.AllowBreaking {
+		return errors.New("the plan has breaking changes, review them and use --allow-breaking to plan them anyway")
+	}
+
+	if c.Out == "" {
+		return nil
+	}
+	f, err := os.Create(c.Out)
+	if err != nil {
+		return err
+	}
+	if err := plan.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // SchemaStatus contains data for executing schema status command
 type SchemaStatus struct {
 	*SchemaCmd
@@ -145,13 +277,7 @@ func (c *SchemaStatus) Execute(args []string) error {
 		fmt.Printf("global options are %+v\n", options)
 	}
 
-	// if not given, set the service name dynamically based on scope
-	if options.ServiceName == "" {
-		options.ServiceName = _defServiceName
-		if c.Scope == _prodScope {
-			options.ServiceName = _prodServiceName
-		}
-	}
+	defaultServiceName(c.Scope)
 
 	client, err := getAdminClient(options)
 	if err != nil {
@@ -178,11 +304,102 @@ func (c *SchemaStatus) Execute(args []string) error {
 	return nil
 }
 
+// SchemaShow contains data for executing the schema show command
+type SchemaShow struct {
+	*SchemaCmd
+	Version int32  `long:"version" description:"Version of the schema, the latest one when not given."`
+	Format  string `long:"format" short:"f" description:"output format" choice:"cql" choice:"uql" choice:"avro" choice:"json" choice:"proto" choice:"jsonschema" choice:"mysql" choice:"postgres" default:"cql"`
+	Package string `long:"package" description:"Package of the proto messages."`
+	Numbers string `long:"numbers" description:"File keeping the proto field numbers, updated with the numbers of new columns."`
+}
+
+// Execute executes a schema show command, printing the entities of a stored
+// schema version like schema dump prints the local ones
+func (c *SchemaShow) Execute(args []string) error {
+	if c.Verbose {
+		fmt.Printf("executing schema show with %v\n", args)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+
+	client, err := schemaAdminClient(c.Scope)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout.Duration())
+	defer cancel()
+
+	defs, err := client.GetStoredSchema(ctx, c.NamePrefix, c.Version)
+	if err != nil {
+		return err
+	}
+	dump := &SchemaDump{Format: c.Format, Scope: c.Scope, NamePrefix: c.NamePrefix, Package: c.Package, Numbers: c.Numbers}
+	return dump.dump(defs)
+}
+
+// SchemaHistory contains data for executing the schema history command
+type SchemaHistory struct {
+	*SchemaCmd
+	Format  string `long:"format" short:"f" description:"Also print the entities of each version in this format." choice:"cql" choice:"uql" choice:"avro" choice:"json" choice:"proto" choice:"jsonschema" choice:"mysql" choice:"postgres"`
+	Package string `long:"package" description:"Package of the proto messages."`
+	Numbers string `long:"numbers" description:"File keeping the proto field numbers, updated with the numbers of new columns."`
+}
+
+// Execute executes a schema history command, listing every stored version of
+// the schema, oldest first
+func (c *SchemaHistory) Execute(args []string) error {
+	if c.Verbose {
+		fmt.Printf("executing schema history with %v\n", args)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+
+	client, err := schemaAdminClient(c.Scope)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout.Duration())
+	defer cancel()
+
+	statuses, err := client.ListSchemaVersions(ctx, c.NamePrefix)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return errors.Errorf("no schema %q found", c.NamePrefix)
+	}
+	dump := &SchemaDump{Format: c.Format, Scope: c.Scope, NamePrefix: c.NamePrefix, Package: c.Package, Numbers: c.Numbers}
+	for i, status := range statuses {
+		if i > 0 && c.Format != "" {
+			fmt.Println()
+		}
+		fmt.Printf("Version: %d\n", status.Version)
+		fmt.Printf("Status: %s\n", status.Status)
+		if c.Format == "" {
+			continue
+		}
+		defs, err := client.GetStoredSchema(ctx, c.NamePrefix, status.Version)
+		if err != nil {
+			return err
+		}
+		if err := dump.dump(defs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SchemaDump contains data for executing the schema dump command
 type SchemaDump struct {
 	*SchemaOptions
-	Format string `long:"format" short:"f" description:"output format" choice:"cql" choice:"uql" choice:"avro" default:"cql"`
-	Args   struct {
+	Format     string `long:"format" short:"f" description:"output format" choice:"cql" choice:"uql" choice:"avro" choice:"json" choice:"proto" choice:"jsonschema" choice:"mysql" choice:"postgres" default:"cql"`
+	Scope      string `short:"s" long:"scope" env:"DOSA_SCOPE" description:"Storage scope of the entities, used as the avro namespace with the prefix."`
+	NamePrefix string `long:"prefix" env:"DOSA_PREFIX" description:"Name prefix of the entities, used as the avro namespace with the scope."`
+	Package    string `long:"package" description:"Package of the proto messages."`
+	Numbers    string `long:"numbers" description:"File keeping the proto field numbers, updated with the numbers of new columns."`
+	Args       struct {
 		Paths []string `positional-arg-name:"paths"`
 	} `positional-args:"yes"`
 }
@@ -213,6 +430,34 @@ func (c *SchemaDump) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
+	return c.dump(defs)
+}
+
+// dump prints the entity definitions in the format of the command, which is
+// shared with the commands showing stored schemas
+func (c *SchemaDump) dump(defs []*dosa.EntityDefinition) error {
+	// json is the encoding read back by schema diff, so all the entities go in one document
+	if c.Format == "json" {
+		data, err := json.MarshalIndent(defs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	// proto messages go in one file, which declares the dosa options once
+	if c.Format == "proto" {
+		return c.dumpProto(defs)
+	}
+
+	var fqn dosa.FQN
+	if c.Format == "avro" {
+		var err error
+		if fqn, err = c.avroNamespace(); err != nil {
+			return err
+		}
+	}
 
 	// for each of those entities, format it in the specified way
 	for _, d := range defs {
@@ -220,15 +465,390 @@ func (c *SchemaDump) Execute(args []string) error {
 		case "cql":
 			fmt.Println(cql.ToCQL(d))
 		case "uql":
-			fmt.Println(uql.ToUQL(d))
+			stmt, err := uql.ToUQL(d)
+			if err != nil {
+				return err
+			}
+			fmt.Println(stmt)
 		case "avro":
-			fmt.Println(avro.ToAvro("TODO", d))
+			data, err := avro.ToAvro(fqn, d)
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, data, "", "  "); err != nil {
+				return err
+			}
+			fmt.Println(buf.String())
+		case "jsonschema":
+			data, err := jsonschema.ToJSONSchema(d)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		case "mysql", "postgres":
+			// the definitions found by the admin client have no indexes
+			ddl, err := sql.ToSQL(sql.Dialects[c.Format], d, nil)
+			if err != nil {
+				return err
+			}
+			fmt.Println(ddl)
+		}
+	}
+
+	return nil
+}
+
+// avroNamespace returns the namespace of the avro records, made of the scope
+// and the name prefix when given
+func (c *SchemaDump) avroNamespace() (dosa.FQN, error) {
+	var names []string
+	for _, name := range []string{c.Scope, c.NamePrefix} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	fqn, err := dosa.ToFQN(strings.Join(names, "."))
+	if err != nil {
+		return "", errors.Wrap(err, "invalid scope or prefix")
+	}
+	return fqn, nil
+}
+
+// dumpProto prints the proto messages of the entities, keeping the field
+// numbers in the numbers file when there is one
+func (c *SchemaDump) dumpProto(defs []*dosa.EntityDefinition) error {
+	numbers := make(proto.Numbers)
+	if c.Numbers != "" {
+		f, err := os.Open(c.Numbers)
+		switch {
+		case err == nil:
+			numbers, err = proto.ReadNumbers(f)
+			f.Close()
+			if err != nil {
+				return errors.Wrapf(err, "could not read %s", c.Numbers)
+			}
+		case !os.IsNotExist(err):
+			return err
+		}
+	}
+
+	data, err := proto.ToProto(defs, proto.Options{Package: c.Package, Numbers: numbers})
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(data))
+
+	if c.Numbers == "" {
+		return nil
+	}
+	f, err := os.Create(c.Numbers)
+	if err != nil {
+		return err
+	}
+	if _, err := numbers.WriteTo(f); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "could not write %s", c.Numbers)
+	}
+	return f.Close()
+}
+
+// SchemaLint contains data for executing the schema lint command
+type SchemaLint struct {
+	*SchemaOptions
+	Rules  []string `short:"r" long:"rule" description:"Set the severity of a rule to off, warning or error, e.g. blob-key=off. Can be repeated."`
+	Strict bool     `long:"strict" description:"Fail on warnings too."`
+	List   bool     `long:"list" description:"List the rules with their default severity, and exit."`
+	Format string   `long:"format" short:"f" description:"output format" choice:"text" choice:"json" default:"text"`
+	Args   struct {
+		Paths []string `positional-arg-name:"paths"`
+	} `positional-args:"yes"`
+}
+
+// Execute executes a schema lint command, which fails when any error, or
+// any warning in strict mode, is found, so that it can run in pre-commit hooks
+func (c *SchemaLint) Execute(args []string) error {
+	if c.Verbose {
+		fmt.Printf("executing schema lint with %v\n", args)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+
+	if c.List {
+		for _, rule := range dosa.LintRules {
+			fmt.Printf("%s (%s): %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		return nil
+	}
+
+	severities := make(map[string]dosa.LintSeverity, len(c.Rules))
+	for _, r := range c.Rules {
+		parts := strings.SplitN(r, "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("invalid rule %q, must be name=severity", r)
+		}
+		severity, err := dosa.ParseLintSeverity(parts[1])
+		if err != nil {
+			return errors.Wrapf(err, "invalid rule %q", r)
+		}
+		severities[parts[0]] = severity
+	}
+
+	// the entities are found like schema dump does
+	client := dosa.NewAdminClient(&devnull.Connector{})
+	if len(c.Args.Paths) != 0 {
+		dirs, err := expandDirectories(c.Args.Paths)
+		if err != nil {
+			return errors.Wrap(err, "could not expand directories")
 		}
+		client.Directories(dirs)
+	}
+	if len(c.Excludes) != 0 {
+		client.Excludes(c.Excludes)
+	}
+	defs, err := client.GetSchema()
+	if err != nil {
+		return err
+	}
+
+	report, err := dosa.LintEntityDefinitions(defs, severities)
+	if err != nil {
+		return err
+	}
+	if c.Format == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
 	}
 
+	errs, warnings := report.Count(dosa.LintError), report.Count(dosa.LintWarning)
+	if errs > 0 || c.Strict && warnings > 0 {
+		return errors.Errorf("lint found %d errors and %d warnings", errs, warnings)
+	}
 	return nil
 }
 
+// SchemaDiff contains data for executing the schema diff command
+type SchemaDiff struct {
+	*SchemaOptions
+	Scope      string `short:"s" long:"scope" env:"DOSA_SCOPE" description:"Storage scope of the stored schema to compare with."`
+	NamePrefix string `long:"prefix" env:"DOSA_PREFIX" description:"Name prefix of the stored schema to compare with."`
+	File       string `long:"file" description:"Compare with the entities of a file written by 'schema dump -f json' instead of the stored schema."`
+	Format     string `long:"format" short:"f" description:"output format" choice:"text" choice:"json" default:"text"`
+	Args       struct {
+		Paths []string `positional-arg-name:"paths"`
+	} `positional-args:"yes"`
+}
+
+// Execute executes a schema diff command, listing the changes from the stored
+// schema or dumped file to the entities found in the paths
+func (c *SchemaDiff) Execute(args []string) error {
+	if c.Verbose {
+		fmt.Printf("executing schema diff with %v\n", args)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+
+	// the local entities are found like schema dump does
+	client := dosa.NewAdminClient(&devnull.Connector{})
+	if len(c.Args.Paths) != 0 {
+		dirs, err := expandDirectories(c.Args.Paths)
+		if err != nil {
+			return errors.Wrap(err, "could not expand directories")
+		}
+		client.Directories(dirs)
+	}
+	if len(c.Excludes) != 0 {
+		client.Excludes(c.Excludes)
+	}
+	local, err := client.GetSchema()
+	if err != nil {
+		return err
+	}
+
+	var base []*dosa.EntityDefinition
+	if c.File != "" {
+		base, err = readEntityDefinitions(c.File)
+	} else {
+		base, err = c.storedSchema()
+	}
+	if err != nil {
+		return err
+	}
+
+	diff := dosa.DiffEntityDefinitions(base, local)
+	if c.Format == "json" {
+		return diff.WriteJSON(os.Stdout)
+	}
+	return diff.WriteText(os.Stdout)
+}
+
+// storedSchema fetches the latest schema of the scope and name prefix from the connector
+func (c *SchemaDiff) storedSchema() ([]*dosa.EntityDefinition, error) {
+	if c.NamePrefix == "" {
+		return nil, errors.New("the --prefix flag is required to compare with a stored schema")
+	}
+	scope := defaultScope(c.Scope)
+	conn, err := schemaConnector(scope)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Shutdown() }()
+	return storedSchema(conn, scope, c.NamePrefix)
+}
+
+// defaultScope returns the scope of the schema commands that read the stored
+// schema, which like the admin client defaults to the one named after the user
+func defaultScope(scope string) string {
+	if scope == "" {
+		return os.Getenv("USER")
+	}
+	return scope
+}
+
+// schemaConnector creates the connector for the schema commands of a scope
+func schemaConnector(scope string) (dosa.Connector, error) {
+	defaultServiceName(scope)
+	return getConnector(options)
+}
+
+// schemaAdminClient creates the admin client for the schema commands of a
+// scope, which keeps its default scope when none is given
+func schemaAdminClient(scope string) (dosa.AdminClient, error) {
+	defaultServiceName(scope)
+	client, err := getAdminClient(options)
+	if err != nil {
+		return nil, err
+	}
+	if scope != "" {
+		client.Scope(scope)
+	}
+	return client, nil
+}
+
+// defaultServiceName sets the service name dynamically based on the scope,
+// if it was not given
+func defaultServiceName(scope string) {
+	if options.ServiceName == "" {
+		options.ServiceName = _defServiceName
+		if scope == _prodScope {
+			options.ServiceName = _prodServiceName
+		}
+	}
+}
+
+// storedSchema fetches the latest schema of a scope and name prefix from a connector
+func storedSchema(conn dosa.Connector, scope, namePrefix string) ([]*dosa.EntityDefinition, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout.Duration())
+	defer cancel()
+
+	eds, err := conn.GetSchema(ctx, scope, namePrefix, dosa.LatestVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get schema %q in scope %q", namePrefix, scope)
+	}
+	return eds, nil
+}
+
+// readEntityDefinitions reads a file written by schema dump in the json format
+func readEntityDefinitions(path string) ([]*dosa.EntityDefinition, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var eds []*dosa.EntityDefinition
+	if err := json.Unmarshal(data, &eds); err != nil {
+		return nil, errors.Wrapf(err, "could not decode entity definitions in %q", path)
+	}
+	return eds, nil
+}
+
+// SchemaGen contains data for executing the schema gen command
+type SchemaGen struct {
+	*SchemaOptions
+	Format   string `long:"format" short:"f" description:"input format" choice:"cql" choice:"uql" choice:"avro" default:"cql"`
+	Package  string `long:"package" description:"Package name of the generated code." default:"entities"`
+	JSONTags bool   `long:"json" description:"Add json tags with the column names."`
+	Output   string `short:"o" long:"output" description:"Write the generated code to a file instead of stdout."`
+	Args     struct {
+		Files []string `positional-arg-name:"files"`
+	} `positional-args:"yes"`
+}
+
+// Execute executes a schema gen command, generating the Go entity structs of
+// the tables in the given files, or in the standard input if there are none
+func (c *SchemaGen) Execute(args []string) error {
+	if c.Verbose {
+		fmt.Printf("executing schema gen with %v\n", args)
+		fmt.Printf("options are %+v\n", *c)
+		fmt.Printf("global options are %+v\n", options)
+	}
+
+	var eds []*dosa.EntityDefinition
+	if len(c.Args.Files) == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if eds, err = parseEntityDefinitions(c.Format, data); err != nil {
+			return errors.Wrap(err, "could not parse the standard input")
+		}
+	}
+	for _, name := range c.Args.Files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		found, err := parseEntityDefinitions(c.Format, data)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse %s", name)
+		}
+		eds = append(eds, found...)
+	}
+	if len(eds) == 0 {
+		return errors.New("no tables found")
+	}
+
+	source, err := gocode.ToGo(eds, gocode.Options{Package: c.Package, JSONTags: c.JSONTags})
+	if err != nil {
+		return err
+	}
+	if c.Output != "" {
+		return ioutil.WriteFile(c.Output, source, 0644)
+	}
+	_, err = os.Stdout.Write(source)
+	return err
+}
+
+// parseEntityDefinitions reads the tables of a schema in the given format
+func parseEntityDefinitions(format string, data []byte) ([]*dosa.EntityDefinition, error) {
+	switch format {
+	case "cql":
+		return cql.FromCQL(string(data))
+	case "uql":
+		return uql.FromUQL(string(data))
+	}
+
+	// avro schemas are JSON documents, which can follow each other
+	var eds []*dosa.EntityDefinition
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var schema json.RawMessage
+		if err := decoder.Decode(&schema); err == io.EOF {
+			return eds, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ed, err := avro.FromAvro(string(schema))
+		if err != nil {
+			return nil, err
+		}
+		eds = append(eds, ed)
+	}
+}
+
 // expandDirectory verifies that each argument is actually a directory or
 // uses the special go suffix of /... to mean recursively walk from here
 // example: ./... means the current directory and all subdirectories
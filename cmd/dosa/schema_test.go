@@ -22,17 +22,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/devnull"
 	"github.com/uber-go/dosa/mocks"
-
-	_ "github.com/uber-go/dosa/connectors/devnull"
+	"github.com/uber-go/dosa/schema/avro"
+	"github.com/uber-go/dosa/schema/jsonschema"
+	"github.com/uber-go/dosa/schema/proto"
+	"github.com/uber-go/dosa/testentity"
 )
 
 func TestSchema_ExpandDirectories(t *testing.T) {
@@ -320,16 +327,596 @@ func TestSchema_Dump_UQL(t *testing.T) {
 	assert.Contains(t, output, "executing schema dump")
 	assert.Contains(t, output, "CREATE TABLE awesome_test_entity")
 	assert.Contains(t, output, "an_int64_value int64;")
-	assert.Contains(t, output, "PRIMARY KEY (an_uuid_key, strkey ASC, int64key DESC);")
+	assert.Contains(t, output, "PRIMARY KEY (an_uuid_key, strkey ASC, int64key DESC);\n")
+	assert.NotContains(t, output, "<nil>")
 }
 
 func TestSchema_Dump_Avro(t *testing.T) {
 	c := StartCapture()
 	exit = func(r int) {}
-	os.Args = []string{"dosa", "schema", "dump", "-f", "avro", "-v", "../../testentity"}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "avro", "-s", "infra_dev", "--prefix", "oss.user", "-v", "../../testentity"}
 	main()
 	output := c.stop(false)
 	assert.Contains(t, output, "executing schema dump")
-	assert.Contains(t, output, "123 34 99 108 117 115")
-	assert.Contains(t, output, "99 111 114 100 34 125")
+	assert.Contains(t, output, `  "namespace": "infra_dev.oss.user",`)
+	assert.Contains(t, output, `  "name": "awesome_test_entity",`)
+
+	// the formatted schema reads back as the entity definition
+	schema := output[strings.Index(output, "{\n"):]
+	ed, err := avro.FromAvro(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, testEntityDefinitions(t)[0], ed)
+}
+
+func TestSchema_Dump_Avro_InvalidNamespace(t *testing.T) {
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "avro", "--prefix", "not-valid", "../../testentity"}
+	main()
+	assert.Contains(t, c.stop(true), "invalid scope or prefix")
+}
+
+func TestSchema_Dump_JSON(t *testing.T) {
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "json", "../../testentity"}
+	main()
+	var eds []*dosa.EntityDefinition
+	assert.NoError(t, json.Unmarshal([]byte(c.stop(false)), &eds))
+	assert.Equal(t, 1, len(eds))
+	assert.Equal(t, "awesome_test_entity", eds[0].Name)
+}
+
+func TestSchema_Dump_Proto(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dosa-dump")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	numbers := filepath.Join(dir, "numbers.json")
+
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "proto", "--package", "events", "--numbers", numbers, "../../testentity"}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, "package events;")
+	assert.Contains(t, output, "message AwesomeTestEntity {")
+	assert.Contains(t, output, `option (dosa_primary_key) = "(an_uuid_key, strkey ASC, int64key DESC)";`)
+	assert.Contains(t, output, `string an_uuid_key = 1 [(dosa_type) = "TUUID"];`)
+
+	// the numbers file keeps the numbers of the next dump
+	f, err := os.Open(numbers)
+	assert.NoError(t, err)
+	defer f.Close()
+	saved, err := proto.ReadNumbers(f)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, saved["awesome_test_entity"]["an_uuid_key"])
+}
+
+func TestSchema_Dump_Proto_BadNumbers(t *testing.T) {
+	f, err := ioutil.TempFile("", "dosa-numbers")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("not json")
+	f.Close()
+
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "proto", "--numbers", f.Name(), "../../testentity"}
+	main()
+	assert.Contains(t, c.stop(true), "could not read")
+}
+
+func TestSchema_Dump_JSONSchema(t *testing.T) {
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "jsonschema", "../../testentity"}
+	main()
+	var s jsonschema.Schema
+	assert.NoError(t, json.Unmarshal([]byte(c.stop(false)), &s))
+	assert.Equal(t, "awesome_test_entity", s.Title)
+	assert.Equal(t, []string{"an_uuid_key", "strkey", "int64key"}, s.Required)
+	assert.Equal(t, "uuid", s.Properties["an_uuid_key"].Format)
+}
+
+func TestSchema_Dump_SQL(t *testing.T) {
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "dump", "-f", "mysql", "../../testentity"}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, "CREATE TABLE `awesome_test_entity` (\n  `an_uuid_key` CHAR(36) NOT NULL,\n  `strkey` VARCHAR(255) NOT NULL,")
+	assert.Contains(t, output, "CREATE INDEX `awesome_test_entity_clustering_order` ON `awesome_test_entity` (`an_uuid_key`, `strkey`, `int64key` DESC);")
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "schema", "dump", "-f", "postgres", "../../testentity"}
+	main()
+	output = c.stop(false)
+	assert.Contains(t, output, `"tsv" TIMESTAMP WITH TIME ZONE,`)
+	assert.Contains(t, output, `PRIMARY KEY ("an_uuid_key", "strkey", "int64key")`)
+}
+
+// lintEntities writes a package with an entity keyed by a blob and a
+// timestamp, returning its directory
+func lintEntities(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "dosa-lint")
+	assert.NoError(t, err)
+	source := `package entities
+
+import (
+	"time"
+
+	"github.com/uber-go/dosa"
+)
+
+type Photo struct {
+	dosa.Entity ` + "`dosa:\"primaryKey=(Data, Taken)\"`" + `
+	Data  []byte
+	Taken time.Time
+}
+`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "photo.go"), []byte(source), 0644))
+	return dir
+}
+
+func TestSchema_Lint(t *testing.T) {
+	dir := lintEntities(t)
+	defer os.RemoveAll(dir)
+
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "lint", dir}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, "error: photo: blob-key: partition key data is a blob\n")
+	assert.Contains(t, output, "warning: photo: ascending-time-clustering: clustering key taken is not descending\n")
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "schema", "lint", dir}
+	main()
+	assert.Contains(t, c.stop(true), "lint found 1 errors and 1 warnings")
+}
+
+func TestSchema_Lint_Severities(t *testing.T) {
+	dir := lintEntities(t)
+	defer os.RemoveAll(dir)
+
+	// without errors, warnings only fail in strict mode
+	var code int
+	exit = func(r int) { code = r }
+	c := StartCapture()
+	os.Args = []string{"dosa", "schema", "lint", "-r", "blob-key=off", "-f", "json", dir}
+	main()
+	var report dosa.LintReport
+	assert.NoError(t, json.Unmarshal([]byte(c.stop(false)), &report))
+	assert.Equal(t, 1, len(report.Findings))
+	assert.Equal(t, 0, code)
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "schema", "lint", "-r", "blob-key=off", "--strict", dir}
+	main()
+	assert.Contains(t, c.stop(true), "lint found 0 errors and 1 warnings")
+	assert.Equal(t, 1, code)
+}
+
+func TestSchema_Lint_List(t *testing.T) {
+	c := StartCapture()
+	exit = func(r int) {}
+	os.Args = []string{"dosa", "schema", "lint", "--list"}
+	main()
+	output := c.stop(false)
+	for _, rule := range dosa.LintRules {
+		assert.Contains(t, output, rule.Name+" ("+string(rule.Severity)+"): ")
+	}
+}
+
+func TestSchema_Lint_Errors(t *testing.T) {
+	data := []struct {
+		args   []string
+		errmsg string
+	}{
+		{[]string{"-r", "blob-key"}, `invalid rule "blob-key", must be name=severity`},
+		{[]string{"-r", "blob-key=fatal"}, `invalid rule "blob-key=fatal": invalid severity "fatal"`},
+		{[]string{"-r", "nope=off"}, `unknown lint rule "nope"`},
+	}
+	for _, d := range data {
+		c := StartCapture()
+		exit = func(r int) {}
+		os.Args = append(append([]string{"dosa", "schema", "lint"}, d.args...), "../../testentity")
+		main()
+		assert.Contains(t, c.stop(true), d.errmsg)
+	}
+}
+
+// storedSchemaConnector serves a fixed stored schema, and records upserts
+type storedSchemaConnector struct {
+	devnull.Connector
+	eds      []*dosa.EntityDefinition
+	upserted []*dosa.EntityDefinition
+}
+
+func (c *storedSchemaConnector) UpsertSchema(ctx context.Context, scope, namePrefix string, eds []*dosa.EntityDefinition) (*dosa.SchemaStatus, error) {
+	c.upserted = eds
+	return &dosa.SchemaStatus{Version: 2, Status: "COMPLETED"}, nil
+}
+
+func (c *storedSchemaConnector) GetSchema(ctx context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	if scope != "scope" || namePrefix != "foo" || version != dosa.LatestVersion {
+		return nil, &dosa.ErrNotFound{}
+	}
+	return c.eds, nil
+}
+
+func testEntityDefinitions(t *testing.T) []*dosa.EntityDefinition {
+	table, err := dosa.TableFromInstance(&testentity.TestEntity{})
+	assert.NoError(t, err)
+	return []*dosa.EntityDefinition{&table.EntityDefinition}
+}
+
+func TestSchema_Diff_File(t *testing.T) {
+	// the dumped entity had one more column, and no int32 column
+	eds := testEntityDefinitions(t)
+	stored := *eds[0]
+	stored.Columns = append([]*dosa.ColumnDefinition{{Name: "gone", Type: dosa.String}}, stored.Columns...)
+	for i, cd := range stored.Columns {
+		if cd.Type == dosa.Int32 {
+			stored.Columns = append(stored.Columns[:i:i], stored.Columns[i+1:]...)
+			break
+		}
+	}
+	data, err := json.Marshal([]*dosa.EntityDefinition{&stored})
+	assert.NoError(t, err)
+	f, err := ioutil.TempFile("", "dosa-schema")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c := StartCapture()
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+	os.Args = []string{"dosa", "schema", "diff", "--file", f.Name(), "-e", "_test.go", "../../testentity"}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, "compatible: awesome_test_entity: column added int32v: Int32\n")
+	assert.Contains(t, output, "breaking: awesome_test_entity: column removed gone: String\n")
+}
+
+func TestSchema_Diff_Connector(t *testing.T) {
+	dosa.RegisterConnector("storedschema", func(dosa.CreationArgs) (dosa.Connector, error) {
+		return &storedSchemaConnector{eds: testEntityDefinitions(t)}, nil
+	})
+	c := StartCapture()
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+	os.Args = []string{"dosa", "--connector", "storedschema", "schema", "diff", "-s", "scope", "--prefix", "foo", "-f", "json", "-e", "_test.go", "../../testentity"}
+	main()
+	var diff dosa.SchemaDiff
+	assert.NoError(t, json.Unmarshal([]byte(c.stop(false)), &diff))
+	assert.Empty(t, diff.Changes)
+
+	// like schema plan, the default scope is named after the user
+	user := os.Getenv("USER")
+	defer os.Setenv("USER", user)
+	os.Setenv("USER", "scope")
+	c = StartCapture()
+	os.Args = []string{"dosa", "--connector", "storedschema", "schema", "diff", "--prefix", "foo", "-f", "json", "-e", "_test.go", "../../testentity"}
+	main()
+	diff = dosa.SchemaDiff{}
+	assert.NoError(t, json.Unmarshal([]byte(c.stop(false)), &diff))
+	assert.Empty(t, diff.Changes)
+}
+
+func TestSchema_Diff_Errors(t *testing.T) {
+	registerPlanConnector(t)
+	cases := []struct {
+		args   []string
+		errmsg string
+	}{
+		{
+			args:   []string{"dosa", "schema", "diff", "../../testentity"},
+			errmsg: "--prefix flag is required",
+		},
+		{
+			args:   []string{"dosa", "--connector", "planner", "schema", "diff", "-s", "other", "--prefix", "foo", "../../testentity"},
+			errmsg: `could not get schema "foo" in scope "other"`,
+		},
+		{
+			args:   []string{"dosa", "schema", "diff", "--file", "/nonexistent", "../../testentity"},
+			errmsg: "no such file",
+		},
+		{
+			args:   []string{"dosa", "schema", "diff", "--file", "/dev/null", "../../testentity"},
+			errmsg: "could not decode entity definitions",
+		},
+	}
+	for _, tc := range cases {
+		c := StartCapture()
+		exit = func(r int) {}
+		os.Args = tc.args
+		main()
+		assert.Contains(t, c.stop(true), tc.errmsg, "%v", tc.args)
+	}
+}
+
+func TestSchema_Gen(t *testing.T) {
+	f, err := ioutil.TempFile("", "dosa-schema")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`create table "user_event" ("user_id" uuid, "created" timestamp, primary key (user_id, created DESC));`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c := StartCapture()
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+	os.Args = []string{"dosa", "schema", "gen", "--package", "events", "--json", f.Name()}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, "package events\n")
+	assert.Contains(t, output, "type UserEvent struct {\n")
+	assert.Contains(t, output, "`dosa:\"name=user_event, primaryKey=(UserID, Created DESC)\"`")
+	assert.Contains(t, output, "UserID      dosa.UUID `dosa:\"name=user_id\" json:\"user_id\"`")
+}
+
+func TestSchema_Gen_Errors(t *testing.T) {
+	f, err := ioutil.TempFile("", "dosa-schema")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("CREATE TABLE t (a varchar;) PRIMARY KEY (a);")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	cases := []struct {
+		args   []string
+		errmsg string
+	}{
+		{
+			args:   []string{"dosa", "schema", "gen", "-f", "uql", f.Name()},
+			errmsg: `unsupported type "varchar"`,
+		},
+		{
+			args:   []string{"dosa", "schema", "gen", "-f", "avro", f.Name()},
+			errmsg: "could not parse",
+		},
+		{
+			args:   []string{"dosa", "schema", "gen", "/nonexistent"},
+			errmsg: "no such file",
+		},
+		{
+			args:   []string{"dosa", "schema", "gen", "-f", "proto", f.Name()},
+			errmsg: "Invalid value",
+		},
+	}
+	for _, tc := range cases {
+		c := StartCapture()
+		exit = func(r int) {}
+		os.Args = tc.args
+		main()
+		assert.Contains(t, c.stop(true), tc.errmsg, "%v", tc.args)
+	}
+}
+
+// registerPlanConnector registers a connector storing the test entity with
+// one more column, so that upserting the test entity removes it
+func registerPlanConnector(t *testing.T) *storedSchemaConnector {
+	eds := testEntityDefinitions(t)
+	stored := *eds[0]
+	stored.Columns = append(stored.Columns[:len(stored.Columns):len(stored.Columns)], &dosa.ColumnDefinition{Name: "gone", Type: dosa.String})
+	conn := &storedSchemaConnector{eds: []*dosa.EntityDefinition{&stored}}
+	dosa.RegisterConnector("planner", func(dosa.CreationArgs) (dosa.Connector, error) {
+		return conn, nil
+	})
+	return conn
+}
+
+func TestSchema_Plan(t *testing.T) {
+	conn := registerPlanConnector(t)
+	dir, err := ioutil.TempDir("", "dosa-plan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	planFile := filepath.Join(dir, "plan.json")
+
+	// breaking changes are refused
+	var code int
+	exit = func(r int) { code = r }
+	c := StartCapture()
+	os.Args = []string{"dosa", "--connector", "planner", "schema", "plan", "-s", "scope", "--prefix", "foo", "-o", planFile, "-e", "_test.go", "../../testentity"}
+	main()
+	assert.Contains(t, c.stop(true), "the plan has breaking changes")
+	assert.Equal(t, 1, code)
+	_, err = os.Stat(planFile)
+	assert.True(t, os.IsNotExist(err))
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "--connector", "planner", "schema", "plan", "-s", "scope", "--prefix", "foo", "--allow-breaking", "-o", planFile, "-e", "_test.go", "../../testentity"}
+	main()
+	assert.Equal(t, "plan for prefix \"foo\" in scope \"scope\":\n1. breaking: awesome_test_entity: column removed gone: String\n", c.stop(false))
+	assert.Equal(t, 0, code)
+
+	// the reviewed plan is applied as is
+	c = StartCapture()
+	os.Args = []string{"dosa", "--connector", "planner", "schema", "upsert", "-s", "scope", "--prefix", "foo", "--plan-file", planFile}
+	main()
+	assert.Contains(t, c.stop(false), "Version: 2")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, testEntityDefinitions(t), conn.upserted)
+
+	// until the stored schema changes
+	conn.eds = testEntityDefinitions(t)
+	conn.upserted = nil
+	c = StartCapture()
+	os.Args = []string{"dosa", "--connector", "planner", "schema", "upsert", "-s", "scope", "--prefix", "foo", "--plan-file", planFile}
+	main()
+	assert.Contains(t, c.stop(true), "the stored schema changed since the plan was made")
+	assert.Equal(t, 1, code)
+	assert.Nil(t, conn.upserted)
+}
+
+func TestSchema_Plan_NewSchema(t *testing.T) {
+	registerPlanConnector(t)
+	c := StartCapture()
+	exit = func(r int) {}
+	// nothing is stored under the bar prefix
+	os.Args = []string{"dosa", "--connector", "planner", "schema", "plan", "-s", "scope", "--prefix", "bar", "-e", "_test.go", "../../testentity"}
+	main()
+	assert.Contains(t, c.stop(false), "1. compatible: awesome_test_entity: entity added: (an_uuid_key, strkey ASC, int64key DESC)\n")
+}
+
+func TestSchema_Plan_Errors(t *testing.T) {
+	registerPlanConnector(t)
+	f, err := ioutil.TempFile("", "dosa-plan")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	plan := dosa.PlanSchema("scope", "foo", nil, testEntityDefinitions(t))
+	assert.NoError(t, plan.WriteJSON(f))
+	f.Close()
+
+	cases := []struct {
+		args   []string
+		errmsg string
+	}{
+		{
+			args:   []string{"dosa", "--connector", "planner", "schema", "upsert", "-s", "scope", "--prefix", "foo", "--plan-file", "/nonexistent"},
+			errmsg: "no such file",
+		},
+		{
+			args:   []string{"dosa", "--connector", "planner", "schema", "upsert", "-s", "scope", "--prefix", "foo", "--plan-file", "/dev/null"},
+			errmsg: "could not decode schema plan",
+		},
+		{
+			args:   []string{"dosa", "--connector", "planner", "schema", "upsert", "-s", "other", "--prefix", "foo", "--plan-file", f.Name()},
+			errmsg: `the plan is for prefix "foo" in scope "scope"`,
+		},
+		{
+			args:   []string{"dosa", "--connector", "planner", "schema", "upsert", "-s", "scope", "--prefix", "foo", "--plan-file", f.Name(), "../../testentity"},
+			errmsg: "the entities of a plan cannot be changed",
+		},
+	}
+	for _, tc := range cases {
+		c := StartCapture()
+		exit = func(r int) {}
+		os.Args = tc.args
+		main()
+		assert.Contains(t, c.stop(true), tc.errmsg, tc.args)
+	}
+}
+
+// historyConnector serves versions of a stored schema, oldest first
+type historyConnector struct {
+	devnull.Connector
+	versions [][]*dosa.EntityDefinition
+}
+
+func (c *historyConnector) GetSchema(ctx context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	if version == dosa.LatestVersion {
+		version = int32(len(c.versions))
+	}
+	if scope != "scope" || namePrefix != "foo" || version < 1 || int(version) > len(c.versions) {
+		return nil, &dosa.ErrNotFound{}
+	}
+	return c.versions[version-1], nil
+}
+
+func (c *historyConnector) ListSchemaVersions(ctx context.Context, scope, namePrefix string) ([]*dosa.SchemaStatus, error) {
+	if scope != "scope" {
+		return nil, &dosa.ErrNotFound{}
+	}
+	var statuses []*dosa.SchemaStatus
+	if namePrefix == "foo" {
+		for i := range c.versions {
+			statuses = append(statuses, &dosa.SchemaStatus{Version: int32(i + 1), Status: "COMPLETED"})
+		}
+	}
+	return statuses, nil
+}
+
+// registerHistoryConnector registers a connector storing the test entity in
+// version 2, and the test entity without its int32 column in version 1
+func registerHistoryConnector(t *testing.T) {
+	eds := testEntityDefinitions(t)
+	first := *eds[0]
+	first.Columns = nil
+	for _, cd := range eds[0].Columns {
+		if cd.Type != dosa.Int32 {
+			first.Columns = append(first.Columns, cd)
+		}
+	}
+	conn := &historyConnector{versions: [][]*dosa.EntityDefinition{{&first}, eds}}
+	dosa.RegisterConnector("history", func(dosa.CreationArgs) (dosa.Connector, error) {
+		return conn, nil
+	})
+}
+
+func TestSchema_Show(t *testing.T) {
+	registerHistoryConnector(t)
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+
+	// the latest version by default
+	c := StartCapture()
+	os.Args = []string{"dosa", "--connector", "history", "schema", "show", "-s", "scope", "--prefix", "foo", "-f", "json"}
+	main()
+	var eds []*dosa.EntityDefinition
+	assert.NoError(t, json.Unmarshal([]byte(c.stop(false)), &eds))
+	assert.Equal(t, testEntityDefinitions(t), eds)
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "--connector", "history", "schema", "show", "-s", "scope", "--prefix", "foo", "--version", "1"}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, "create table \"awesome_test_entity\"")
+	assert.NotContains(t, output, "int32v")
+}
+
+func TestSchema_History(t *testing.T) {
+	registerHistoryConnector(t)
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+
+	c := StartCapture()
+	os.Args = []string{"dosa", "--connector", "history", "schema", "history", "-s", "scope", "--prefix", "foo"}
+	main()
+	assert.Equal(t, "Version: 1\nStatus: COMPLETED\nVersion: 2\nStatus: COMPLETED\n", c.stop(false))
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "--connector", "history", "schema", "history", "-s", "scope", "--prefix", "foo", "-f", "uql"}
+	main()
+	output := c.stop(false)
+	assert.True(t, strings.HasPrefix(output, "Version: 1\nStatus: COMPLETED\nCREATE TABLE awesome_test_entity"), output)
+	assert.Contains(t, output, "\n\nVersion: 2\nStatus: COMPLETED\n")
+	assert.Equal(t, 1, strings.Count(output, "int32v"))
+}
+
+func TestSchema_History_Errors(t *testing.T) {
+	registerHistoryConnector(t)
+	cases := []struct {
+		args   []string
+		errmsg string
+	}{
+		{
+			args:   []string{"dosa", "--connector", "history", "schema", "history", "-s", "scope", "--prefix", "bar"},
+			errmsg: `no schema "bar" found`,
+		},
+		{
+			args:   []string{"dosa", "--connector", "history", "schema", "history", "-s", "other", "--prefix", "foo"},
+			errmsg: "ListSchemaVersions failed",
+		},
+		{
+			args:   []string{"dosa", "--connector", "history", "schema", "show", "-s", "scope", "--prefix", "foo", "--version", "3"},
+			errmsg: "GetSchema failed",
+		},
+	}
+	for _, tc := range cases {
+		c := StartCapture()
+		exit = func(r int) {}
+		os.Args = tc.args
+		main()
+		assert.Contains(t, c.stop(true), tc.errmsg, "%v", tc.args)
+	}
 }
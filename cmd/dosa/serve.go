@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	_ "github.com/uber-go/dosa/connectors/memory"
+	"github.com/uber-go/dosa/gateway"
+	dosarpc "github.com/uber/dosa-idl/.gen/dosa"
+)
+
+// for testing, we make waiting for a shutdown signal an overridable routine
+var waitForShutdown = func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+}
+
+// ServeCmd contains data for executing the serve command
+type ServeCmd struct {
+	Backend string   `long:"backend" default:"memory" description:"Name of the connector storing the data, e.g. memory."`
+	Scopes  []string `short:"s" long:"scope" description:"Scope to create on startup, can be repeated."`
+}
+
+// Execute runs a local gateway until interrupted. It listens on the global
+// host, port and transport, so other commands can use it without extra flags.
+func (c *ServeCmd) Execute(args []string) error {
+	// same default as the scope commands, see doScopeOp
+	if options.ServiceName == "" {
+		options.ServiceName = _defServiceName // defined in options.go
+	}
+
+	conn, err := dosa.GetConnector(c.Backend, nil)
+	if err != nil {
+		return errors.Wrapf(err, "backend %q", c.Backend)
+	}
+	defer func() { _ = conn.Shutdown() }()
+
+	server, err := gateway.NewServer(&gateway.Config{
+		Transport:   options.Transport,
+		Host:        options.Host,
+		Port:        options.Port,
+		ServiceName: options.ServiceName,
+	}, conn)
+	if err != nil {
+		return err
+	}
+	for _, s := range c.Scopes {
+		scope := s
+		if err := server.Handler.CreateScope(context.Background(), &dosarpc.CreateScopeRequest{Name: &scope}); err != nil {
+			return errors.Wrapf(err, "create scope on %q", scope)
+		}
+	}
+
+	if err := server.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("serving %q backend as %q over %s on %s:%s\n", c.Backend, options.ServiceName, options.Transport, options.Host, options.Port)
+	waitForShutdown()
+	return server.Stop()
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServe(t *testing.T) {
+	defer func(f func()) { waitForShutdown = f }(waitForShutdown)
+	waitForShutdown = func() {}
+	exit = func(r int) {
+		assert.Equal(t, 0, r)
+	}
+	c := StartCapture()
+	os.Args = []string{"dosa", "--service", "", "--transport", "http", "-p", "0", "serve", "--scope", "one", "--scope", "two"}
+	main()
+	output := c.stop(false)
+	assert.Contains(t, output, `"memory" backend`)
+	assert.Contains(t, output, _defServiceName)
+}
+
+func TestServe_Errors(t *testing.T) {
+	defer func(f func()) { waitForShutdown = f }(waitForShutdown)
+	waitForShutdown = func() {}
+	exit = func(r int) {
+		assert.Equal(t, 1, r)
+	}
+
+	c := StartCapture()
+	os.Args = []string{"dosa", "serve", "--backend", "nope"}
+	main()
+	assert.Contains(t, c.stop(true), `backend "nope"`)
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "--transport", "carrier-pigeon", "serve"}
+	main()
+	assert.Contains(t, c.stop(true), "invalid transport")
+
+	c = StartCapture()
+	os.Args = []string{"dosa", "--transport", "http", "serve", "--scope", "Not-Valid"}
+	main()
+	assert.Contains(t, c.stop(true), "Not-Valid")
+}
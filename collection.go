@@ -0,0 +1,273 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Collection types keep the kind of collection in the third byte of a Type,
+// the type of its keys in the second byte and the type of its elements, or
+// of the values of a map, in the first byte.
+const (
+	elemMask       Type = 0xff
+	keyShift            = 8
+	collectionMask Type = 0xff << 16
+	listType       Type = 1 << 16
+	setType        Type = 2 << 16
+	mapType        Type = 3 << 16
+)
+
+// Set is the value type of the Go maps holding set columns: a map[K]Set is a set of K.
+// Other Go maps are map columns, and slices other than []byte are list columns.
+type Set struct{}
+
+var setValueType = reflect.TypeOf(Set{})
+
+// ListOf returns the type of lists of elem, or Invalid if elem is not a
+// primitive type other than Counter
+func ListOf(elem Type) Type {
+	if !elem.isElement() {
+		return Invalid
+	}
+	return listType | elem
+}
+
+// SetOf returns the type of sets of elem, or Invalid if elem is not a
+// primitive type other than Counter and Blob
+func SetOf(elem Type) Type {
+	// blobs cannot be the keys of Go maps
+	if !elem.isElement() || elem == Blob {
+		return Invalid
+	}
+	return setType | elem
+}
+
+// MapOf returns the type of maps from key to value, or Invalid if key is not
+// a primitive type other than Counter and Blob, or value is not a primitive
+// type other than Counter
+func MapOf(key, value Type) Type {
+	if !key.isElement() || key == Blob || !value.isElement() {
+		return Invalid
+	}
+	return mapType | key<<keyShift | value
+}
+
+// IsList checks if t is a list type
+func (t Type) IsList() bool {
+	return t&collectionMask == listType
+}
+
+// IsSet checks if t is a set type
+func (t Type) IsSet() bool {
+	return t&collectionMask == setType
+}
+
+// IsMap checks if t is a map type
+func (t Type) IsMap() bool {
+	return t&collectionMask == mapType
+}
+
+// IsCollection checks if t is a list, set or map type
+func (t Type) IsCollection() bool {
+	return t.IsList() || t.IsSet() || t.IsMap()
+}
+
+// Elem returns the type of the elements of a list or set, or of the values of a map
+func (t Type) Elem() Type {
+	if !t.IsCollection() {
+		return Invalid
+	}
+	return t & elemMask
+}
+
+// Key returns the type of the keys of a map
+func (t Type) Key() Type {
+	if !t.IsMap() {
+		return Invalid
+	}
+	return t >> keyShift & elemMask
+}
+
+// isValid checks if t is a primitive type, or a collection of primitive types
+func (t Type) isValid() bool {
+	switch {
+	case t.IsList():
+		return t == ListOf(t.Elem())
+	case t.IsSet():
+		return t == SetOf(t.Elem())
+	case t.IsMap():
+		return t == MapOf(t.Key(), t.Elem())
+	}
+	return t.isPrimitive()
+}
+
+// isPrimitive checks if t is one of the primitive types
+func (t Type) isPrimitive() bool {
+	return t > Invalid && int(t) < len(_Type_index)-1
+}
+
+// isElement checks if t can be the type of the elements of a collection
+func (t Type) isElement() bool {
+	return t.isPrimitive() && t != Counter
+}
+
+// goType returns the Go type of the values of a valid type
+func goType(t Type) reflect.Type {
+	switch {
+	case t.IsList():
+		return reflect.SliceOf(goType(t.Elem()))
+	case t.IsSet():
+		return reflect.MapOf(goType(t.Elem()), setValueType)
+	case t.IsMap():
+		return reflect.MapOf(goType(t.Key()), goType(t.Elem()))
+	}
+	switch t {
+	case TUUID:
+		return uuidType
+	case String:
+		return stringType
+	case Int32:
+		return int32Type
+	case Int64:
+		return int64Type
+	case Double:
+		return doubleType
+	case Blob:
+		return blobType
+	case Timestamp:
+		return timestampType
+	case Bool:
+		return boolType
+	case TDecimal:
+		return decimalType
+	case TDate:
+		return dateType
+	case Counter:
+		return counterType
+	}
+	return nil
+}
+
+// TypeOf returns the type of the columns that can hold value, or Invalid
+func TypeOf(value FieldValue) Type {
+	if value == nil {
+		return Invalid
+	}
+	t, err := storageType(reflect.TypeOf(value))
+	if err != nil {
+		return Invalid
+	}
+	return t
+}
+
+// MarshalCollection encodes the value of a list, set or map column as a JSON
+// array. Lists keep their order, the elements of sets are sorted, and maps are
+// encoded as arrays of [key, value] pairs, sorted by key.
+func MarshalCollection(value FieldValue) ([]byte, error) {
+	t := TypeOf(value)
+	if !t.IsCollection() {
+		return nil, errors.Errorf("%T is not a collection", value)
+	}
+	v := reflect.ValueOf(value)
+	elems := make([]json.RawMessage, 0, v.Len())
+	if t.IsList() {
+		for i := 0; i < v.Len(); i++ {
+			elem, err := json.Marshal(v.Index(i).Interface())
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot encode element %d", i)
+			}
+			elems = append(elems, elem)
+		}
+		return json.Marshal(elems)
+	}
+	for _, key := range v.MapKeys() {
+		elem, err := json.Marshal(key.Interface())
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot encode key %v", key.Interface())
+		}
+		if t.IsMap() {
+			mapValue, err := json.Marshal(v.MapIndex(key).Interface())
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot encode value of key %v", key.Interface())
+			}
+			elem, _ = json.Marshal([]json.RawMessage{elem, mapValue})
+		}
+		elems = append(elems, elem)
+	}
+	sort.Sort(byBytes(elems))
+	return json.Marshal(elems)
+}
+
+// UnmarshalCollection decodes the value of a collection column of type t, encoded by MarshalCollection
+func UnmarshalCollection(t Type, data []byte) (FieldValue, error) {
+	if !t.IsCollection() || !t.isValid() {
+		return nil, errors.Errorf("%v is not a collection type", t)
+	}
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return nil, errors.Wrapf(err, "invalid %v", t)
+	}
+	typ := goType(t)
+	if t.IsList() {
+		list := reflect.MakeSlice(typ, len(elems), len(elems))
+		for i, elem := range elems {
+			if err := json.Unmarshal(elem, list.Index(i).Addr().Interface()); err != nil {
+				return nil, errors.Wrapf(err, "invalid element %d of %v", i, t)
+			}
+		}
+		return list.Interface(), nil
+	}
+	collection := reflect.MakeMap(typ)
+	for i, elem := range elems {
+		key := reflect.New(typ.Key())
+		value := reflect.ValueOf(Set{})
+		if t.IsMap() {
+			var pair []json.RawMessage
+			if err := json.Unmarshal(elem, &pair); err != nil || len(pair) != 2 {
+				return nil, errors.Errorf("invalid entry %d of %v: not a [key, value] pair", i, t)
+			}
+			elem = pair[0]
+			value = reflect.New(typ.Elem())
+			if err := json.Unmarshal(pair[1], value.Interface()); err != nil {
+				return nil, errors.Wrapf(err, "invalid value %d of %v", i, t)
+			}
+			value = value.Elem()
+		}
+		if err := json.Unmarshal(elem, key.Interface()); err != nil {
+			return nil, errors.Wrapf(err, "invalid key %d of %v", i, t)
+		}
+		collection.SetMapIndex(key.Elem(), value)
+	}
+	return collection.Interface(), nil
+}
+
+// byBytes sorts encoded elements
+type byBytes []json.RawMessage
+
+func (b byBytes) Len() int           { return len(b) }
+func (b byBytes) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byBytes) Less(i, j int) bool { return bytes.Compare(b[i], b[j]) < 0 }
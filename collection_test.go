@@ -0,0 +1,166 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionTypes(t *testing.T) {
+	data := []struct {
+		typ   Type
+		name  string
+		elem  Type
+		key   Type
+		goVal interface{}
+	}{
+		{typ: ListOf(Int64), name: "List<Int64>", elem: Int64, goVal: []int64{}},
+		{typ: ListOf(Blob), name: "List<Blob>", elem: Blob, goVal: [][]byte{}},
+		{typ: SetOf(TUUID), name: "Set<TUUID>", elem: TUUID, goVal: map[UUID]Set{}},
+		{typ: MapOf(String, Timestamp), name: "Map<String,Timestamp>", elem: Timestamp, key: String, goVal: map[string]time.Time{}},
+	}
+	for _, d := range data {
+		assert.True(t, d.typ.IsCollection(), d.name)
+		assert.True(t, d.typ.isValid(), d.name)
+		assert.Equal(t, d.name, d.typ.String())
+		assert.Equal(t, d.typ, FromString(d.name))
+		assert.Equal(t, d.elem, d.typ.Elem())
+		assert.Equal(t, d.key, d.typ.Key())
+		assert.Equal(t, d.typ, TypeOf(d.goVal))
+	}
+	assert.True(t, ListOf(Bool).IsList())
+	assert.True(t, SetOf(Bool).IsSet())
+	assert.True(t, MapOf(Bool, Bool).IsMap())
+	assert.False(t, Int64.IsCollection())
+	assert.Equal(t, Invalid, Int64.Elem())
+
+	// collections hold primitive types other than counters, and blobs cannot be keys
+	for _, invalid := range []Type{
+		ListOf(Counter), ListOf(Invalid), ListOf(ListOf(Int64)),
+		SetOf(Blob), MapOf(Blob, Int64), MapOf(Int64, SetOf(Int64)),
+	} {
+		assert.Equal(t, Invalid, invalid)
+	}
+	for _, name := range []string{"List<Counter>", "Set<Blob>", "Map<Int64>", "Map<Int64,Int64", "Array<Int64>", "Type(65536)"} {
+		assert.Equal(t, Invalid, FromString(name), name)
+	}
+	assert.Equal(t, "List<Invalid>", Type(listType).String())
+	assert.False(t, Type(listType).isValid())
+	assert.Equal(t, Invalid, TypeOf(nil))
+	assert.Equal(t, Invalid, TypeOf([][]int64{}))
+	assert.Equal(t, Invalid, TypeOf(map[string]float32{}))
+}
+
+func TestMarshalCollection(t *testing.T) {
+	ts := time.Unix(1500000000, 0).UTC()
+	data := []struct {
+		value   FieldValue
+		encoded string
+	}{
+		{value: []int64{3, 1, 2}, encoded: `[3,1,2]`},
+		{value: [][]byte{{1}, nil}, encoded: `["AQ==",null]`},
+		{value: map[string]Set{"b": {}, "a": {}}, encoded: `["a","b"]`},
+		{value: map[UUID]Set{}, encoded: `[]`},
+		{value: map[int32]time.Time{2: ts, 1: ts}, encoded: `[[1,"2017-07-14T02:40:00Z"],[2,"2017-07-14T02:40:00Z"]]`},
+		{value: map[bool]float64{true: 0.5}, encoded: `[[true,0.5]]`},
+	}
+	for _, d := range data {
+		encoded, err := MarshalCollection(d.value)
+		assert.NoError(t, err)
+		assert.Equal(t, d.encoded, string(encoded))
+		decoded, err := UnmarshalCollection(TypeOf(d.value), encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, d.value, decoded)
+	}
+
+	_, err := MarshalCollection([]byte{})
+	assert.Contains(t, err.Error(), "not a collection")
+	_, err = UnmarshalCollection(Int64, []byte(`[]`))
+	assert.Contains(t, err.Error(), "not a collection type")
+	_, err = UnmarshalCollection(ListOf(Int64), []byte(`{}`))
+	assert.Contains(t, err.Error(), "invalid List<Int64>")
+	_, err = UnmarshalCollection(ListOf(Int64), []byte(`["a"]`))
+	assert.Contains(t, err.Error(), "invalid element 0")
+	_, err = UnmarshalCollection(SetOf(Int64), []byte(`["a"]`))
+	assert.Contains(t, err.Error(), "invalid key 0")
+	_, err = UnmarshalCollection(MapOf(Int64, Int64), []byte(`[[1]]`))
+	assert.Contains(t, err.Error(), "not a [key, value] pair")
+	_, err = UnmarshalCollection(MapOf(Int64, Int64), []byte(`[[1,"a"]]`))
+	assert.Contains(t, err.Error(), "invalid value 0")
+}
+
+type CollectionTypes struct {
+	Entity    `dosa:"primaryKey=ID"`
+	ID        string
+	Tags      []string
+	Blobs     [][]byte
+	Labels    map[string]Set
+	Attrs     map[string]string
+	Positions map[int32]time.Time
+}
+
+func TestCollectionTypesFromInstance(t *testing.T) {
+	dosaTable, err := TableFromInstance(&CollectionTypes{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Type{
+		"id":        String,
+		"tags":      ListOf(String),
+		"blobs":     ListOf(Blob),
+		"labels":    SetOf(String),
+		"attrs":     MapOf(String, String),
+		"positions": MapOf(Int32, Timestamp),
+	}, dosaTable.ColumnTypes())
+}
+
+type CollectionKey struct {
+	Entity `dosa:"primaryKey=Tags"`
+	Tags   []string
+}
+
+func TestCollectionKey(t *testing.T) {
+	_, err := TableFromInstance(&CollectionKey{})
+	assert.Contains(t, err.Error(), "a collection column cannot be used in key: \"tags\"")
+}
+
+func TestStringToDosaCollectionType(t *testing.T) {
+	data := map[string]Type{
+		"[]string":                ListOf(String),
+		"[][]byte":                ListOf(Blob),
+		"[]dosa.UUID":             ListOf(TUUID),
+		"map[string]dosa.Set":     SetOf(String),
+		"map[int64]time.Time":     MapOf(Int64, Timestamp),
+		"map[dosa.UUID][]byte":    MapOf(TUUID, Blob),
+		"[][]string":              Invalid,
+		"[]dosa.CounterValue":     Invalid,
+		"map[string]renamed.Set":  Invalid,
+		"map[string]map[int]int":  Invalid,
+		"map[string":              Invalid,
+		"map[]string":             Invalid,
+		"[]float32":               Invalid,
+		"map[string]dosa.Unknown": Invalid,
+	}
+	for kind, expected := range data {
+		assert.Equal(t, expected, stringToDosaType(kind, "dosa"), kind)
+	}
+}
@@ -90,6 +90,15 @@ type SchemaStatus struct {
 	Status string
 }
 
+// The application statuses of schema versions reported by the connectors that
+// store schemas
+const (
+	// SchemaStatusAccepted is the status of a version upserted but not applied yet
+	SchemaStatusAccepted = "ACCEPTED"
+	// SchemaStatusCompleted is the status of an applied version
+	SchemaStatusCompleted = "COMPLETED"
+)
+
 // Connector is the interface that must be implemented for a backend service
 // It can also be implemented using an RPC such as thrift (dosa-idl)
 // When fields are returned from read/range/search/scan methods, it's legal for the connector
@@ -131,6 +140,10 @@ type Connector interface {
 	UpsertSchema(ctx context.Context, scope string, namePrefix string, ed []*EntityDefinition) (status *SchemaStatus, err error)
 	// CheckSchemaStatus checks the status of the schema whether it is accepted or in progress of application.
 	CheckSchemaStatus(ctx context.Context, scope string, namePrefix string, version int32) (*SchemaStatus, error)
+	// GetSchema returns the entity definitions of a version of the schema, or of its latest version for LatestVersion
+	GetSchema(ctx context.Context, scope string, namePrefix string, version int32) ([]*EntityDefinition, error)
+	// ListSchemaVersions returns the status of every version of the schema, oldest first
+	ListSchemaVersions(ctx context.Context, scope string, namePrefix string) ([]*SchemaStatus, error)
 
 	// Datastore management
 	// CreateScope creates a scope for storage of data, usually implemented by a keyspace for this data
@@ -151,6 +151,22 @@ func (c *Connector) CheckSchemaStatus(ctx context.Context, scope string, namePre
 	return c.Next.CheckSchemaStatus(ctx, scope, namePrefix, version)
 }
 
+// GetSchema calls Next
+func (c *Connector) GetSchema(ctx context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	if c.Next == nil {
+		return nil, ErrNoMoreConnector{}
+	}
+	return c.Next.GetSchema(ctx, scope, namePrefix, version)
+}
+
+// ListSchemaVersions calls Next
+func (c *Connector) ListSchemaVersions(ctx context.Context, scope, namePrefix string) ([]*dosa.SchemaStatus, error) {
+	if c.Next == nil {
+		return nil, ErrNoMoreConnector{}
+	}
+	return c.Next.ListSchemaVersions(ctx, scope, namePrefix)
+}
+
 // CreateScope calls Next
 func (c *Connector) CreateScope(ctx context.Context, scope string) error {
 	if c.Next == nil {
@@ -182,6 +182,24 @@ func TestBase_Shutdown(t *testing.T) {
 	assert.NoError(t, bcWNext.Shutdown())
 }
 
+func TestBase_GetSchema(t *testing.T) {
+	_, err := bc.GetSchema(ctx, "testScope", "testPrefix", dosa.LatestVersion)
+	assert.Error(t, err)
+
+	eds, err := bcWNext.GetSchema(ctx, "testScope", "testPrefix", dosa.LatestVersion)
+	assert.NotNil(t, eds)
+	assert.NoError(t, err)
+}
+
+func TestBase_ListSchemaVersions(t *testing.T) {
+	_, err := bc.ListSchemaVersions(ctx, "testScope", "testPrefix")
+	assert.Error(t, err)
+
+	statuses, err := bcWNext.ListSchemaVersions(ctx, "testScope", "testPrefix")
+	assert.NotNil(t, statuses)
+	assert.NoError(t, err)
+}
+
 func TestBase_CheckSchemaStatus(t *testing.T) {
 	_, err := bc.CheckSchemaStatus(ctx, "testScope", "testPrefix", int32(1))
 	assert.Error(t, err)
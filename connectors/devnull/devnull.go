@@ -115,6 +115,16 @@ func (c *Connector) CheckSchemaStatus(ctx context.Context, scope, namePrefix str
 	}, nil
 }
 
+// GetSchema always returns an empty schema
+func (c *Connector) GetSchema(ctx context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	return []*dosa.EntityDefinition{}, nil
+}
+
+// ListSchemaVersions always returns version 1 with ACCEPTED status, matching CheckSchemaStatus
+func (c *Connector) ListSchemaVersions(ctx context.Context, scope, namePrefix string) ([]*dosa.SchemaStatus, error) {
+	return []*dosa.SchemaStatus{{Version: int32(1), Status: "ACCEPTED"}}, nil
+}
+
 // CreateScope returns success
 func (c *Connector) CreateScope(ctx context.Context, scope string) error {
 	return nil
@@ -127,6 +127,18 @@ func TestDevNull_CheckSchemaStatus(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDevNull_GetSchema(t *testing.T) {
+	eds, err := sut.GetSchema(ctx, "testScope", "testPrefix", dosa.LatestVersion)
+	assert.Empty(t, eds)
+	assert.NoError(t, err)
+}
+
+func TestDevNull_ListSchemaVersions(t *testing.T) {
+	statuses, err := sut.ListSchemaVersions(ctx, "testScope", "testPrefix")
+	assert.Len(t, statuses, 1)
+	assert.NoError(t, err)
+}
+
 func TestDevNull_UpsertSchema(t *testing.T) {
 	defs := make([]*dosa.EntityDefinition, 4)
 	status, err := sut.UpsertSchema(ctx, "testScope", "testPrefix", defs)
@@ -0,0 +1,292 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/uber-go/dosa"
+)
+
+// Row keys are built from an order preserving encoding of the key columns, so
+// that iterating over a bucket returns the rows of a partition in the same
+// order as the memory connector keeps them. Each encoded column is prefix free:
+// fixed width types are written big-endian, and variable width types are
+// escaped and terminated. Descending columns have all their bytes inverted.
+const (
+	escapeByte     = 0x00
+	escapedByte    = 0xff
+	terminatorByte = 0x01
+)
+
+// elementValues has a value of each type that can be the element of a
+// collection column
+var elementValues = []dosa.FieldValue{
+	dosa.UUID(""), "", int32(0), int64(0), float64(0), []byte{}, time.Time{}, false, dosa.Decimal(""), dosa.Date{},
+}
+
+// init registers the types of all the column values with gob, since rows are
+// encoded as maps of interfaces. The Go types of collection columns are
+// built from the types of their elements.
+func init() {
+	gob.Register(dosa.CounterValue(0))
+	setType := reflect.TypeOf(dosa.Set{})
+	for _, elem := range elementValues {
+		gob.Register(elem)
+		elemType := reflect.TypeOf(elem)
+		gob.Register(reflect.Zero(reflect.SliceOf(elemType)).Interface())
+		// blobs cannot be the keys of sets and maps
+		if elemType.Kind() == reflect.Slice {
+			continue
+		}
+		gob.Register(reflect.Zero(reflect.MapOf(elemType, setType)).Interface())
+		for _, value := range elementValues {
+			gob.Register(reflect.Zero(reflect.MapOf(elemType, reflect.TypeOf(value))).Interface())
+		}
+	}
+}
+
+// encodeKey builds the key of a row: the partition key columns followed by the
+// clustering key columns
+func encodeKey(ed *dosa.EntityDefinition, values map[string]dosa.FieldValue) ([]byte, error) {
+	key, err := encodePartitionKey(ed, values)
+	if err != nil {
+		return nil, err
+	}
+	for _, ck := range ed.Key.ClusteringKeys {
+		v, ok := values[ck.Name]
+		if !ok || v == nil {
+			return nil, errors.Errorf("missing value for clustering key %q", ck.Name)
+		}
+		if key, err = encodeColumn(key, v, ck.Descending); err != nil {
+			return nil, errors.Wrapf(err, "clustering key %q", ck.Name)
+		}
+	}
+	return key, nil
+}
+
+// encodePartitionKey builds the common prefix of the keys of all the rows in a partition
+func encodePartitionKey(ed *dosa.EntityDefinition, values map[string]dosa.FieldValue) ([]byte, error) {
+	var key []byte
+	for _, pk := range ed.Key.PartitionKeys {
+		v, ok := values[pk]
+		if !ok || v == nil {
+			return nil, errors.Errorf("missing value for partition key %q", pk)
+		}
+		var err error
+		if key, err = encodeColumn(key, v, false); err != nil {
+			return nil, errors.Wrapf(err, "partition key %q", pk)
+		}
+	}
+	return key, nil
+}
+
+// encodeColumn appends the encoding of a single value to buf
+func encodeColumn(buf []byte, v dosa.FieldValue, descending bool) ([]byte, error) {
+	start := len(buf)
+	buf, err := encodeValue(buf, v)
+	if err != nil {
+		return nil, err
+	}
+	if descending {
+		for i := start; i < len(buf); i++ {
+			buf[i] = ^buf[i]
+		}
+	}
+	return buf, nil
+}
+
+// encodeValue appends the ascending encoding of a value to buf. The ordering
+// of the encoded values is the one implemented by the memory connector.
+func encodeValue(buf []byte, v dosa.FieldValue) ([]byte, error) {
+	switch v := v.(type) {
+	case dosa.UUID:
+		// UUIDs are ordered by version first, then time UUIDs by their
+		// timestamp, and other UUIDs by their string representation
+		u := uuid.FromStringOrNil(string(v))
+		buf = append(buf, u.Version())
+		if u.Version() == 1 {
+			return appendUint64(buf, uint64(timeFromUUID(u))), nil
+		}
+		return appendEscaped(buf, []byte(v)), nil
+	case string:
+		return appendEscaped(buf, []byte(v)), nil
+	case []byte:
+		return appendEscaped(buf, v), nil
+	case int64:
+		return appendUint64(buf, uint64(v)^(1<<63)), nil
+	case int32:
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(v)^(1<<31))
+		return append(buf, b[:]...), nil
+	case float64:
+		bits := math.Float64bits(v)
+		if v == 0 {
+			// -0 and +0 are equal
+			bits = 0
+		}
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		return appendUint64(buf, bits), nil
+	case time.Time:
+		return appendUint64(buf, uint64(v.UnixNano())^(1<<63)), nil
+	case bool:
+		if v {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case dosa.Decimal:
+		return appendDecimal(buf, v)
+	case dosa.Date:
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(v.Days())^(1<<31))
+		return append(buf, b[:]...), nil
+	}
+	return nil, errors.Errorf("unsupported key type %T", v)
+}
+
+// appendDecimal appends the encoding of a decimal number, which is ordered
+// numerically like Decimal.Cmp: a sign byte, then the position of the
+// decimal point relative to the first significant digit, then the
+// significant digits. Both are inverted for negative numbers. The canonical
+// form is encoded, so equal numbers like "1.5" and "1.50" have the same encoding.
+func appendDecimal(buf []byte, d dosa.Decimal) ([]byte, error) {
+	canonical, err := d.Canonical()
+	if err != nil {
+		return nil, err
+	}
+	s := string(canonical)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	digits := strings.TrimRight(intPart+fracPart, "0")
+	exponent := len(intPart)
+	if intPart == "" {
+		trimmed := strings.TrimLeft(digits, "0")
+		exponent = len(trimmed) - len(digits)
+		digits = trimmed
+	}
+	if digits == "" {
+		// zero sorts between negative and positive numbers
+		return append(buf, 1), nil
+	}
+	sign := byte(2)
+	if negative {
+		sign = 0
+	}
+	buf = append(buf, sign)
+	start := len(buf)
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(int32(exponent))^(1<<31))
+	buf = appendEscaped(append(buf, b[:]...), []byte(digits))
+	if negative {
+		for i := start; i < len(buf); i++ {
+			buf[i] = ^buf[i]
+		}
+	}
+	return buf, nil
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// appendEscaped appends variable length data, escaping the escape byte and
+// terminating it, so that shorter values sort before longer ones
+func appendEscaped(buf []byte, data []byte) []byte {
+	for _, b := range data {
+		if b == escapeByte {
+			buf = append(buf, escapeByte, escapedByte)
+			continue
+		}
+		buf = append(buf, b)
+	}
+	return append(buf, escapeByte, terminatorByte)
+}
+
+// compareValues compares two values of the same type, using the key encoding.
+// Collections cannot be keys, and are compared by their canonical encoding,
+// which only tells if they are equal.
+func compareValues(v1, v2 dosa.FieldValue) (int, error) {
+	if dosa.TypeOf(v1).IsCollection() {
+		e1, err := dosa.MarshalCollection(v1)
+		if err != nil {
+			return 0, err
+		}
+		e2, err := dosa.MarshalCollection(v2)
+		if err != nil {
+			return 0, err
+		}
+		return bytes.Compare(e1, e2), nil
+	}
+	e1, err := encodeValue(nil, v1)
+	if err != nil {
+		return 0, err
+	}
+	e2, err := encodeValue(nil, v2)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.Compare(e1, e2), nil
+}
+
+// timeFromUUID returns the time bits from a version 1 UUID, see RFC 4122
+func timeFromUUID(u uuid.UUID) int64 {
+	low := int64(binary.BigEndian.Uint32(u[0:4]))
+	mid := int64(binary.BigEndian.Uint16(u[4:6]))
+	hi := int64((binary.BigEndian.Uint16(u[6:8]) & 0x0fff))
+	return low + (mid << 32) + (hi << 48)
+}
+
+// encodeRow serializes all the values of a row
+func encodeRow(values map[string]dosa.FieldValue) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, errors.Wrap(err, "failed to encode row")
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRow deserializes a row written by encodeRow
+func decodeRow(data []byte) (map[string]dosa.FieldValue, error) {
+	values := map[string]dosa.FieldValue{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, errors.Wrap(err, "failed to decode row")
+	}
+	return values, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func TestEncodeValue_Ordering(t *testing.T) {
+	v1a := dosa.UUID(uuid.NewV1().String())
+	v1b := dosa.UUID(uuid.NewV1().String())
+	now := time.Now()
+	// each list is in ascending order
+	tcs := [][]dosa.FieldValue{
+		{"", "\x00", "\x00\x00", "a", "a\x00", "ab", "b"},
+		{[]byte{}, []byte{0}, []byte{0, 0xff}, []byte{1}},
+		{int64(math.MinInt64), int64(-1), int64(0), int64(1), int64(math.MaxInt64)},
+		{int32(math.MinInt32), int32(-1), int32(0), int32(1), int32(math.MaxInt32)},
+		{math.Inf(-1), -2.5, -1e-300, 0.0, 1e-300, 2.5, math.Inf(1)},
+		{now.Add(-time.Hour), now, now.Add(time.Nanosecond)},
+		{false, true},
+		{dosa.Decimal("-100"), dosa.Decimal("-20.5"), dosa.Decimal("-20"), dosa.Decimal("-0.05"), dosa.Decimal("0"),
+			dosa.Decimal("0.005"), dosa.Decimal("0.05"), dosa.Decimal("0.5"), dosa.Decimal("1"), dosa.Decimal("1.05"),
+			dosa.Decimal("1.5"), dosa.Decimal("20"), dosa.Decimal("100")},
+		{dosa.NewDate(1969, time.December, 31), dosa.NewDate(1970, time.January, 1), dosa.NewDate(2017, time.March, 4)},
+		// time UUIDs sort by time, before random UUIDs
+		{v1a, v1b, dosa.UUID("00000000-0000-4000-8000-000000000000"), dosa.UUID("ffffffff-ffff-4fff-bfff-ffffffffffff")},
+	}
+	for _, tc := range tcs {
+		for i := 1; i < len(tc); i++ {
+			cmp, err := compareValues(tc[i-1], tc[i])
+			assert.NoError(t, err)
+			assert.Equal(t, -1, cmp, "%v < %v", tc[i-1], tc[i])
+
+			// descending columns sort the other way around
+			e1, err := encodeColumn(nil, tc[i-1], true)
+			assert.NoError(t, err)
+			e2, err := encodeColumn(nil, tc[i], true)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, bytes.Compare(e1, e2), "%v > %v", tc[i-1], tc[i])
+		}
+	}
+
+	cmp, err := compareValues(-0.0, math.Copysign(0, -1))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cmp)
+
+	// decimals that only differ by trailing or leading zeros are equal
+	for _, pair := range [][]dosa.Decimal{{"1.5", "01.50"}, {"-0", "0.000"}, {"+2", "2.0"}} {
+		cmp, err = compareValues(pair[0], pair[1])
+		assert.NoError(t, err)
+		assert.Equal(t, 0, cmp, "%v == %v", pair[0], pair[1])
+	}
+	_, err = encodeValue(nil, dosa.Decimal("1e5"))
+	assert.Error(t, err)
+
+	_, err = encodeValue(nil, struct{}{})
+	assert.Error(t, err)
+}
+
+func TestEncodeKey_PrefixFree(t *testing.T) {
+	ed := &dosa.EntityDefinition{
+		Name: "t",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"p1", "p2"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "c", Descending: true}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "p1", Type: dosa.String},
+			{Name: "p2", Type: dosa.String},
+			{Name: "c", Type: dosa.String},
+		},
+	}
+	// the partition ("a", "bc") must not be a prefix of the partition ("ab", "c")
+	k1, err := encodePartitionKey(ed, map[string]dosa.FieldValue{"p1": "a", "p2": "bc"})
+	assert.NoError(t, err)
+	k2, err := encodeKey(ed, map[string]dosa.FieldValue{"p1": "ab", "p2": "c", "c": "x"})
+	assert.NoError(t, err)
+	assert.False(t, bytes.HasPrefix(k2, k1))
+
+	_, err = encodeKey(ed, map[string]dosa.FieldValue{"p1": "a", "p2": "b"})
+	assert.Error(t, err)
+}
+
+func TestEncodeRow(t *testing.T) {
+	row := map[string]dosa.FieldValue{
+		"s": "x", "i32": int32(1), "i64": int64(2), "d": 1.5, "b": true,
+		"blob": []byte{1}, "id": dosa.NewUUID(), "ts": time.Unix(10, 5).UTC(),
+		"dec": dosa.Decimal("-1.50"), "date": dosa.NewDate(2017, time.March, 4),
+	}
+	data, err := encodeRow(row)
+	assert.NoError(t, err)
+	decoded, err := decodeRow(data)
+	assert.NoError(t, err)
+	assert.Equal(t, row, decoded)
+
+	_, err = decodeRow([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestEncodeRow_Collections(t *testing.T) {
+	row := map[string]dosa.FieldValue{
+		"strings":  []string{"a", "b"},
+		"uuids":    []dosa.UUID{dosa.NewUUID()},
+		"blobs":    [][]byte{{1}, {0}},
+		"times":    []time.Time{time.Unix(10, 5).UTC()},
+		"decimals": []dosa.Decimal{"1.50", "-2"},
+		"dates":    []dosa.Date{dosa.NewDate(2017, time.March, 4)},
+		"set":      map[int32]dosa.Set{1: {}, 2: {}},
+		"dateSet":  map[dosa.Date]dosa.Set{dosa.NewDate(2017, time.March, 4): {}},
+		"map":      map[string]int64{"a": 1, "b": 2},
+		"uuidMap":  map[dosa.UUID]dosa.Decimal{dosa.NewUUID(): "0.5"},
+		"blobMap":  map[bool][]byte{true: {1, 2}},
+	}
+	data, err := encodeRow(row)
+	assert.NoError(t, err)
+	decoded, err := decodeRow(data)
+	assert.NoError(t, err)
+	assert.Equal(t, row, decoded)
+}
@@ -0,0 +1,723 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package file contains a connector persisting data in a local BoltDB file,
+// for development environments and small tools that need their data to
+// survive a restart.
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	name = "file"
+
+	// _defaultPath is used when no path is provided in the creation arguments
+	_defaultPath = "dosa.db"
+)
+
+var (
+	dataBucket   = []byte("data")
+	schemaBucket = []byte("schema")
+)
+
+// Connector is a connector storing data in a BoltDB file.
+// The file has one top level bucket per scope, each containing:
+//
+// - a "schema" bucket mapping the name prefix and version to the list of entity definitions
+// - a "data" bucket with one bucket per table (name prefix and entity name)
+//
+// Within a table, the key of a row is an order preserving encoding of its
+// partition key followed by its clustering key, and the value is the
+// gob-encoded row. All the rows of a partition are thus stored next to each
+// other, in clustering key order.
+type Connector struct {
+	db *bolt.DB
+}
+
+// NewConnector opens or creates the database file at the given path
+func NewConnector(path string) (*Connector, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %q", path)
+	}
+	return &Connector{db: db}, nil
+}
+
+// CreateIfNotExists inserts a row, failing if it already exists
+func (c *Connector) CreateIfNotExists(_ context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, ei, values, func(into, from map[string]dosa.FieldValue) error {
+			return &dosa.ErrAlreadyExists{}
+		})
+	})
+}
+
+// Upsert inserts a row, or merges the values into the existing row
+func (c *Connector) Upsert(_ context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, ei, values, mergeValues)
+	})
+}
+
+// MultiUpsert upserts several rows in one transaction
+func (c *Connector) MultiUpsert(_ context.Context, ei *dosa.EntityInfo, multiValues []map[string]dosa.FieldValue) ([]error, error) {
+	results := make([]error, len(multiValues))
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if _, err := scopeBucket(tx, ei.Ref.Scope); err != nil {
+			return err
+		}
+		for i, values := range multiValues {
+			results[i] = put(tx, ei, values, mergeValues)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Increment atomically adds delta to a counter column, creating the row if needed
+func (c *Connector) Increment(_ context.Context, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue, column string, delta int64) (dosa.CounterValue, error) {
+	cd := ei.Def.FindColumnDefinition(column)
+	if cd == nil {
+		return 0, errors.Errorf("column %q not found in entity %q", column, ei.Def.Name)
+	}
+	if cd.Type != dosa.Counter {
+		return 0, errors.Errorf("column %q of entity %q is not a counter", column, ei.Def.Name)
+	}
+
+	values := make(map[string]dosa.FieldValue)
+	for name := range ei.Def.KeySet() {
+		values[name] = keys[name]
+	}
+	value := dosa.CounterValue(delta)
+	values[column] = value
+	// bolt runs one read-write transaction at a time, so reading and writing the
+	// counter is atomic
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, ei, values, func(into, _ map[string]dosa.FieldValue) error {
+			current, _ := into[column].(dosa.CounterValue)
+			value = current + dosa.CounterValue(delta)
+			into[column] = value
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// Read fetches a row by primary key
+func (c *Connector) Read(_ context.Context, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue, minimumFields []string) (values map[string]dosa.FieldValue, err error) {
+	err = c.db.View(func(tx *bolt.Tx) error {
+		values, err = get(tx, ei, keys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return project(values, minimumFields), nil
+}
+
+// MultiRead fetches several rows by primary key in one transaction
+func (c *Connector) MultiRead(_ context.Context, ei *dosa.EntityInfo, multiKeys []map[string]dosa.FieldValue, minimumFields []string) ([]*dosa.FieldValuesOrError, error) {
+	results := make([]*dosa.FieldValuesOrError, len(multiKeys))
+	err := c.db.View(func(tx *bolt.Tx) error {
+		if _, err := scopeBucket(tx, ei.Ref.Scope); err != nil {
+			return err
+		}
+		for i, keys := range multiKeys {
+			values, err := get(tx, ei, keys)
+			if err == nil {
+				values = project(values, minimumFields)
+			}
+			results[i] = &dosa.FieldValuesOrError{Values: values, Error: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Remove deletes a row by primary key; removing a missing row is not an error
+func (c *Connector) Remove(_ context.Context, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return remove(tx, ei, keys)
+	})
+}
+
+// MultiRemove deletes several rows in one transaction
+func (c *Connector) MultiRemove(_ context.Context, ei *dosa.EntityInfo, multiKeys []map[string]dosa.FieldValue) ([]error, error) {
+	results := make([]error, len(multiKeys))
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if _, err := scopeBucket(tx, ei.Ref.Scope); err != nil {
+			return err
+		}
+		for i, keys := range multiKeys {
+			results[i] = remove(tx, ei, keys)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// RemoveRange removes all the rows matching the conditions
+func (c *Connector) RemoveRange(_ context.Context, ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tableBucket(tx, ei.Ref, false)
+		if err != nil || b == nil {
+			return err
+		}
+		prefix, err := rangePrefix(ei, columnConditions)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		err = iterate(b, prefix, "", 0, func(k []byte, values map[string]dosa.FieldValue) (bool, error) {
+			ok, err := matchesClusteringConditions(ei, columnConditions, values)
+			if ok {
+				// keys are only valid until the bucket is modified
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return ok, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Range returns the rows of a partition matching the conditions, in clustering key order
+func (c *Connector) Range(_ context.Context, ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition, minimumFields []string, token string, limit int) ([]map[string]dosa.FieldValue, string, error) {
+	prefix, err := rangePrefix(ei, columnConditions)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.page(ei, prefix, minimumFields, token, limit, func(values map[string]dosa.FieldValue) (bool, error) {
+		return matchesClusteringConditions(ei, columnConditions, values)
+	})
+}
+
+// Search returns the rows with the given value for a column. There are no
+// indexes, so this scans the whole table.
+func (c *Connector) Search(_ context.Context, ei *dosa.EntityInfo, fieldPair dosa.FieldNameValuePair, minimumFields []string, token string, limit int) ([]map[string]dosa.FieldValue, string, error) {
+	if ei.Def.FindColumnDefinition(fieldPair.Name) == nil {
+		return nil, "", errors.Errorf("column %q not found in entity %q", fieldPair.Name, ei.Def.Name)
+	}
+	return c.page(ei, nil, minimumFields, token, limit, func(values map[string]dosa.FieldValue) (bool, error) {
+		v, ok := values[fieldPair.Name]
+		if !ok || v == nil {
+			return false, nil
+		}
+		cmp, err := compareValues(v, fieldPair.Value)
+		return cmp == 0, err
+	})
+}
+
+// Scan returns all the rows of a table, ordered by key
+func (c *Connector) Scan(_ context.Context, ei *dosa.EntityInfo, minimumFields []string, token string, limit int) ([]map[string]dosa.FieldValue, string, error) {
+	return c.page(ei, nil, minimumFields, token, limit, func(map[string]dosa.FieldValue) (bool, error) {
+		return true, nil
+	})
+}
+
+// page returns up to limit rows of the table whose key starts with prefix and
+// which match the filter, projected on minimumFields, starting after the row
+// identified by the token. A limit of 0 or less returns all the rows. The
+// returned token is empty when there are no more rows.
+func (c *Connector) page(ei *dosa.EntityInfo, prefix []byte, minimumFields []string, token string, limit int, filter func(map[string]dosa.FieldValue) (bool, error)) ([]map[string]dosa.FieldValue, string, error) {
+	var keys [][]byte
+	results := []map[string]dosa.FieldValue{}
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b, err := tableBucket(tx, ei.Ref, false)
+		if err != nil || b == nil {
+			return err
+		}
+		// fetch one more row than requested to know if there are more
+		fetch := 0
+		if limit > 0 {
+			fetch = limit + 1
+		}
+		return iterate(b, prefix, token, fetch, func(k []byte, values map[string]dosa.FieldValue) (bool, error) {
+			ok, err := filter(values)
+			if ok && err == nil {
+				// keys are only valid during the transaction
+				keys = append(keys, append([]byte(nil), k...))
+				results = append(results, project(values, minimumFields))
+			}
+			return ok, err
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if limit > 0 && len(results) > limit {
+		return results[:limit], base64.StdEncoding.EncodeToString(keys[limit-1]), nil
+	}
+	return results, "", nil
+}
+
+// project returns the requested fields of a row, or all of them if none were
+// requested. Rows are decoded for every read, so they need not be copied.
+func project(values map[string]dosa.FieldValue, minimumFields []string) map[string]dosa.FieldValue {
+	if len(minimumFields) == 0 {
+		return values
+	}
+	projected := make(map[string]dosa.FieldValue, len(minimumFields))
+	for _, name := range minimumFields {
+		if value, ok := values[name]; ok {
+			projected[name] = value
+		}
+	}
+	return projected
+}
+
+// CheckSchema returns the latest version registered with exactly the provided entity definitions
+func (c *Connector) CheckSchema(_ context.Context, scope, namePrefix string, eds []*dosa.EntityDefinition) (version int32, err error) {
+	version = dosa.InvalidVersion
+	err = c.db.View(func(tx *bolt.Tx) error {
+		sb, err := scopeBucket(tx, scope)
+		if err != nil {
+			return err
+		}
+		versions, err := schemaVersions(sb.Bucket(schemaBucket), namePrefix)
+		if err != nil {
+			return err
+		}
+		if sv := dosa.MatchingSchemaVersion(versions, eds); sv != nil {
+			version = sv.Version
+			return nil
+		}
+		return errors.Wrapf(&dosa.ErrNotFound{}, "no matching schema %q in scope %q", namePrefix, scope)
+	})
+	return version, err
+}
+
+// UpsertSchema stores a new version of the schema, provided it is compatible
+// with the latest one. Upserting the latest schema again does not create a new version.
+func (c *Connector) UpsertSchema(_ context.Context, scope, namePrefix string, eds []*dosa.EntityDefinition) (status *dosa.SchemaStatus, err error) {
+	for _, ed := range eds {
+		if err := ed.EnsureValid(); err != nil {
+			return nil, errors.Wrap(err, "invalid entity definition")
+		}
+	}
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		sb, err := scopeBucket(tx, scope)
+		if err != nil {
+			return err
+		}
+		b := sb.Bucket(schemaBucket)
+		versions, err := schemaVersions(b, namePrefix)
+		if err != nil {
+			return err
+		}
+		version := int32(len(versions) + 1)
+		if len(versions) > 0 {
+			latest := versions[len(versions)-1]
+			if latest.Same(eds) {
+				status = &dosa.SchemaStatus{Version: latest.Version, Status: dosa.SchemaStatusCompleted}
+				return nil
+			}
+			if err := latest.EnsureCompatible(eds); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(eds)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode schema")
+		}
+		if err := b.Put(schemaKey(namePrefix, version), data); err != nil {
+			return err
+		}
+		status = &dosa.SchemaStatus{Version: version, Status: dosa.SchemaStatusCompleted}
+		return nil
+	})
+	return status, err
+}
+
+// CheckSchemaStatus returns the status of a stored schema version
+func (c *Connector) CheckSchemaStatus(_ context.Context, scope, namePrefix string, version int32) (*dosa.SchemaStatus, error) {
+	err := c.db.View(func(tx *bolt.Tx) error {
+		sb, err := scopeBucket(tx, scope)
+		if err != nil {
+			return err
+		}
+		if sb.Bucket(schemaBucket).Get(schemaKey(namePrefix, version)) == nil {
+			return errors.Wrapf(&dosa.ErrNotFound{}, "version %d of schema %q in scope %q", version, namePrefix, scope)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dosa.SchemaStatus{Version: version, Status: dosa.SchemaStatusCompleted}, nil
+}
+
+// GetSchema returns the entity definitions of a stored schema version, or of
+// the latest one for dosa.LatestVersion
+func (c *Connector) GetSchema(_ context.Context, scope, namePrefix string, version int32) (eds []*dosa.EntityDefinition, err error) {
+	err = c.db.View(func(tx *bolt.Tx) error {
+		sb, err := scopeBucket(tx, scope)
+		if err != nil {
+			return err
+		}
+		b := sb.Bucket(schemaBucket)
+		if version == dosa.LatestVersion {
+			versions, err := schemaVersions(b, namePrefix)
+			if err != nil {
+				return err
+			}
+			version = int32(len(versions))
+		}
+		data := b.Get(schemaKey(namePrefix, version))
+		if data == nil {
+			return errors.Wrapf(&dosa.ErrNotFound{}, "version %d of schema %q in scope %q", version, namePrefix, scope)
+		}
+		if err := json.Unmarshal(data, &eds); err != nil {
+			return errors.Wrapf(err, "failed to decode schema %q", namePrefix)
+		}
+		return nil
+	})
+	return eds, err
+}
+
+// ListSchemaVersions returns the status of every stored version of a schema, oldest first
+func (c *Connector) ListSchemaVersions(_ context.Context, scope, namePrefix string) (statuses []*dosa.SchemaStatus, err error) {
+	err = c.db.View(func(tx *bolt.Tx) error {
+		sb, err := scopeBucket(tx, scope)
+		if err != nil {
+			return err
+		}
+		versions, err := schemaVersions(sb.Bucket(schemaBucket), namePrefix)
+		if err != nil {
+			return err
+		}
+		statuses = make([]*dosa.SchemaStatus, len(versions))
+		for i, s := range versions {
+			statuses[i] = &dosa.SchemaStatus{Version: s.Version, Status: dosa.SchemaStatusCompleted}
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// CreateScope creates the buckets of a new scope
+func (c *Connector) CreateScope(_ context.Context, scope string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(scope)) != nil {
+			return errors.Wrapf(&dosa.ErrAlreadyExists{}, "scope %q", scope)
+		}
+		sb, err := tx.CreateBucket([]byte(scope))
+		if err != nil {
+			return errors.Wrapf(err, "failed to create scope %q", scope)
+		}
+		if _, err := sb.CreateBucket(schemaBucket); err != nil {
+			return err
+		}
+		_, err = sb.CreateBucket(dataBucket)
+		return err
+	})
+}
+
+// TruncateScope removes all the data of a scope, keeping its schema
+func (c *Connector) TruncateScope(_ context.Context, scope string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		sb, err := scopeBucket(tx, scope)
+		if err != nil {
+			return err
+		}
+		if err := sb.DeleteBucket(dataBucket); err != nil {
+			return err
+		}
+		_, err = sb.CreateBucket(dataBucket)
+		return err
+	})
+}
+
+// DropScope removes a scope along with its schema and data
+func (c *Connector) DropScope(_ context.Context, scope string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(scope)); err != nil {
+			if err == bolt.ErrBucketNotFound {
+				return errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// ScopeExists returns true if the scope was created and not dropped since
+func (c *Connector) ScopeExists(_ context.Context, scope string) (exists bool, err error) {
+	err = c.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket([]byte(scope)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+// Shutdown closes the database file
+func (c *Connector) Shutdown() error {
+	return c.db.Close()
+}
+
+// scopeBucket returns the bucket of a scope, or a not found error
+func scopeBucket(tx *bolt.Tx, scope string) (*bolt.Bucket, error) {
+	sb := tx.Bucket([]byte(scope))
+	if sb == nil {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	return sb, nil
+}
+
+// tableBucket returns the bucket holding the rows of an entity. When the
+// table has never been written to, it is created if requested, otherwise nil
+// is returned.
+func tableBucket(tx *bolt.Tx, ref *dosa.SchemaRef, create bool) (*bolt.Bucket, error) {
+	sb, err := scopeBucket(tx, ref.Scope)
+	if err != nil {
+		return nil, err
+	}
+	data := sb.Bucket(dataBucket)
+	table := []byte(ref.NamePrefix + "\x00" + ref.EntityName)
+	if create {
+		return data.CreateBucketIfNotExists(table)
+	}
+	return data.Bucket(table), nil
+}
+
+// put writes a row, merging it with mergeFunc if it already exists
+func put(tx *bolt.Tx, ei *dosa.EntityInfo, values map[string]dosa.FieldValue, mergeFunc func(into, from map[string]dosa.FieldValue) error) error {
+	b, err := tableBucket(tx, ei.Ref, true)
+	if err != nil {
+		return err
+	}
+	key, err := encodeKey(ei.Def, values)
+	if err != nil {
+		return err
+	}
+	row := values
+	if existing := b.Get(key); existing != nil {
+		if row, err = decodeRow(existing); err != nil {
+			return err
+		}
+		if err := mergeFunc(row, values); err != nil {
+			return err
+		}
+	}
+	data, err := encodeRow(row)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// get reads a row, or returns a not found error
+func get(tx *bolt.Tx, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue) (map[string]dosa.FieldValue, error) {
+	b, err := tableBucket(tx, ei.Ref, false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := encodeKey(ei.Def, keys)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, &dosa.ErrNotFound{}
+	}
+	data := b.Get(key)
+	if data == nil {
+		return nil, &dosa.ErrNotFound{}
+	}
+	return decodeRow(data)
+}
+
+// remove deletes a row if it exists
+func remove(tx *bolt.Tx, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue) error {
+	b, err := tableBucket(tx, ei.Ref, false)
+	if err != nil {
+		return err
+	}
+	key, err := encodeKey(ei.Def, keys)
+	if err != nil || b == nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+func mergeValues(into, from map[string]dosa.FieldValue) error {
+	for k, v := range from {
+		into[k] = v
+	}
+	return nil
+}
+
+// iterate calls f for the rows whose key starts with prefix, in key order,
+// starting after the row identified by token. It stops after f accepted limit
+// rows, or at the end of the prefix when limit is 0 or less.
+func iterate(b *bolt.Bucket, prefix []byte, token string, limit int, f func(k []byte, values map[string]dosa.FieldValue) (bool, error)) error {
+	start := prefix
+	if token != "" {
+		after, err := base64.StdEncoding.DecodeString(token)
+		if err != nil || !bytes.HasPrefix(after, prefix) {
+			return errors.Errorf("invalid token %q", token)
+		}
+		start = after
+	}
+	cursor := b.Cursor()
+	k, v := cursor.Seek(start)
+	if token != "" && bytes.Equal(k, start) {
+		k, v = cursor.Next()
+	}
+	accepted := 0
+	for ; k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		values, err := decodeRow(v)
+		if err != nil {
+			return err
+		}
+		ok, err := f(k, values)
+		if err != nil {
+			return err
+		}
+		if ok {
+			accepted++
+			if limit > 0 && accepted == limit {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// rangePrefix returns the encoded partition key from the equality conditions on the partition key columns
+func rangePrefix(ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) ([]byte, error) {
+	values := make(map[string]dosa.FieldValue, len(ei.Def.Key.PartitionKeys))
+	for _, pk := range ei.Def.Key.PartitionKeys {
+		conds := columnConditions[pk]
+		if len(conds) != 1 || conds[0].Op != dosa.Eq {
+			return nil, errors.Errorf("partition key %q requires exactly one equality condition", pk)
+		}
+		values[pk] = conds[0].Value
+	}
+	return encodePartitionKey(ei.Def, values)
+}
+
+// matchesClusteringConditions checks if a row matches all the conditions on the clustering keys
+func matchesClusteringConditions(ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition, values map[string]dosa.FieldValue) (bool, error) {
+	for _, ck := range ei.Def.Key.ClusteringKeys {
+		for _, cond := range columnConditions[ck.Name] {
+			cmp, err := compareValues(values[ck.Name], cond.Value)
+			if err != nil {
+				return false, errors.Wrapf(err, "condition on %q", ck.Name)
+			}
+			if !passCol(cmp, cond.Op) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// passCol checks if the result of a comparison satisfies an operator
+func passCol(cmp int, op dosa.Operator) bool {
+	switch op {
+	case dosa.Eq:
+		return cmp == 0
+	case dosa.Gt:
+		return cmp > 0
+	case dosa.GtOrEq:
+		return cmp >= 0
+	case dosa.Lt:
+		return cmp < 0
+	case dosa.LtOrEq:
+		return cmp <= 0
+	}
+	panic("invalid operator " + op.String())
+}
+
+// schemaKey is the name prefix followed by the big-endian version, so that the
+// versions of a prefix are stored in order
+func schemaKey(namePrefix string, version int32) []byte {
+	key := make([]byte, len(namePrefix)+5)
+	copy(key, namePrefix)
+	binary.BigEndian.PutUint32(key[len(namePrefix)+1:], uint32(version))
+	return key
+}
+
+// schemaVersions reads all the stored versions of a name prefix, oldest first
+func schemaVersions(b *bolt.Bucket, namePrefix string) ([]*dosa.SchemaVersion, error) {
+	prefix := []byte(namePrefix + "\x00")
+	var versions []*dosa.SchemaVersion
+	cursor := b.Cursor()
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		if len(k) != len(prefix)+4 {
+			// a longer name prefix starting with this one
+			continue
+		}
+		var eds []*dosa.EntityDefinition
+		if err := json.Unmarshal(v, &eds); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode schema %q", namePrefix)
+		}
+		versions = append(versions, &dosa.SchemaVersion{
+			Version:  int32(binary.BigEndian.Uint32(k[len(prefix):])),
+			Entities: eds,
+		})
+	}
+	return versions, nil
+}
+
+// Name returns the name of the connector
+func Name() string {
+	return name
+}
+
+func init() {
+	dosa.RegisterConnector(name, func(args dosa.CreationArgs) (dosa.Connector, error) {
+		path := _defaultPath
+		if p, ok := args["path"].(string); ok && p != "" {
+			path = p
+		}
+		return NewConnector(path)
+	})
+}
@@ -0,0 +1,609 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/memory"
+)
+
+var testSchemaRef = dosa.SchemaRef{
+	Scope:      "scope1",
+	NamePrefix: "namePrefix",
+	EntityName: "eName",
+	Version:    1,
+}
+
+var clusteredEi = &dosa.EntityInfo{
+	Ref: &testSchemaRef,
+	Def: &dosa.EntityDefinition{
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "f1", Type: dosa.String},
+			{Name: "c1", Type: dosa.Int64},
+			{Name: "c2", Type: dosa.Double},
+			{Name: "c3", Type: dosa.String},
+			{Name: "c4", Type: dosa.Blob},
+			{Name: "c5", Type: dosa.Bool},
+			{Name: "c6", Type: dosa.Int32},
+			{Name: "c7", Type: dosa.TUUID},
+			{Name: "c8", Type: dosa.Timestamp},
+		},
+		Key: &dosa.PrimaryKey{
+			PartitionKeys: []string{"f1"},
+			ClusteringKeys: []*dosa.ClusteringKey{
+				{Name: "c1", Descending: false},
+				{Name: "c7", Descending: true},
+			},
+		},
+		Name: "t1",
+	},
+}
+
+// newTestConnector opens a connector on a new file, with the test scope created
+func newTestConnector(t *testing.T) (*Connector, func()) {
+	dir, err := ioutil.TempDir("", "dosa-file")
+	assert.NoError(t, err)
+	sut, err := NewConnector(filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	assert.NoError(t, sut.CreateScope(context.TODO(), testSchemaRef.Scope))
+	return sut, func() {
+		_ = sut.Shutdown()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func partitionConditions(f1 string) map[string][]*dosa.Condition {
+	return map[string][]*dosa.Condition{
+		"f1": {{Op: dosa.Eq, Value: dosa.FieldValue(f1)}},
+	}
+}
+
+func TestConnector_CreateReadRemove(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	id := dosa.NewUUID()
+	now := time.Unix(0, time.Now().UnixNano())
+	row := map[string]dosa.FieldValue{
+		"f1": "data", "c1": int64(1), "c2": -1.5, "c3": "x", "c4": []byte{0, 1},
+		"c5": true, "c6": int32(-3), "c7": id, "c8": now,
+	}
+	keys := map[string]dosa.FieldValue{"f1": "data", "c1": int64(1), "c7": id}
+
+	_, err := sut.Read(context.TODO(), clusteredEi, keys, dosa.All())
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	assert.NoError(t, sut.CreateIfNotExists(context.TODO(), clusteredEi, row))
+	err = sut.CreateIfNotExists(context.TODO(), clusteredEi, row)
+	assert.True(t, dosa.ErrorIsAlreadyExists(err))
+
+	values, err := sut.Read(context.TODO(), clusteredEi, keys, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "x", values["c3"])
+	assert.Equal(t, []byte{0, 1}, values["c4"])
+	assert.Equal(t, id, values["c7"])
+	assert.True(t, now.Equal(values["c8"].(time.Time)))
+
+	// upsert merges the values
+	assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+		"f1": "data", "c1": int64(1), "c7": id, "c3": "y"}))
+	values, err = sut.Read(context.TODO(), clusteredEi, keys, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "y", values["c3"])
+	assert.Equal(t, int32(-3), values["c6"])
+
+	assert.NoError(t, sut.Remove(context.TODO(), clusteredEi, keys))
+	assert.NoError(t, sut.Remove(context.TODO(), clusteredEi, keys))
+	_, err = sut.Read(context.TODO(), clusteredEi, keys, dosa.All())
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	// keys are required
+	err = sut.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{"f1": "data"})
+	assert.Error(t, err)
+}
+
+func TestConnector_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dosa-file")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "test.db")
+
+	conn, err := dosa.GetConnector("file", dosa.CreationArgs{"path": path})
+	assert.NoError(t, err)
+	assert.NoError(t, conn.CreateScope(context.TODO(), testSchemaRef.Scope))
+	_, err = conn.UpsertSchema(context.TODO(), testSchemaRef.Scope, testSchemaRef.NamePrefix, []*dosa.EntityDefinition{clusteredEi.Def})
+	assert.NoError(t, err)
+	id := dosa.NewUUID()
+	assert.NoError(t, conn.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+		"f1": "data", "c1": int64(1), "c7": id, "c3": "kept"}))
+	assert.NoError(t, conn.Shutdown())
+
+	conn, err = NewConnector(path)
+	assert.NoError(t, err)
+	defer func() { _ = conn.Shutdown() }()
+	values, err := conn.Read(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+		"f1": "data", "c1": int64(1), "c7": id}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", values["c3"])
+	version, err := conn.CheckSchema(context.TODO(), testSchemaRef.Scope, testSchemaRef.NamePrefix, []*dosa.EntityDefinition{clusteredEi.Def})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), version)
+}
+
+func TestConnector_RangeOrderMatchesMemory(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	mem := memory.NewConnector()
+	assert.NoError(t, mem.CreateScope(context.TODO(), clusteredEi.Ref.Scope))
+	_, err := mem.UpsertSchema(context.TODO(), clusteredEi.Ref.Scope, clusteredEi.Ref.NamePrefix, []*dosa.EntityDefinition{clusteredEi.Def})
+	assert.NoError(t, err)
+
+	for x := 0; x < 20; x++ {
+		id := dosa.NewUUID()
+		if x%2 == 0 {
+			id = dosa.UUID(uuid.NewV1().String())
+		}
+		row := map[string]dosa.FieldValue{"f1": "data", "c1": int64(x%3 - 1), "c6": int32(x), "c7": id}
+		assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, row))
+		assert.NoError(t, mem.Upsert(context.TODO(), clusteredEi, row))
+	}
+	// a different partition is never returned
+	assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+		"f1": "data2", "c1": int64(0), "c7": dosa.NewUUID()}))
+
+	expected, _, err := mem.Range(context.TODO(), clusteredEi, partitionConditions("data"), dosa.All(), "", 0)
+	assert.NoError(t, err)
+	actual, token, err := sut.Range(context.TODO(), clusteredEi, partitionConditions("data"), dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Len(t, actual, 20)
+	for i := range expected {
+		assert.Equal(t, expected[i]["c6"], actual[i]["c6"])
+	}
+
+	// conditions on clustering keys
+	conditions := partitionConditions("data")
+	conditions["c1"] = []*dosa.Condition{{Op: dosa.GtOrEq, Value: int64(0)}}
+	expected, _, err = mem.Range(context.TODO(), clusteredEi, conditions, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	actual, _, err = sut.Range(context.TODO(), clusteredEi, conditions, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, actual, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i]["c6"], actual[i]["c6"])
+	}
+
+	// the partition key is required
+	_, _, err = sut.Range(context.TODO(), clusteredEi, map[string][]*dosa.Condition{}, dosa.All(), "", 0)
+	assert.Error(t, err)
+}
+
+func TestConnector_DecimalDateKeys(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	mem := memory.NewConnector()
+	ei := &dosa.EntityInfo{
+		Ref: &testSchemaRef,
+		Def: &dosa.EntityDefinition{
+			Name: "t2",
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "f1", Type: dosa.String},
+				{Name: "amount", Type: dosa.TDecimal},
+				{Name: "day", Type: dosa.TDate},
+			},
+			Key: &dosa.PrimaryKey{
+				PartitionKeys: []string{"f1"},
+				ClusteringKeys: []*dosa.ClusteringKey{
+					{Name: "amount", Descending: true},
+					{Name: "day", Descending: false},
+				},
+			},
+		},
+	}
+	assert.NoError(t, mem.CreateScope(context.TODO(), ei.Ref.Scope))
+	_, err := mem.UpsertSchema(context.TODO(), ei.Ref.Scope, ei.Ref.NamePrefix, []*dosa.EntityDefinition{ei.Def})
+	assert.NoError(t, err)
+
+	amounts := []dosa.Decimal{"10.25", "-3", "0.5", "100", "-0.75"}
+	for i, amount := range amounts {
+		for _, day := range []dosa.Date{dosa.NewDate(2017, time.March, 4), dosa.NewDate(1969, time.July, 20-i)} {
+			row := map[string]dosa.FieldValue{"f1": "data", "amount": amount, "day": day}
+			assert.NoError(t, sut.Upsert(context.TODO(), ei, row))
+			assert.NoError(t, mem.Upsert(context.TODO(), ei, row))
+		}
+	}
+
+	expected, _, err := mem.Range(context.TODO(), ei, partitionConditions("data"), dosa.All(), "", 0)
+	assert.NoError(t, err)
+	actual, _, err := sut.Range(context.TODO(), ei, partitionConditions("data"), dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	values, err := sut.Read(context.TODO(), ei, map[string]dosa.FieldValue{
+		"f1": "data", "amount": dosa.Decimal("0.5"), "day": dosa.NewDate(2017, time.March, 4)}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.Decimal("0.5"), values["amount"])
+	assert.Equal(t, dosa.NewDate(2017, time.March, 4), values["day"])
+}
+
+func TestConnector_DecimalPartitionKey(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	ei := &dosa.EntityInfo{
+		Ref: &testSchemaRef,
+		Def: &dosa.EntityDefinition{
+			Name: "prices",
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "amount", Type: dosa.TDecimal},
+				{Name: "label", Type: dosa.String},
+			},
+			Key: &dosa.PrimaryKey{PartitionKeys: []string{"amount"}},
+		},
+	}
+
+	// equal decimals find the same row, whatever their number of digits
+	assert.NoError(t, sut.Upsert(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("1.5"), "label": "first"}))
+	values, err := sut.Read(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("1.50")}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "first", values["label"])
+	assert.NoError(t, sut.Upsert(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("01.500"), "label": "second"}))
+	values, err = sut.Read(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("1.5")}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", values["label"])
+	rows, _, err := sut.Scan(context.TODO(), ei, dosa.All(), "", 100)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
+
+func TestConnector_Collections(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	ei := &dosa.EntityInfo{
+		Ref: &testSchemaRef,
+		Def: &dosa.EntityDefinition{
+			Name: "collections",
+			Key:  &dosa.PrimaryKey{PartitionKeys: []string{"f1"}},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "f1", Type: dosa.String},
+				{Name: "blobs", Type: dosa.ListOf(dosa.Blob)},
+				{Name: "amounts", Type: dosa.ListOf(dosa.TDecimal)},
+				{Name: "tags", Type: dosa.SetOf(dosa.String)},
+				{Name: "days", Type: dosa.SetOf(dosa.TDate)},
+				{Name: "counts", Type: dosa.MapOf(dosa.String, dosa.Int64)},
+				{Name: "times", Type: dosa.MapOf(dosa.Int32, dosa.Timestamp)},
+			},
+		},
+	}
+	now := time.Unix(1500000000, 123456789).UTC()
+	values := map[string]dosa.FieldValue{
+		"f1":      "data",
+		"blobs":   [][]byte{{1}, {2, 3}},
+		"amounts": []dosa.Decimal{"1.50", "-2"},
+		"tags":    map[string]dosa.Set{"a": {}, "b": {}},
+		"days":    map[dosa.Date]dosa.Set{dosa.NewDate(2017, time.March, 4): {}},
+		"counts":  map[string]int64{"x": 1, "y": 2},
+		"times":   map[int32]time.Time{1: now},
+	}
+	assert.NoError(t, sut.Upsert(context.TODO(), ei, values))
+	read, err := sut.Read(context.TODO(), ei, map[string]dosa.FieldValue{"f1": "data"}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, values, read)
+
+	// collections can be searched, whatever the order of their elements
+	found, _, err := sut.Search(context.TODO(), ei, dosa.FieldNameValuePair{
+		Name:  "counts",
+		Value: map[string]int64{"y": 2, "x": 1},
+	}, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{values}, found)
+	found, _, err = sut.Search(context.TODO(), ei, dosa.FieldNameValuePair{
+		Name:  "tags",
+		Value: map[string]dosa.Set{"a": {}},
+	}, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestConnector_Paging(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+
+	// nothing written yet
+	data, token, err := sut.Scan(context.TODO(), clusteredEi, dosa.All(), "", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+	assert.Empty(t, token)
+
+	for x := 0; x < 25; x++ {
+		assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+			"f1": "data", "c1": int64(x), "c7": dosa.NewUUID(), "c3": []string{"even", "odd"}[x%2]}))
+	}
+
+	var all []map[string]dosa.FieldValue
+	token = ""
+	for {
+		data, token, err = sut.Range(context.TODO(), clusteredEi, partitionConditions("data"), dosa.All(), token, 10)
+		assert.NoError(t, err)
+		all = append(all, data...)
+		if token == "" {
+			break
+		}
+		assert.Len(t, data, 10)
+	}
+	assert.Len(t, all, 25)
+	for x, row := range all {
+		assert.Equal(t, int64(x), row["c1"])
+	}
+
+	// exactly one page
+	data, token, err = sut.Scan(context.TODO(), clusteredEi, dosa.All(), "", 25)
+	assert.NoError(t, err)
+	assert.Len(t, data, 25)
+	assert.Empty(t, token)
+
+	data, token, err = sut.Search(context.TODO(), clusteredEi, dosa.FieldNameValuePair{Name: "c3", Value: "odd"}, dosa.All(), "", 5)
+	assert.NoError(t, err)
+	assert.Len(t, data, 5)
+	assert.NotEmpty(t, token)
+	data, token, err = sut.Search(context.TODO(), clusteredEi, dosa.FieldNameValuePair{Name: "c3", Value: "odd"}, dosa.All(), token, 10)
+	assert.NoError(t, err)
+	assert.Len(t, data, 7)
+	assert.Empty(t, token)
+
+	_, _, err = sut.Scan(context.TODO(), clusteredEi, dosa.All(), "not a token", 10)
+	assert.Error(t, err)
+
+	// remove the upper half
+	conditions := partitionConditions("data")
+	conditions["c1"] = []*dosa.Condition{{Op: dosa.Gt, Value: int64(12)}}
+	assert.NoError(t, sut.RemoveRange(context.TODO(), clusteredEi, conditions))
+	data, _, err = sut.Scan(context.TODO(), clusteredEi, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, data, 13)
+}
+
+func TestConnector_MinimumFields(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	id := dosa.NewUUID()
+	keys := map[string]dosa.FieldValue{"f1": "data", "c1": int64(1), "c7": id}
+	assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+		"f1": "data", "c1": int64(1), "c7": id, "c3": "x", "c6": int32(6)}))
+
+	values, err := sut.Read(context.TODO(), clusteredEi, keys, []string{"c3", "c2"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosa.FieldValue{"c3": "x"}, values)
+
+	results, err := sut.MultiRead(context.TODO(), clusteredEi, []map[string]dosa.FieldValue{keys}, []string{"c6"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosa.FieldValue{"c6": int32(6)}, results[0].Values)
+
+	rows, _, err := sut.Range(context.TODO(), clusteredEi, partitionConditions("data"), []string{"c1"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"c1": int64(1)}}, rows)
+
+	// the searched column need not be returned
+	rows, _, err = sut.Search(context.TODO(), clusteredEi, dosa.FieldNameValuePair{Name: "c3", Value: "x"}, []string{"c6"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"c6": int32(6)}}, rows)
+
+	rows, _, err = sut.Scan(context.TODO(), clusteredEi, []string{"f1"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"f1": "data"}}, rows)
+}
+
+func TestConnector_Multi(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	ids := []dosa.UUID{dosa.NewUUID(), dosa.NewUUID()}
+	rows := []map[string]dosa.FieldValue{
+		{"f1": "data", "c1": int64(1), "c7": ids[0]},
+		{"f1": "data", "c1": int64(2), "c7": ids[1]},
+		{"f1": "data"},
+	}
+	errs, err := sut.MultiUpsert(context.TODO(), clusteredEi, rows)
+	assert.NoError(t, err)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.Error(t, errs[2])
+
+	missing := map[string]dosa.FieldValue{"f1": "data", "c1": int64(3), "c7": ids[0]}
+	results, err := sut.MultiRead(context.TODO(), clusteredEi, []map[string]dosa.FieldValue{rows[0], missing, rows[1]}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, ids[0], results[0].Values["c7"])
+	assert.True(t, dosa.ErrorIsNotFound(results[1].Error))
+	assert.Equal(t, ids[1], results[2].Values["c7"])
+
+	errs, err = sut.MultiRemove(context.TODO(), clusteredEi, rows[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, nil}, errs)
+	data, _, err := sut.Scan(context.TODO(), clusteredEi, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestConnector_Increment(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	counterEi := &dosa.EntityInfo{
+		Ref: &testSchemaRef,
+		Def: &dosa.EntityDefinition{
+			Name: "counters",
+			Key:  &dosa.PrimaryKey{PartitionKeys: []string{"f1"}},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "f1", Type: dosa.String},
+				{Name: "hits", Type: dosa.Counter},
+				{Name: "misses", Type: dosa.Counter},
+			},
+		},
+	}
+	keys := map[string]dosa.FieldValue{"f1": "data"}
+
+	assert.NoError(t, sut.Upsert(context.TODO(), counterEi, map[string]dosa.FieldValue{"f1": "data", "hits": dosa.CounterValue(2)}))
+	value, err := sut.Increment(context.TODO(), counterEi, keys, "misses", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.CounterValue(-1), value)
+
+	var wg sync.WaitGroup
+	for x := 0; x < 20; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sut.Increment(context.TODO(), counterEi, keys, "hits", 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	values, err := sut.Read(context.TODO(), counterEi, keys, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosa.FieldValue{
+		"f1":     "data",
+		"hits":   dosa.CounterValue(22),
+		"misses": dosa.CounterValue(-1),
+	}, values)
+
+	_, err = sut.Increment(context.TODO(), counterEi, keys, "f1", 1)
+	assert.Contains(t, err.Error(), "not a counter")
+	_, err = sut.Increment(context.TODO(), counterEi, keys, "nope", 1)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestConnector_Scopes(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	row := map[string]dosa.FieldValue{"f1": "data", "c1": int64(1), "c7": dosa.NewUUID()}
+
+	err := sut.CreateScope(context.TODO(), testSchemaRef.Scope)
+	assert.True(t, dosa.ErrorIsAlreadyExists(err))
+	exists, err := sut.ScopeExists(context.TODO(), testSchemaRef.Scope)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// scopes are isolated
+	other := *clusteredEi
+	otherRef := testSchemaRef
+	otherRef.Scope = "scope2"
+	other.Ref = &otherRef
+	err = sut.Upsert(context.TODO(), &other, row)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	assert.NoError(t, sut.CreateScope(context.TODO(), otherRef.Scope))
+	assert.NoError(t, sut.Upsert(context.TODO(), &other, row))
+	assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, row))
+
+	_, err = sut.UpsertSchema(context.TODO(), testSchemaRef.Scope, testSchemaRef.NamePrefix, []*dosa.EntityDefinition{clusteredEi.Def})
+	assert.NoError(t, err)
+	assert.NoError(t, sut.TruncateScope(context.TODO(), testSchemaRef.Scope))
+	data, _, err := sut.Scan(context.TODO(), clusteredEi, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+	data, _, err = sut.Scan(context.TODO(), &other, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+	// truncating keeps the schema
+	_, err = sut.CheckSchemaStatus(context.TODO(), testSchemaRef.Scope, testSchemaRef.NamePrefix, 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, sut.DropScope(context.TODO(), otherRef.Scope))
+	assert.True(t, dosa.ErrorIsNotFound(sut.DropScope(context.TODO(), otherRef.Scope)))
+	assert.True(t, dosa.ErrorIsNotFound(sut.TruncateScope(context.TODO(), otherRef.Scope)))
+	exists, err = sut.ScopeExists(context.TODO(), otherRef.Scope)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestConnector_SchemaVersions(t *testing.T) {
+	sut, cleanup := newTestConnector(t)
+	defer cleanup()
+	scope, prefix := testSchemaRef.Scope, testSchemaRef.NamePrefix
+	v1 := clusteredEi.Def
+
+	_, err := sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	status, err := sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, &dosa.SchemaStatus{Version: 1, Status: dosa.SchemaStatusCompleted}, status)
+	status, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), status.Version)
+
+	// adding a column creates a new version
+	v2 := *v1
+	v2.Columns = append(append([]*dosa.ColumnDefinition{}, v1.Columns...), &dosa.ColumnDefinition{Name: "c9", Type: dosa.String})
+	status, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{&v2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), status.Version)
+
+	// removing it again is not compatible
+	_, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.Error(t, err)
+
+	// a longer prefix has its own versions
+	status, err = sut.UpsertSchema(context.TODO(), scope, prefix+"2", []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), status.Version)
+
+	version, err := sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), version)
+	version, err = sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{&v2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), version)
+
+	status, err = sut.CheckSchemaStatus(context.TODO(), scope, prefix, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), status.Version)
+	_, err = sut.CheckSchemaStatus(context.TODO(), scope, prefix, 3)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	_, err = sut.UpsertSchema(context.TODO(), "nope", prefix, []*dosa.EntityDefinition{v1})
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	// the definitions of every version can be read back
+	eds, err := sut.GetSchema(context.TODO(), scope, prefix, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.EntityDefinition{v1}, eds)
+	eds, err = sut.GetSchema(context.TODO(), scope, prefix, dosa.LatestVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.EntityDefinition{&v2}, eds)
+	_, err = sut.GetSchema(context.TODO(), scope, prefix, 3)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	_, err = sut.GetSchema(context.TODO(), "nope", prefix, 1)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	statuses, err := sut.ListSchemaVersions(context.TODO(), scope, prefix)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.SchemaStatus{{Version: 1, Status: dosa.SchemaStatusCompleted}, {Version: 2, Status: dosa.SchemaStatusCompleted}}, statuses)
+	statuses, err = sut.ListSchemaVersions(context.TODO(), scope, prefix+"x")
+	assert.NoError(t, err)
+	assert.Empty(t, statuses)
+	_, err = sut.ListSchemaVersions(context.TODO(), "nope", prefix)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+var collectionEi = &dosa.EntityInfo{
+	Ref: &testSchemaRef,
+	Def: &dosa.EntityDefinition{
+		Name: "collections",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys: []string{"f1"},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "f1", Type: dosa.String},
+			{Name: "blobs", Type: dosa.ListOf(dosa.Blob)},
+			{Name: "tags", Type: dosa.SetOf(dosa.String), Tags: map[string]string{searchableTag: ""}},
+			{Name: "times", Type: dosa.MapOf(dosa.Int32, dosa.Timestamp)},
+		},
+	},
+}
+
+func TestConnector_Collections(t *testing.T) {
+	sut := newScopedConnector()
+	now := time.Unix(1500000000, 123456789).UTC()
+	values := map[string]dosa.FieldValue{
+		"f1":    "data",
+		"blobs": [][]byte{{1}, nil},
+		"tags":  map[string]dosa.Set{"a": {}, "b": {}, "c": {}},
+		"times": map[int32]time.Time{1: now},
+	}
+	assert.NoError(t, sut.Upsert(context.TODO(), collectionEi, values))
+	// changing the values written or read does not change the stored row
+	values["blobs"].([][]byte)[0][0] = 2
+	values["tags"].(map[string]dosa.Set)["d"] = dosa.Set{}
+	read, err := sut.Read(context.TODO(), collectionEi, map[string]dosa.FieldValue{"f1": "data"}, dosa.All())
+	assert.NoError(t, err)
+	read["times"].(map[int32]time.Time)[2] = now
+	expected := map[string]dosa.FieldValue{
+		"f1":    "data",
+		"blobs": [][]byte{{1}, nil},
+		"tags":  map[string]dosa.Set{"a": {}, "b": {}, "c": {}},
+		"times": map[int32]time.Time{1: now},
+	}
+	read, err = sut.Read(context.TODO(), collectionEi, map[string]dosa.FieldValue{"f1": "data"}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, read)
+
+	// collections can be searched, whatever the order of their elements
+	found, _, err := sut.Search(context.TODO(), collectionEi, dosa.FieldNameValuePair{
+		Name:  "tags",
+		Value: map[string]dosa.Set{"c": {}, "b": {}, "a": {}},
+	}, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{expected}, found)
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, sut.Snapshot(&snapshot))
+	assert.Contains(t, snapshot.String(), `{"type":"Set\u003cString\u003e","value":"[\"a\",\"b\",\"c\"]"}`)
+	restored := NewConnector()
+	assert.NoError(t, restored.Restore(bytes.NewReader(snapshot.Bytes())))
+	read, err = restored.Read(context.TODO(), collectionEi, map[string]dosa.FieldValue{"f1": "data"}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, read)
+}
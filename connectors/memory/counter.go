@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// Increment atomically adds delta to a counter column, creating the row if needed
+func (c *Connector) Increment(_ context.Context, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue, column string, delta int64) (dosa.CounterValue, error) {
+	cd := ei.Def.FindColumnDefinition(column)
+	if cd == nil {
+		return 0, errors.Errorf("column %q not found in entity %q", column, ei.Def.Name)
+	}
+	if cd.Type != dosa.Counter {
+		return 0, errors.Errorf("column %q of entity %q is not a counter", column, ei.Def.Name)
+	}
+
+	values := make(map[string]dosa.FieldValue)
+	for name := range ei.Def.KeySet() {
+		values[name] = keys[name]
+	}
+	value := dosa.CounterValue(delta)
+	values[column] = value
+	// mergedInsert holds the write lock, so reading and writing the counter is atomic
+	err := c.mergedInsert(ei, values, func(into map[string]dosa.FieldValue, _ map[string]dosa.FieldValue) error {
+		current, _ := into[column].(dosa.CounterValue)
+		value = current + dosa.CounterValue(delta)
+		into[column] = value
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+var counterEi = &dosa.EntityInfo{
+	Ref: &testSchemaRef,
+	Def: &dosa.EntityDefinition{
+		Name: "counters",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys: []string{"f1"},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "f1", Type: dosa.String},
+			{Name: "hits", Type: dosa.Counter},
+			{Name: "misses", Type: dosa.Counter},
+		},
+	},
+}
+
+func TestConnector_Increment(t *testing.T) {
+	sut := newScopedConnector()
+	keys := map[string]dosa.FieldValue{"f1": "data"}
+
+	value, err := sut.Increment(context.TODO(), counterEi, keys, "hits", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.CounterValue(2), value)
+	// other counters of the row start at zero
+	value, err = sut.Increment(context.TODO(), counterEi, keys, "misses", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.CounterValue(-1), value)
+
+	var wg sync.WaitGroup
+	for x := 0; x < 100; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sut.Increment(context.TODO(), counterEi, keys, "hits", 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	values, err := sut.Read(context.TODO(), counterEi, keys, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosa.FieldValue{
+		"f1":     "data",
+		"hits":   dosa.CounterValue(102),
+		"misses": dosa.CounterValue(-1),
+	}, values)
+
+	// counters survive snapshots
+	var buf bytes.Buffer
+	assert.NoError(t, sut.Snapshot(&buf))
+	restored := NewConnector()
+	assert.NoError(t, restored.Restore(&buf))
+	values, err = restored.Read(context.TODO(), counterEi, keys, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.CounterValue(102), values["hits"])
+}
+
+func TestConnector_IncrementErrors(t *testing.T) {
+	sut := newScopedConnector()
+	keys := map[string]dosa.FieldValue{"f1": "data"}
+
+	_, err := sut.Increment(context.TODO(), counterEi, keys, "nothere", 1)
+	assert.Contains(t, err.Error(), "not found")
+	_, err = sut.Increment(context.TODO(), counterEi, keys, "f1", 1)
+	assert.Contains(t, err.Error(), "not a counter")
+	_, err = NewConnector().Increment(context.TODO(), counterEi, keys, "hits", 1)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+}
+
+func TestConnector_WatchIncrement(t *testing.T) {
+	sut := newScopedConnector()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := sut.Watch(ctx, counterEi, nil)
+	assert.NoError(t, err)
+
+	keys := map[string]dosa.FieldValue{"f1": "data"}
+	_, err = sut.Increment(ctx, counterEi, keys, "hits", 1)
+	assert.NoError(t, err)
+	_, err = sut.Increment(ctx, counterEi, keys, "hits", 1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &dosa.RowChange{
+		Type:  dosa.Insert,
+		After: map[string]dosa.FieldValue{"f1": "data", "hits": dosa.CounterValue(1)},
+	}, <-changes)
+	assert.Equal(t, &dosa.RowChange{
+		Type:   dosa.Update,
+		Before: map[string]dosa.FieldValue{"f1": "data", "hits": dosa.CounterValue(1)},
+		After:  map[string]dosa.FieldValue{"f1": "data", "hits": dosa.CounterValue(2)},
+	}, <-changes)
+}
@@ -24,12 +24,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"reflect"
 	"sort"
 	"sync"
 	"time"
 
 	"encoding/binary"
 
+	"github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 	"github.com/uber-go/dosa"
 	"github.com/uber-go/dosa/connectors/base"
@@ -39,21 +41,97 @@ const name = "memory"
 
 // Connector is an in-memory connector.
 // The in-memory connector stores its data like this:
-// map[string]map[string][]map[string]dosa.FieldValue
+// map[string]*scopeData, where each scope holds a map[tableKey]map[string][]map[string]dosa.FieldValue
 //
-// the first 'string' is the table name (entity name)
-// the second 'string' is the partition key, encoded using encoding/gob to guarantee uniqueness
+// the first 'string' is the scope name
+// the tableKey is the name prefix and entity name
+// the next 'string' is the partition key, encoded using encoding/gob to guarantee uniqueness
 // within each 'partition' you have a list of rows ([]map[string]dosa.FieldValue)
 // these rows are kept ordered so that reads are lightning fast and searches are quick too
 // the row itself is a map of field name to value (map[string]dosaFieldValue])
 //
+// Rows are also kept in inverted maps for every column tagged searchable, so that Search
+// does not need to scan the whole table.
+//
+// Scopes must be created before any data is written to them, and every read or write must
+// refer to a schema version upserted for its scope and name prefix.
+//
 // A read-write mutex lock is used to control concurrency, making reads work in parallel but
 // writes are not. There is no attempt to improve the concurrency of the read or write path by
 // adding more granular locks.
 type Connector struct {
 	base.Connector
-	data map[string]map[string][]map[string]dosa.FieldValue
+	data map[string]*scopeData
 	lock sync.RWMutex
+	feed dosa.ChangeFeed
+	// autoUpsert connectors upsert the schemas that were not upserted yet in CheckSchema
+	autoUpsert bool
+}
+
+// tableKey identifies the table of an entity within a scope
+type tableKey struct {
+	namePrefix string
+	entityName string
+}
+
+// scopeData holds the schema versions and tables of a scope. The definition of each
+// entity written to is kept as well, so that the data can be snapshotted and restored.
+type scopeData struct {
+	// name prefix -> versions, oldest first
+	schemas map[string][]*dosa.SchemaVersion
+	tables  map[tableKey]map[string][]map[string]dosa.FieldValue
+	defs    map[tableKey]*dosa.EntityDefinition
+	// table -> searchable column name -> inverted map
+	indexes map[tableKey]map[string]searchIndex
+}
+
+func newScopeData() *scopeData {
+	s := &scopeData{schemas: make(map[string][]*dosa.SchemaVersion)}
+	s.truncate()
+	return s
+}
+
+// truncate removes all the rows of the scope, but keeps its schema versions
+func (s *scopeData) truncate() {
+	s.tables = make(map[tableKey]map[string][]map[string]dosa.FieldValue)
+	s.defs = make(map[tableKey]*dosa.EntityDefinition)
+	s.indexes = make(map[tableKey]map[string]searchIndex)
+}
+
+func tableKeyOf(ei *dosa.EntityInfo) tableKey {
+	return tableKey{namePrefix: ei.Ref.NamePrefix, entityName: ei.Def.Name}
+}
+
+// scope returns the data of a scope, or a not found error if it does not exist
+func (c *Connector) scope(scope string) (*scopeData, error) {
+	s, ok := c.data[scope]
+	if !ok {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	return s, nil
+}
+
+// scopeFor returns the data of the scope of an entity, provided the entity refers
+// to a known schema version
+func (c *Connector) scopeFor(ei *dosa.EntityInfo) (*scopeData, error) {
+	s, err := c.scope(ei.Ref.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.schemaVersion(ei.Ref.Scope, ei.Ref.NamePrefix, ei.Ref.Version); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// table returns the partitions of the table of an entity, which are nil when nothing
+// was written to it yet
+func (c *Connector) table(ei *dosa.EntityInfo) (map[string][]map[string]dosa.FieldValue, error) {
+	s, err := c.scopeFor(ei)
+	if err != nil {
+		return nil, err
+	}
+	return s.tables[tableKeyOf(ei)], nil
 }
 
 // partitionRange represents one section of a partition.
@@ -85,12 +163,19 @@ func (pr *partitionRange) values() []map[string]dosa.FieldValue {
 
 // partitionKeyBuilder extracts the partition key components from the map and encodes them,
 // generating a unique string. It uses the encoding/gob method to make a byte array as the
-// key, and returns this as a string
+// key, and returns this as a string. Decimals are encoded in their canonical form, so that
+// equal decimals like "1.5" and "1.50" are in the same partition.
 func partitionKeyBuilder(ei *dosa.EntityInfo, values map[string]dosa.FieldValue) string {
 	encodedKey := bytes.Buffer{}
 	encoder := gob.NewEncoder(&encodedKey)
 	for _, k := range ei.Def.Key.PartitionKeys {
-		_ = encoder.Encode(values[k])
+		value := values[k]
+		if d, ok := value.(dosa.Decimal); ok {
+			if canonical, err := d.Canonical(); err == nil {
+				value = canonical
+			}
+		}
+		_ = encoder.Encode(value)
 	}
 	return string(encodedKey.Bytes())
 }
@@ -232,6 +317,16 @@ func compareType(d1 dosa.FieldValue, d2 dosa.FieldValue) int8 {
 			return -1
 		}
 		return 1
+	case dosa.Decimal:
+		return int8(d1.Cmp(d2.(dosa.Decimal)))
+	case dosa.Date:
+		if d1 == d2.(dosa.Date) {
+			return 0
+		}
+		if d1.Before(d2.(dosa.Date)) {
+			return -1
+		}
+		return 1
 	}
 	panic(d1)
 }
@@ -253,26 +348,88 @@ func (c *Connector) CreateIfNotExists(_ context.Context, ei *dosa.EntityInfo, va
 func (c *Connector) Read(_ context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue, minimumFields []string) (map[string]dosa.FieldValue, error) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	if c.data[ei.Def.Name] == nil {
+	s, err := c.scopeFor(ei)
+	if err != nil {
+		return nil, err
+	}
+	row := s.find(ei, values)
+	if row == nil {
 		return nil, &dosa.ErrNotFound{}
 	}
-	entityRef := c.data[ei.Def.Name]
-	encodedPartitionKey := partitionKeyBuilder(ei, values)
-	partitionRef := entityRef[encodedPartitionKey]
+	return project(row, minimumFields), nil
+}
+
+// find returns the stored row with the primary key found in values, or nil if there is none
+func (s *scopeData) find(ei *dosa.EntityInfo, values map[string]dosa.FieldValue) map[string]dosa.FieldValue {
+	partitionRef := s.tables[tableKeyOf(ei)][partitionKeyBuilder(ei, values)]
 	// no data in this partition? easy out!
 	if len(partitionRef) == 0 {
-		return nil, &dosa.ErrNotFound{}
+		return nil
 	}
 
 	if len(ei.Def.ClusteringKeySet()) == 0 {
-		return partitionRef[0], nil
+		return partitionRef[0]
 	}
 	// clustering key, search for the value in the set
 	found, inx := findInsertionPoint(ei, partitionRef, values)
 	if !found {
-		return nil, &dosa.ErrNotFound{}
+		return nil
 	}
-	return partitionRef[inx], nil
+	return partitionRef[inx]
+}
+
+// project returns a copy of a row holding only the requested fields, or all of them if
+// none were requested. Blobs and collections are copied too, so callers cannot change
+// the stored rows.
+func project(values map[string]dosa.FieldValue, minimumFields []string) map[string]dosa.FieldValue {
+	if len(minimumFields) == 0 {
+		projected := make(map[string]dosa.FieldValue, len(values))
+		for name, value := range values {
+			projected[name] = copyValue(value)
+		}
+		return projected
+	}
+	projected := make(map[string]dosa.FieldValue, len(minimumFields))
+	for _, name := range minimumFields {
+		if value, ok := values[name]; ok {
+			projected[name] = copyValue(value)
+		}
+	}
+	return projected
+}
+
+// projectAll projects every row in a list
+func projectAll(multiValues []map[string]dosa.FieldValue, minimumFields []string) []map[string]dosa.FieldValue {
+	projected := make([]map[string]dosa.FieldValue, len(multiValues))
+	for i, values := range multiValues {
+		projected[i] = project(values, minimumFields)
+	}
+	return projected
+}
+
+// copyValue returns a copy of the values that are passed by reference
+func copyValue(value dosa.FieldValue) dosa.FieldValue {
+	if b, ok := value.([]byte); ok && b != nil {
+		return append([]byte{}, b...)
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() == reflect.Slice && !v.IsNil():
+		// lists, whose elements may be blobs
+		list := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			list.Index(i).Set(reflect.ValueOf(copyValue(v.Index(i).Interface())))
+		}
+		return list.Interface()
+	case v.Kind() == reflect.Map && !v.IsNil():
+		// sets and maps, whose keys cannot be blobs
+		collection := reflect.MakeMap(v.Type())
+		for _, key := range v.MapKeys() {
+			collection.SetMapIndex(key, reflect.ValueOf(copyValue(v.MapIndex(key).Interface())))
+		}
+		return collection.Interface()
+	}
+	return value
 }
 
 // Upsert works a lot like CreateIfNotExists but merges the data when it finds an existing row
@@ -291,10 +448,38 @@ func (c *Connector) mergedInsert(ei *dosa.EntityInfo,
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	if c.data[ei.Def.Name] == nil {
-		c.data[ei.Def.Name] = make(map[string][]map[string]dosa.FieldValue)
+	s, err := c.scopeFor(ei)
+	if err != nil {
+		return err
+	}
+	var change *dosa.RowChange
+	if c.feed.Watching(ei) {
+		change = &dosa.RowChange{Type: dosa.Insert}
+		if before := s.find(ei, values); before != nil {
+			change = &dosa.RowChange{Type: dosa.Update, Before: project(before, nil)}
+		}
+	}
+	// keep a copy, so that callers cannot change the stored row afterwards
+	if err := s.insert(ei, project(values, nil), mergeFunc); err != nil {
+		return err
+	}
+	if change != nil {
+		change.After = project(s.find(ei, values), nil)
+		c.feed.Publish(ei, change)
+	}
+	return nil
+}
+
+// insert adds a row to the table of an entity, calling mergeFunc if the row already exists
+func (s *scopeData) insert(ei *dosa.EntityInfo,
+	values map[string]dosa.FieldValue,
+	mergeFunc func(map[string]dosa.FieldValue, map[string]dosa.FieldValue) error) error {
+	key := tableKeyOf(ei)
+	if s.tables[key] == nil {
+		s.tables[key] = make(map[string][]map[string]dosa.FieldValue)
 	}
-	entityRef := c.data[ei.Def.Name]
+	s.defs[key] = ei.Def
+	entityRef := s.tables[key]
 	encodedPartitionKey := partitionKeyBuilder(ei, values)
 	if entityRef[encodedPartitionKey] == nil {
 		entityRef[encodedPartitionKey] = make([]map[string]dosa.FieldValue, 0, 1)
@@ -303,17 +488,18 @@ func (c *Connector) mergedInsert(ei *dosa.EntityInfo,
 	// no data in this partition? easy out!
 	if len(partitionRef) == 0 {
 		entityRef[encodedPartitionKey] = append(entityRef[encodedPartitionKey], values)
+		s.index(ei, values)
 		return nil
 	}
 
 	if len(ei.Def.ClusteringKeySet()) == 0 {
 		// no clustering key, so the row must already exist, merge it
-		return mergeFunc(partitionRef[0], values)
+		return s.merge(ei, partitionRef[0], values, mergeFunc)
 	}
 	// there is a clustering key, find the insertion point (binary search would be fastest)
 	found, offset := findInsertionPoint(ei, partitionRef, values)
 	if found {
-		return mergeFunc(partitionRef[offset], values)
+		return s.merge(ei, partitionRef[offset], values, mergeFunc)
 	}
 	// perform slice magic to insert value at given offset
 	l := len(entityRef[encodedPartitionKey])                                                                     // get length
@@ -322,6 +508,7 @@ func (c *Connector) mergedInsert(ei *dosa.EntityInfo,
 	copy(entityRef[encodedPartitionKey][offset+1:], entityRef[encodedPartitionKey][offset:])
 	// and plunk value into appropriate location
 	entityRef[encodedPartitionKey][offset] = values
+	s.index(ei, values)
 	return nil
 }
 
@@ -329,10 +516,14 @@ func (c *Connector) mergedInsert(ei *dosa.EntityInfo,
 func (c *Connector) Remove(_ context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if c.data[ei.Def.Name] == nil {
+	s, err := c.scopeFor(ei)
+	if err != nil {
+		return err
+	}
+	entityRef := s.tables[tableKeyOf(ei)]
+	if entityRef == nil {
 		return nil
 	}
-	entityRef := c.data[ei.Def.Name]
 	encodedPartitionKey := partitionKeyBuilder(ei, values)
 	if entityRef[encodedPartitionKey] == nil {
 		return nil
@@ -345,11 +536,15 @@ func (c *Connector) Remove(_ context.Context, ei *dosa.EntityInfo, values map[st
 
 	// no clustering keys? Simple, delete this
 	if len(ei.Def.ClusteringKeySet()) == 0 {
+		s.unindex(ei, partitionRef[0])
+		c.publishRemoved(ei, partitionRef[:1])
 		entityRef[encodedPartitionKey] = nil
 		return nil
 	}
 	found, offset := findInsertionPoint(ei, partitionRef, values)
 	if found {
+		s.unindex(ei, partitionRef[offset])
+		c.publishRemoved(ei, partitionRef[offset:offset+1])
 		entityRef[encodedPartitionKey] = append(entityRef[encodedPartitionKey][:offset], entityRef[encodedPartitionKey][offset+1:]...)
 	}
 	return nil
@@ -360,8 +555,16 @@ func (c *Connector) RemoveRange(_ context.Context, ei *dosa.EntityInfo, columnCo
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	partitionRange := c.findRange(ei, columnConditions)
+	partitionRange, err := c.findRange(ei, columnConditions)
+	if err != nil {
+		return err
+	}
 	if partitionRange != nil {
+		s := c.data[ei.Ref.Scope]
+		for _, values := range partitionRange.values() {
+			s.unindex(ei, values)
+		}
+		c.publishRemoved(ei, partitionRange.values())
 		partitionRange.delete()
 	}
 
@@ -373,78 +576,82 @@ func (c *Connector) Range(_ context.Context, ei *dosa.EntityInfo, columnConditio
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	partitionRange := c.findRange(ei, columnConditions)
+	partitionRange, err := c.findRange(ei, columnConditions)
+	if err != nil {
+		return nil, "", err
+	}
 	if partitionRange == nil {
 		return nil, "", &dosa.ErrNotFound{}
 	}
 
 	// TODO: enforce limits and return a token when there are more rows
-	return partitionRange.values(), "", nil
+	return projectAll(partitionRange.values(), minimumFields), "", nil
 }
 
 // findRange finds the partitionRange specified by the given entity info and column conditions.
-// In the case that no entities are found an empty partitionRange with a nil partition field will be returned.
+// In the case that no entities are found a nil partitionRange will be returned.
 //
 // Note that this function reads from the connector's data map. Any calling functions should hold
 // at least a read lock on the map.
-func (c *Connector) findRange(ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) *partitionRange {
-	if c.data[ei.Def.Name] == nil {
-		return nil
+func (c *Connector) findRange(ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) (*partitionRange, error) {
+	entityRef, err := c.table(ei)
+	if err != nil || entityRef == nil {
+		return nil, err
+	}
+
+	if err := dosa.EnsureValidRangeConditions(ei.Def, columnConditions, func(name string) string { return name }); err != nil {
+		return nil, err
 	}
-	entityRef := c.data[ei.Def.Name]
 
 	// find the equals conditions on each of the partition keys
 	values := make(map[string]dosa.FieldValue)
 	for _, pk := range ei.Def.Key.PartitionKeys {
-		// TODO: assert len(columnConditions[pk] == 1
-		// TODO: assert columnConditions[pk][0].Op is equals
 		values[pk] = columnConditions[pk][0].Value
 	}
 
 	encodedPartitionKey := partitionKeyBuilder(ei, values)
 	partitionRef := entityRef[encodedPartitionKey]
-	// no data in this partition? easy out!
-	if len(partitionRef) == 0 {
-		return nil
-	}
-	// hunt through the partitionRef and return values that match search criteria
-	// TODO: This can be done much faster using a binary search
-	startinx, endinx := 0, len(partitionRef)-1
-	for startinx < len(partitionRef) && !matchesClusteringConditions(ei, columnConditions, partitionRef[startinx]) {
-		startinx++
-	}
-	// TODO: adjust startinx with a passed in token
-	for endinx >= startinx && !matchesClusteringConditions(ei, columnConditions, partitionRef[endinx]) {
-		endinx--
-
-	}
+	// valid conditions select a contiguous set of rows within the sorted partition, so
+	// both ends of the range can be found with a binary search
+	startinx := sort.Search(len(partitionRef), func(offset int) bool {
+		return compareToConditions(ei, columnConditions, partitionRef[offset]) >= 0
+	})
+	endinx := sort.Search(len(partitionRef), func(offset int) bool {
+		return compareToConditions(ei, columnConditions, partitionRef[offset]) > 0
+	})
 	if endinx <= startinx {
-		return nil
+		return nil, nil
 	}
 
 	return &partitionRange{
 		entityRef:    entityRef,
 		partitionKey: encodedPartitionKey,
 		start:        startinx,
-		end:          endinx,
-	}
+		end:          endinx - 1,
+	}, nil
 }
 
-// matchesClusteringConditions checks if a data row matches the conditions in the columnConditions that apply to
-// clustering columns. If a condition does NOT match, it returns false, otherwise true
-// This function is pretty fast if there are no conditions on the clustering columns
-func matchesClusteringConditions(ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition, data map[string]dosa.FieldValue) bool {
+// compareToConditions locates a data row relative to the rows matching the conditions on the
+// clustering columns. It returns -1 if the row sorts before them, 1 if it sorts after them,
+// and 0 if the row matches all the conditions.
+func compareToConditions(ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition, data map[string]dosa.FieldValue) int8 {
 	for _, col := range ei.Def.Key.ClusteringKeys {
-		if conds, ok := columnConditions[col.Name]; ok {
-			// conditions exist on this clustering key
-			for _, cond := range conds {
-				if !passCol(data[col.Name], cond) {
-					return false
-				}
+		for _, cond := range columnConditions[col.Name] {
+			if passCol(data[col.Name], cond) {
+				continue
+			}
+			// the value is either too small or too large, which is reversed for descending columns
+			var cmp int8 = 1
+			if cond.Op == dosa.Gt || cond.Op == dosa.GtOrEq || (cond.Op == dosa.Eq && compareType(data[col.Name], cond.Value) < 0) {
+				cmp = -1
+			}
+			if col.Descending {
+				cmp = -cmp
 			}
+			return cmp
 		}
 	}
-	return true
+	return 0
 }
 
 // passCol checks if a column passes a specific condition
@@ -469,10 +676,13 @@ func passCol(data dosa.FieldValue, cond *dosa.Condition) bool {
 func (c *Connector) Scan(_ context.Context, ei *dosa.EntityInfo, minimumFields []string, token string, limit int) ([]map[string]dosa.FieldValue, string, error) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	if c.data[ei.Def.Name] == nil {
+	entityRef, err := c.table(ei)
+	if err != nil {
+		return nil, "", err
+	}
+	if entityRef == nil {
 		return nil, "", &dosa.ErrNotFound{}
 	}
-	entityRef := c.data[ei.Def.Name]
 	allTheThings := make([]map[string]dosa.FieldValue, 0)
 	// TODO: stop when we reach the limit, and make a token for continuation
 	for _, vals := range entityRef {
@@ -481,13 +691,49 @@ func (c *Connector) Scan(_ context.Context, ei *dosa.EntityInfo, minimumFields [
 	if len(allTheThings) == 0 {
 		return nil, "", &dosa.ErrNotFound{}
 	}
-	return allTheThings, "", nil
+	return projectAll(allTheThings, minimumFields), "", nil
+}
+
+// CreateScope creates a new, empty scope
+func (c *Connector) CreateScope(_ context.Context, scope string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.data[scope]; ok {
+		return errors.Wrapf(&dosa.ErrAlreadyExists{}, "scope %q", scope)
+	}
+	c.data[scope] = newScopeData()
+	return nil
 }
 
-// CheckSchema is just a stub; there is no schema management for the in memory connector
-// since creating a new one leaves you with no data!
-func (c *Connector) CheckSchema(ctx context.Context, scope, namePrefix string, ed []*dosa.EntityDefinition) (int32, error) {
-	return 1, nil
+// TruncateScope removes all the data in a scope, but keeps the scope and its schema versions
+func (c *Connector) TruncateScope(_ context.Context, scope string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s, err := c.scope(scope)
+	if err != nil {
+		return err
+	}
+	s.truncate()
+	return nil
+}
+
+// DropScope removes a scope and all of its data
+func (c *Connector) DropScope(_ context.Context, scope string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, err := c.scope(scope); err != nil {
+		return err
+	}
+	delete(c.data, scope)
+	return nil
+}
+
+// ScopeExists returns true if the scope was created and not dropped since
+func (c *Connector) ScopeExists(_ context.Context, scope string) (bool, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	_, ok := c.data[scope]
+	return ok, nil
 }
 
 // Shutdown deletes all the data
@@ -506,12 +752,30 @@ func Name() string {
 // NewConnector creates a new in-memory connector
 func NewConnector() *Connector {
 	c := Connector{}
-	c.data = make(map[string]map[string][]map[string]dosa.FieldValue)
+	c.data = make(map[string]*scopeData)
 	return &c
 }
 
+// NewAutoUpsertConnector creates a new in-memory connector which, unlike the
+// gateway, upserts the schemas that were not upserted yet in CheckSchema
+func NewAutoUpsertConnector() *Connector {
+	c := NewConnector()
+	c.autoUpsert = true
+	return c
+}
+
 func init() {
-	dosa.RegisterConnector("memory", func(dosa.CreationArgs) (dosa.Connector, error) {
-		return NewConnector(), nil
+	dosa.RegisterConnector("memory", func(args dosa.CreationArgs) (dosa.Connector, error) {
+		c := NewConnector()
+		if autoUpsert, ok := args["autoUpsert"].(bool); ok {
+			c.autoUpsert = autoUpsert
+		}
+		// optionally seed the connector with a snapshot file
+		if path, ok := args["snapshot"].(string); ok && path != "" {
+			if err := c.RestoreFile(path); err != nil {
+				return nil, errors.Wrapf(err, "failed to restore snapshot %q", path)
+			}
+		}
+		return c, nil
 	})
 }
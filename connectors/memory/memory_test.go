@@ -36,7 +36,7 @@ var testSchemaRef = dosa.SchemaRef{
 	Scope:      "scope1",
 	NamePrefix: "namePrefix",
 	EntityName: "eName",
-	Version:    12345,
+	Version:    1,
 }
 
 var testEi = &dosa.EntityInfo{
@@ -82,8 +82,17 @@ var clusteredEi = &dosa.EntityInfo{
 	},
 }
 
+// newScopedConnector returns a connector in which the scope of testSchemaRef exists,
+// along with the first version of its schema
+func newScopedConnector() *Connector {
+	c := NewConnector()
+	_ = c.CreateScope(context.TODO(), testSchemaRef.Scope)
+	_, _ = c.UpsertSchema(context.TODO(), testSchemaRef.Scope, testSchemaRef.NamePrefix, []*dosa.EntityDefinition{testEi.Def})
+	return c
+}
+
 func TestConnector_CreateIfNotExists(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	err := sut.CreateIfNotExists(context.TODO(), testEi, map[string]dosa.FieldValue{
 		"f1": dosa.FieldValue("data"),
@@ -97,7 +106,7 @@ func TestConnector_CreateIfNotExists(t *testing.T) {
 	assert.True(t, dosa.ErrorIsAlreadyExists(err))
 }
 func TestConnector_Upsert(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	err := sut.Upsert(context.TODO(), testEi, map[string]dosa.FieldValue{
 		"f1": dosa.FieldValue("data"),
@@ -121,7 +130,7 @@ func TestConnector_Upsert(t *testing.T) {
 }
 
 func TestConnector_Read(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	// read with no data
 	vals, err := sut.Read(context.TODO(), testEi, map[string]dosa.FieldValue{
@@ -188,7 +197,7 @@ func TestConnector_Read(t *testing.T) {
 }
 
 func TestConnector_Remove(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	// remove with no data
 	err := sut.Remove(context.TODO(), testEi, map[string]dosa.FieldValue{
@@ -241,7 +250,7 @@ func TestConnector_Remove(t *testing.T) {
 
 func TestConnector_RemoveRange(t *testing.T) {
 	const idcount = 10
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	// test removing a range with no data in the range
 	err := sut.RemoveRange(context.TODO(), clusteredEi, map[string][]*dosa.Condition{
@@ -296,8 +305,57 @@ func TestConnector_RemoveRange(t *testing.T) {
 	assert.True(t, dosa.ErrorIsNotFound(err))
 }
 
-func TestConnector_Shutdown(t *testing.T) {
+func TestConnector_Scopes(t *testing.T) {
 	sut := NewConnector()
+	values := map[string]dosa.FieldValue{"f1": dosa.FieldValue("data")}
+
+	// writing to a missing scope fails
+	err := sut.Upsert(context.TODO(), testEi, values)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	assert.Contains(t, err.Error(), `"scope1"`)
+	_, err = sut.Read(context.TODO(), testEi, values, dosa.All())
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	assert.True(t, dosa.ErrorIsNotFound(sut.TruncateScope(context.TODO(), "scope1")))
+	assert.True(t, dosa.ErrorIsNotFound(sut.DropScope(context.TODO(), "scope1")))
+
+	assert.NoError(t, sut.CreateScope(context.TODO(), "scope1"))
+	assert.True(t, dosa.ErrorIsAlreadyExists(sut.CreateScope(context.TODO(), "scope1")))
+	_, err = sut.UpsertSchema(context.TODO(), "scope1", testSchemaRef.NamePrefix, []*dosa.EntityDefinition{testEi.Def})
+	assert.NoError(t, err)
+	exists, err := sut.ScopeExists(context.TODO(), "scope1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, sut.Upsert(context.TODO(), testEi, values))
+
+	// the same entity in another scope or with another name prefix is a different table
+	otherScope := *testEi.Ref
+	otherScope.Scope = "scope2"
+	otherPrefix := *testEi.Ref
+	otherPrefix.NamePrefix = "otherPrefix"
+	assert.NoError(t, sut.CreateScope(context.TODO(), "scope2"))
+	for _, ref := range []*dosa.SchemaRef{&otherScope, &otherPrefix} {
+		_, err = sut.UpsertSchema(context.TODO(), ref.Scope, ref.NamePrefix, []*dosa.EntityDefinition{testEi.Def})
+		assert.NoError(t, err)
+		_, err = sut.Read(context.TODO(), &dosa.EntityInfo{Ref: ref, Def: testEi.Def}, values, dosa.All())
+		assert.True(t, dosa.ErrorIsNotFound(err))
+	}
+
+	// truncating keeps the scope and its schema but removes its data
+	assert.NoError(t, sut.TruncateScope(context.TODO(), "scope1"))
+	_, err = sut.Read(context.TODO(), testEi, values, dosa.All())
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	assert.NoError(t, sut.Upsert(context.TODO(), testEi, values))
+
+	assert.NoError(t, sut.DropScope(context.TODO(), "scope1"))
+	exists, err = sut.ScopeExists(context.TODO(), "scope1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	err = sut.Upsert(context.TODO(), testEi, values)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+}
+
+func TestConnector_Shutdown(t *testing.T) {
+	sut := newScopedConnector()
 
 	err := sut.Shutdown()
 	assert.NoError(t, err)
@@ -306,7 +364,7 @@ func TestConnector_Shutdown(t *testing.T) {
 
 // test CreateIfNotExists with partitioning
 func TestConnector_CreateIfNotExists2(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	testUUIDs := make([]dosa.UUID, 10)
 	for x := 0; x < 10; x++ {
@@ -354,7 +412,7 @@ func TestConnector_CreateIfNotExists2(t *testing.T) {
 }
 
 func TestConnector_Upsert2(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	testUUIDs := make([]dosa.UUID, 10)
 	for x := 0; x < 10; x++ {
@@ -405,7 +463,7 @@ func TestConnector_Upsert2(t *testing.T) {
 
 func TestConnector_Range(t *testing.T) {
 	const idcount = 10
-	sut := NewConnector()
+	sut := newScopedConnector()
 
 	// no data at all (corner case)
 	data, token, err := sut.Range(context.TODO(), clusteredEi, map[string][]*dosa.Condition{
@@ -501,7 +559,7 @@ func TestConnector_Range(t *testing.T) {
 }
 
 func TestConnector_TimeUUIDs(t *testing.T) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 	const idcount = 10
 
 	// insert a bunch of values with V1 timestamps as clustering keys
@@ -553,7 +611,7 @@ func (u ByUUID) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
 func (u ByUUID) Less(i, j int) bool { return string(u[i]) > string(u[j]) }
 
 func BenchmarkConnector_CreateIfNotExists(b *testing.B) {
-	sut := NewConnector()
+	sut := newScopedConnector()
 	for x := 0; x < b.N; x++ {
 		id := dosa.NewUUID()
 		err := sut.CreateIfNotExists(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
@@ -562,7 +620,7 @@ func BenchmarkConnector_CreateIfNotExists(b *testing.B) {
 			"c7": dosa.FieldValue(id)})
 		assert.NoError(b, err)
 		if x%1000 == 0 {
-			sut.data = nil
+			_ = sut.TruncateScope(context.TODO(), testSchemaRef.Scope)
 		}
 	}
 }
@@ -573,7 +631,7 @@ func BenchmarkConnector_Read(b *testing.B) {
 	for x := 0; x < idcount; x++ {
 		testUUIDs[x] = dosa.NewUUID()
 	}
-	sut := NewConnector()
+	sut := newScopedConnector()
 	for x := 0; x < idcount; x++ {
 		err := sut.CreateIfNotExists(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
 			"f1": dosa.FieldValue("data"),
@@ -591,6 +649,173 @@ func BenchmarkConnector_Read(b *testing.B) {
 	}
 }
 
+// rangedEi has two clustering keys sorted in opposite directions
+var rangedEi = &dosa.EntityInfo{
+	Ref: &testSchemaRef,
+	Def: &dosa.EntityDefinition{
+		Name: "ranged",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"f1"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "a"}, {Name: "b", Descending: true}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "f1", Type: dosa.String},
+			{Name: "a", Type: dosa.Int64},
+			{Name: "b", Type: dosa.Int64},
+			{Name: "c", Type: dosa.Blob},
+		},
+	},
+}
+
+// linearRange is the reference implementation of Range: it checks every row of the partition
+func linearRange(partition []map[string]dosa.FieldValue, columnConditions map[string][]*dosa.Condition) []map[string]dosa.FieldValue {
+	var matches []map[string]dosa.FieldValue
+	for _, row := range partition {
+		matched := true
+		for _, name := range []string{"a", "b"} {
+			for _, cond := range columnConditions[name] {
+				matched = matched && passCol(row[name], cond)
+			}
+		}
+		if matched {
+			matches = append(matches, row)
+		}
+	}
+	return matches
+}
+
+func TestConnector_RangeMatchesLinearSearch(t *testing.T) {
+	sut := newScopedConnector()
+	for a := 0; a < 5; a++ {
+		for b := 0; b < 5; b++ {
+			assert.NoError(t, sut.Upsert(context.TODO(), rangedEi, map[string]dosa.FieldValue{
+				"f1": "data", "a": int64(a), "b": int64(b)}))
+		}
+	}
+	partitionOnly := map[string][]*dosa.Condition{"f1": {{Op: dosa.Eq, Value: "data"}}}
+	partition, _, err := sut.Range(context.TODO(), rangedEi, partitionOnly, dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, partition, 25)
+
+	ops := []dosa.Operator{dosa.Eq, dosa.Gt, dosa.GtOrEq, dosa.Lt, dosa.LtOrEq}
+	var tcs []map[string][]*dosa.Condition
+	for v := int64(-1); v <= 5; v++ {
+		for _, op := range ops {
+			tcs = append(tcs, map[string][]*dosa.Condition{"a": {{Op: op, Value: v}}})
+			tcs = append(tcs, map[string][]*dosa.Condition{"a": {{Op: dosa.Eq, Value: int64(2)}}, "b": {{Op: op, Value: v}}})
+		}
+		for w := v + 1; w <= 5; w++ {
+			tcs = append(tcs, map[string][]*dosa.Condition{"a": {{Op: dosa.GtOrEq, Value: v}, {Op: dosa.Lt, Value: w}}})
+			tcs = append(tcs, map[string][]*dosa.Condition{"a": {{Op: dosa.Eq, Value: int64(4)}}, "b": {{Op: dosa.Gt, Value: v}, {Op: dosa.LtOrEq, Value: w}}})
+		}
+	}
+	for _, conditions := range tcs {
+		conditions["f1"] = partitionOnly["f1"]
+		expected := linearRange(partition, conditions)
+		actual, _, err := sut.Range(context.TODO(), rangedEi, conditions, dosa.All(), "", 0)
+		if len(expected) == 0 {
+			assert.True(t, dosa.ErrorIsNotFound(err), "%v", conditions)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "%v", conditions)
+	}
+}
+
+func TestConnector_RangeInvalidConditions(t *testing.T) {
+	sut := newScopedConnector()
+	assert.NoError(t, sut.Upsert(context.TODO(), rangedEi, map[string]dosa.FieldValue{"f1": "data", "a": int64(1), "b": int64(1)}))
+	for _, conditions := range []map[string][]*dosa.Condition{
+		{"a": {{Op: dosa.Eq, Value: int64(1)}}},
+		{"f1": {{Op: dosa.Eq, Value: "data"}}, "c": {{Op: dosa.Eq, Value: []byte{}}}},
+		{"f1": {{Op: dosa.Eq, Value: "data"}}, "b": {{Op: dosa.Eq, Value: int64(1)}}},
+	} {
+		_, _, err := sut.Range(context.TODO(), rangedEi, conditions, dosa.All(), "", 0)
+		assert.Error(t, err)
+		assert.False(t, dosa.ErrorIsNotFound(err))
+		assert.Error(t, sut.RemoveRange(context.TODO(), rangedEi, conditions))
+	}
+}
+
+func TestConnector_ProjectionAndCopies(t *testing.T) {
+	sut := newScopedConnector()
+	values := map[string]dosa.FieldValue{"f1": "data", "a": int64(1), "b": int64(2), "c": []byte{1, 2}}
+	assert.NoError(t, sut.Upsert(context.TODO(), rangedEi, values))
+	// changing the written row does not change the stored one
+	values["a"] = int64(3)
+	values["c"].([]byte)[0] = 9
+
+	keys := map[string]dosa.FieldValue{"f1": "data", "a": int64(1), "b": int64(2)}
+	row, err := sut.Read(context.TODO(), rangedEi, keys, []string{"b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosa.FieldValue{"b": int64(2), "c": []byte{1, 2}}, row)
+	// neither does changing the returned row
+	row["b"] = int64(5)
+	row["c"].([]byte)[0] = 9
+
+	rows, _, err := sut.Range(context.TODO(), rangedEi, map[string][]*dosa.Condition{"f1": {{Op: dosa.Eq, Value: "data"}}}, []string{"c"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"c": []byte{1, 2}}}, rows)
+	rows[0]["c"].([]byte)[1] = 9
+
+	rows, _, err = sut.Scan(context.TODO(), rangedEi, []string{"a", "missing"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"a": int64(1)}}, rows)
+
+	row, err = sut.Read(context.TODO(), rangedEi, keys, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosa.FieldValue{"f1": "data", "a": int64(1), "b": int64(2), "c": []byte{1, 2}}, row)
+}
+
+// populate100k writes 100k rows to a single partition
+func populate100k(b *testing.B) *Connector {
+	sut := newScopedConnector()
+	for x := 0; x < 100000; x++ {
+		err := sut.CreateIfNotExists(context.TODO(), rangedEi, map[string]dosa.FieldValue{
+			"f1": "data", "a": int64(x / 10), "b": int64(x % 10)})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	return sut
+}
+
+func BenchmarkConnector_Range100k(b *testing.B) {
+	sut := populate100k(b)
+	tcs := map[string]map[string][]*dosa.Condition{
+		"narrow": {"a": {{Op: dosa.Eq, Value: int64(5000)}}, "b": {{Op: dosa.GtOrEq, Value: int64(5)}}},
+		"wide":   {"a": {{Op: dosa.GtOrEq, Value: int64(1000)}, {Op: dosa.Lt, Value: int64(9000)}}},
+	}
+	partition := sut.data[testSchemaRef.Scope].tables[tableKeyOf(rangedEi)][partitionKeyBuilder(rangedEi, map[string]dosa.FieldValue{"f1": "data"})]
+	for name, conditions := range tcs {
+		conditions["f1"] = []*dosa.Condition{{Op: dosa.Eq, Value: "data"}}
+		b.Run(name+"/binary", func(b *testing.B) {
+			for x := 0; x < b.N; x++ {
+				if _, _, err := sut.Range(context.TODO(), rangedEi, conditions, []string{"a"}, "", 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(name+"/linear", func(b *testing.B) {
+			for x := 0; x < b.N; x++ {
+				projectAll(linearRange(partition, conditions), []string{"a"})
+			}
+		})
+	}
+}
+
+func BenchmarkConnector_Read100k(b *testing.B) {
+	sut := populate100k(b)
+	b.ResetTimer()
+	for x := 0; x < b.N; x++ {
+		_, err := sut.Read(context.TODO(), rangedEi, map[string]dosa.FieldValue{
+			"f1": "data", "a": int64(x % 10000), "b": int64(x % 10)}, dosa.All())
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestCompareType(t *testing.T) {
 	tuuid := dosa.NewUUID()
 	v1uuid := dosa.UUID(uuid.NewV1().String())
@@ -608,6 +833,8 @@ func TestCompareType(t *testing.T) {
 		{dosa.FieldValue(false), dosa.FieldValue(false), 0},
 		{dosa.FieldValue([]byte{1}), dosa.FieldValue([]byte{1}), 0},
 		{dosa.FieldValue(1.0), dosa.FieldValue(1.0), 0},
+		{dosa.FieldValue(dosa.Decimal("1.5")), dosa.FieldValue(dosa.Decimal("1.50")), 0},
+		{dosa.FieldValue(dosa.NewDate(2017, 1, 2)), dosa.FieldValue(dosa.NewDate(2017, 1, 2)), 0},
 
 		{dosa.FieldValue(int32(1)), dosa.FieldValue(int32(2)), -1},
 		{dosa.FieldValue(int64(1)), dosa.FieldValue(int64(2)), -1},
@@ -618,6 +845,8 @@ func TestCompareType(t *testing.T) {
 		{dosa.FieldValue(false), dosa.FieldValue(true), -1},
 		{dosa.FieldValue([]byte{1}), dosa.FieldValue([]byte{2}), -1},
 		{dosa.FieldValue(0.9), dosa.FieldValue(1.0), -1},
+		{dosa.FieldValue(dosa.Decimal("9.99")), dosa.FieldValue(dosa.Decimal("10")), -1},
+		{dosa.FieldValue(dosa.NewDate(2016, 12, 31)), dosa.FieldValue(dosa.NewDate(2017, 1, 1)), -1},
 
 		{dosa.FieldValue(int32(2)), dosa.FieldValue(int32(1)), 1},
 		{dosa.FieldValue(int64(2)), dosa.FieldValue(int64(1)), 1},
@@ -628,6 +857,8 @@ func TestCompareType(t *testing.T) {
 		{dosa.FieldValue(true), dosa.FieldValue(false), 1},
 		{dosa.FieldValue([]byte{2}), dosa.FieldValue([]byte{1}), 1},
 		{dosa.FieldValue(1.1), dosa.FieldValue(1.0), 1},
+		{dosa.FieldValue(dosa.Decimal("-1")), dosa.FieldValue(dosa.Decimal("-1.01")), 1},
+		{dosa.FieldValue(dosa.NewDate(2017, 2, 1)), dosa.FieldValue(dosa.NewDate(2017, 1, 31)), 1},
 	}
 	for _, test := range tests {
 		assert.Equal(t, test.result, compareType(test.t1, test.t2))
@@ -636,8 +867,39 @@ func TestCompareType(t *testing.T) {
 	assert.Panics(t, func() { compareType(t, t) })
 }
 
-func TestConnector_Scan(t *testing.T) {
+func TestConnector_DecimalPartitionKey(t *testing.T) {
 	sut := NewConnector()
+	ei := &dosa.EntityInfo{
+		Ref: &testSchemaRef,
+		Def: &dosa.EntityDefinition{
+			Name: "prices",
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "amount", Type: dosa.TDecimal},
+				{Name: "label", Type: dosa.String},
+			},
+			Key: &dosa.PrimaryKey{PartitionKeys: []string{"amount"}},
+		},
+	}
+	assert.NoError(t, sut.CreateScope(context.TODO(), ei.Ref.Scope))
+	_, err := sut.UpsertSchema(context.TODO(), ei.Ref.Scope, ei.Ref.NamePrefix, []*dosa.EntityDefinition{ei.Def})
+	assert.NoError(t, err)
+
+	// equal decimals find the same row, whatever their number of digits
+	assert.NoError(t, sut.Upsert(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("1.5"), "label": "first"}))
+	values, err := sut.Read(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("1.50")}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "first", values["label"])
+	assert.NoError(t, sut.Upsert(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("01.500"), "label": "second"}))
+	values, err = sut.Read(context.TODO(), ei, map[string]dosa.FieldValue{"amount": dosa.Decimal("1.5")}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", values["label"])
+	rows, _, err := sut.Scan(context.TODO(), ei, dosa.All(), "", 100)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
+
+func TestConnector_Scan(t *testing.T) {
+	sut := newScopedConnector()
 	testUUIDs := make([]dosa.UUID, 10)
 	for x := 0; x < 10; x++ {
 		testUUIDs[x] = dosa.NewUUID()
@@ -680,6 +942,12 @@ func TestConstruction(t *testing.T) {
 	assert.IsType(t, NewConnector(), c)
 
 	v, err := c.CheckSchema(context.TODO(), "dummy", "dummy", nil)
+	assert.Equal(t, int32(dosa.InvalidVersion), v)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	c, err = dosa.GetConnector("memory", dosa.CreationArgs{"autoUpsert": true})
+	assert.NoError(t, err)
+	v, err = c.CheckSchema(context.TODO(), "dummy", "dummy", nil)
 	assert.Equal(t, int32(1), v)
 	assert.NoError(t, err)
 }
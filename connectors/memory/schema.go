@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// schemaVersion returns a specific schema version for a name prefix
+func (s *scopeData) schemaVersion(scope, namePrefix string, version int32) (*dosa.SchemaVersion, error) {
+	versions := s.schemas[namePrefix]
+	// versions are numbered from 1 without gaps
+	if version < 1 || int(version) > len(versions) {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "version %d of schema %q in scope %q", version, namePrefix, scope)
+	}
+	return versions[version-1], nil
+}
+
+// CheckSchema returns the latest version upserted with exactly the provided
+// entity definitions, or an error like the gateway. Connectors created with
+// auto upsert instead upsert a schema that was not upserted yet, creating its
+// scope if needed, so that they can be used right away.
+func (c *Connector) CheckSchema(ctx context.Context, scope, namePrefix string, eds []*dosa.EntityDefinition) (int32, error) {
+	version, err := c.matchingVersion(scope, namePrefix, eds)
+	if !c.autoUpsert || !dosa.ErrorIsNotFound(err) {
+		return version, err
+	}
+	if err := c.CreateScope(ctx, scope); err != nil && !dosa.ErrorIsAlreadyExists(err) {
+		return dosa.InvalidVersion, err
+	}
+	status, err := c.UpsertSchema(ctx, scope, namePrefix, eds)
+	if err != nil {
+		return dosa.InvalidVersion, err
+	}
+	return status.Version, nil
+}
+
+// matchingVersion returns the latest version upserted with exactly the provided entity definitions
+func (c *Connector) matchingVersion(scope, namePrefix string, eds []*dosa.EntityDefinition) (int32, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, err := c.scope(scope)
+	if err != nil {
+		return dosa.InvalidVersion, err
+	}
+	if sv := dosa.MatchingSchemaVersion(s.schemas[namePrefix], eds); sv != nil {
+		return sv.Version, nil
+	}
+	return dosa.InvalidVersion, errors.Wrapf(&dosa.ErrNotFound{}, "no matching schema %q in scope %q", namePrefix, scope)
+}
+
+// UpsertSchema stores a new version of the schema, provided it is compatible
+// with the latest one. Upserting the latest schema again does not create a new version.
+func (c *Connector) UpsertSchema(_ context.Context, scope, namePrefix string, eds []*dosa.EntityDefinition) (*dosa.SchemaStatus, error) {
+	for _, ed := range eds {
+		if err := ed.EnsureValid(); err != nil {
+			return nil, errors.Wrap(err, "invalid entity definition")
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s, err := c.scope(scope)
+	if err != nil {
+		return nil, err
+	}
+	versions := s.schemas[namePrefix]
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if latest.Same(eds) {
+			return &dosa.SchemaStatus{Version: latest.Version, Status: dosa.SchemaStatusCompleted}, nil
+		}
+		if err := latest.EnsureCompatible(eds); err != nil {
+			return nil, err
+		}
+	}
+	sv := &dosa.SchemaVersion{Version: int32(len(versions) + 1), Entities: append([]*dosa.EntityDefinition(nil), eds...)}
+	s.schemas[namePrefix] = append(versions, sv)
+	return &dosa.SchemaStatus{Version: sv.Version, Status: dosa.SchemaStatusAccepted}, nil
+}
+
+// CheckSchemaStatus returns the status of an upserted schema version. Versions
+// are applied as soon as they are upserted, so every known version is completed.
+func (c *Connector) CheckSchemaStatus(_ context.Context, scope, namePrefix string, version int32) (*dosa.SchemaStatus, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, err := c.scope(scope)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.schemaVersion(scope, namePrefix, version); err != nil {
+		return nil, err
+	}
+	return &dosa.SchemaStatus{Version: version, Status: dosa.SchemaStatusCompleted}, nil
+}
+
+// GetSchema returns the entity definitions of an upserted schema version, or
+// of the latest one for dosa.LatestVersion
+func (c *Connector) GetSchema(_ context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, err := c.scope(scope)
+	if err != nil {
+		return nil, err
+	}
+	if version == dosa.LatestVersion {
+		version = int32(len(s.schemas[namePrefix]))
+	}
+	sv, err := s.schemaVersion(scope, namePrefix, version)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*dosa.EntityDefinition(nil), sv.Entities...), nil
+}
+
+// ListSchemaVersions returns the status of every upserted version of a schema, oldest first
+func (c *Connector) ListSchemaVersions(_ context.Context, scope, namePrefix string) ([]*dosa.SchemaStatus, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, err := c.scope(scope)
+	if err != nil {
+		return nil, err
+	}
+	versions := s.schemas[namePrefix]
+	statuses := make([]*dosa.SchemaStatus, len(versions))
+	for i, sv := range versions {
+		statuses[i] = &dosa.SchemaStatus{Version: sv.Version, Status: dosa.SchemaStatusCompleted}
+	}
+	return statuses, nil
+}
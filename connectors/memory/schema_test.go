@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func TestConnector_SchemaVersions(t *testing.T) {
+	sut := NewConnector()
+	scope, prefix := testSchemaRef.Scope, testSchemaRef.NamePrefix
+	v1 := testEi.Def
+
+	// schema operations need an existing scope
+	_, err := sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	_, err = sut.CheckSchemaStatus(context.TODO(), scope, prefix, 1)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	assert.NoError(t, sut.CreateScope(context.TODO(), scope))
+
+	status, err := sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, &dosa.SchemaStatus{Version: 1, Status: dosa.SchemaStatusAccepted}, status)
+	// upserting the same schema again does not create a new version
+	status, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, &dosa.SchemaStatus{Version: 1, Status: dosa.SchemaStatusCompleted}, status)
+
+	// adding a column creates a new version
+	v2 := *v1
+	v2.Columns = append([]*dosa.ColumnDefinition{{Name: "c8", Type: dosa.Int64}}, v1.Columns...)
+	status, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{&v2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), status.Version)
+
+	// removing it again is not compatible
+	_, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.Error(t, err)
+	// neither is an invalid definition
+	_, err = sut.UpsertSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{{Name: "bad"}})
+	assert.Error(t, err)
+
+	// each definition matches the version it was upserted in
+	version, err := sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), version)
+	version, err = sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{&v2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), version)
+	version, err = sut.CheckSchema(context.TODO(), scope, "otherPrefix", []*dosa.EntityDefinition{v1})
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	assert.Equal(t, int32(dosa.InvalidVersion), version)
+
+	status, err = sut.CheckSchemaStatus(context.TODO(), scope, prefix, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &dosa.SchemaStatus{Version: 2, Status: dosa.SchemaStatusCompleted}, status)
+	_, err = sut.CheckSchemaStatus(context.TODO(), scope, prefix, 3)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	// the definitions of every version can be read back
+	eds, err := sut.GetSchema(context.TODO(), scope, prefix, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.EntityDefinition{v1}, eds)
+	eds, err = sut.GetSchema(context.TODO(), scope, prefix, dosa.LatestVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.EntityDefinition{&v2}, eds)
+	_, err = sut.GetSchema(context.TODO(), scope, prefix, 3)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+	_, err = sut.GetSchema(context.TODO(), scope, "otherPrefix", dosa.LatestVersion)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	statuses, err := sut.ListSchemaVersions(context.TODO(), scope, prefix)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.SchemaStatus{{Version: 1, Status: dosa.SchemaStatusCompleted}, {Version: 2, Status: dosa.SchemaStatusCompleted}}, statuses)
+	statuses, err = sut.ListSchemaVersions(context.TODO(), scope, "otherPrefix")
+	assert.NoError(t, err)
+	assert.Empty(t, statuses)
+}
+
+func TestConnector_CheckSchemaAutoUpsert(t *testing.T) {
+	sut := NewAutoUpsertConnector()
+	scope, prefix := testSchemaRef.Scope, testSchemaRef.NamePrefix
+	v1 := testEi.Def
+
+	// checking a new schema upserts it
+	version, err := sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), version)
+	exists, err := sut.ScopeExists(context.TODO(), scope)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	version, err = sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{v1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), version)
+	assert.NoError(t, sut.Upsert(context.TODO(), testEi, map[string]dosa.FieldValue{"f1": dosa.FieldValue("data")}))
+
+	// but incompatible changes are still refused
+	v2 := *v1
+	v2.Columns = v1.Columns[:len(v1.Columns)-1]
+	_, err = sut.CheckSchema(context.TODO(), scope, prefix, []*dosa.EntityDefinition{&v2})
+	assert.Error(t, err)
+}
+
+func TestConnector_UnknownVersion(t *testing.T) {
+	sut := newScopedConnector()
+	values := map[string]dosa.FieldValue{"f1": dosa.FieldValue("data")}
+	assert.NoError(t, sut.Upsert(context.TODO(), testEi, values))
+
+	for _, version := range []int32{dosa.InvalidVersion, 0, 2} {
+		ref := testSchemaRef
+		ref.Version = version
+		ei := &dosa.EntityInfo{Ref: &ref, Def: testEi.Def}
+		err := sut.Upsert(context.TODO(), ei, values)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "version")
+		}
+		_, err = sut.Read(context.TODO(), ei, values, dosa.All())
+		assert.Error(t, err)
+		_, _, err = sut.Scan(context.TODO(), ei, dosa.All(), "", 0)
+		assert.Error(t, err)
+		assert.Error(t, sut.Remove(context.TODO(), ei, values))
+	}
+}
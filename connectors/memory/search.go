@@ -0,0 +1,228 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// searchableTag marks a column that can be searched on
+const searchableTag = "searchable"
+
+// searchIndex is an inverted map for one searchable column. It maps each encoded
+// value to the rows holding it, keyed by their encoded primary key.
+type searchIndex map[string]map[string]map[string]dosa.FieldValue
+
+// isSearchable returns true if a column was tagged searchable
+func isSearchable(cd *dosa.ColumnDefinition) bool {
+	_, ok := cd.Tags[searchableTag]
+	return ok
+}
+
+// encodeFieldValue generates a unique string for a value, using the same
+// encoding as partitionKeyBuilder. Collections are encoded as JSON instead,
+// since gob encodes maps in no particular order.
+func encodeFieldValue(value dosa.FieldValue) string {
+	if dosa.TypeOf(value).IsCollection() {
+		encoded, _ := dosa.MarshalCollection(value)
+		return string(encoded)
+	}
+	encoded := bytes.Buffer{}
+	_ = gob.NewEncoder(&encoded).Encode(value)
+	return string(encoded.Bytes())
+}
+
+// primaryKeyBuilder encodes all the primary key components of a row, generating a
+// string that is unique within the table
+func primaryKeyBuilder(ei *dosa.EntityInfo, values map[string]dosa.FieldValue) string {
+	encodedKey := bytes.Buffer{}
+	encoder := gob.NewEncoder(&encodedKey)
+	for _, k := range ei.Def.Key.PartitionKeys {
+		_ = encoder.Encode(values[k])
+	}
+	for _, k := range ei.Def.Key.ClusteringKeys {
+		_ = encoder.Encode(values[k.Name])
+	}
+	return string(encodedKey.Bytes())
+}
+
+// index adds a row to the inverted maps of every searchable column of its entity
+func (s *scopeData) index(ei *dosa.EntityInfo, values map[string]dosa.FieldValue) {
+	key := tableKeyOf(ei)
+	for _, cd := range ei.Def.Columns {
+		value, ok := values[cd.Name]
+		if !ok || value == nil || !isSearchable(cd) {
+			continue
+		}
+		if s.indexes[key] == nil {
+			s.indexes[key] = make(map[string]searchIndex)
+		}
+		idx := s.indexes[key][cd.Name]
+		if idx == nil {
+			idx = make(searchIndex)
+			s.indexes[key][cd.Name] = idx
+		}
+		encodedValue := encodeFieldValue(value)
+		if idx[encodedValue] == nil {
+			idx[encodedValue] = make(map[string]map[string]dosa.FieldValue)
+		}
+		idx[encodedValue][primaryKeyBuilder(ei, values)] = values
+	}
+}
+
+// unindex removes a row from the inverted maps of its table. Every indexed column is
+// checked, in case the row was written with another version of the entity.
+func (s *scopeData) unindex(ei *dosa.EntityInfo, values map[string]dosa.FieldValue) {
+	for column, idx := range s.indexes[tableKeyOf(ei)] {
+		value, ok := values[column]
+		if !ok || value == nil {
+			continue
+		}
+		encodedValue := encodeFieldValue(value)
+		delete(idx[encodedValue], primaryKeyBuilder(ei, values))
+		if len(idx[encodedValue]) == 0 {
+			delete(idx, encodedValue)
+		}
+	}
+}
+
+// merge calls mergeFunc on an existing row, keeping the inverted maps up to date
+func (s *scopeData) merge(ei *dosa.EntityInfo, into, from map[string]dosa.FieldValue,
+	mergeFunc func(map[string]dosa.FieldValue, map[string]dosa.FieldValue) error) error {
+	s.unindex(ei, into)
+	defer s.index(ei, into)
+	return mergeFunc(into, from)
+}
+
+// Search returns the rows holding a value in a searchable column, ordered by
+// partition key, then by clustering key like the rows of a partition. The token
+// holds the primary key of the last row returned; a limit of zero or less
+// returns all the remaining rows.
+func (c *Connector) Search(_ context.Context, ei *dosa.EntityInfo, fieldPair dosa.FieldNameValuePair, minimumFields []string, token string, limit int) ([]map[string]dosa.FieldValue, string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	s, err := c.scopeFor(ei)
+	if err != nil {
+		return nil, "", err
+	}
+	cd := ei.Def.FindColumnDefinition(fieldPair.Name)
+	if cd == nil {
+		return nil, "", errors.Errorf("column %q not found in entity %q", fieldPair.Name, ei.Def.Name)
+	}
+	if !isSearchable(cd) {
+		return nil, "", errors.Errorf("column %q of entity %q is not searchable", cd.Name, ei.Def.Name)
+	}
+	var after map[string]dosa.FieldValue
+	if token != "" {
+		if after, err = decodeSearchToken(ei, token); err != nil {
+			return nil, "", err
+		}
+	}
+
+	rows := s.indexes[tableKeyOf(ei)][cd.Name][encodeFieldValue(fieldPair.Value)]
+	found := byPrimaryKey{ei: ei}
+	for _, row := range rows {
+		if after == nil || comparePrimaryKeys(ei, row, after) > 0 {
+			found.rows = append(found.rows, row)
+		}
+	}
+	if len(found.rows) == 0 {
+		return nil, "", &dosa.ErrNotFound{}
+	}
+	sort.Sort(found)
+	nextToken := ""
+	if limit > 0 && len(found.rows) > limit {
+		found.rows = found.rows[:limit]
+		if nextToken, err = encodeSearchToken(ei, found.rows[limit-1]); err != nil {
+			return nil, "", err
+		}
+	}
+	return projectAll(found.rows, minimumFields), nextToken, nil
+}
+
+// comparePrimaryKeys orders rows by partition key, then by clustering key
+func comparePrimaryKeys(ei *dosa.EntityInfo, v1, v2 map[string]dosa.FieldValue) int8 {
+	for _, pk := range ei.Def.Key.PartitionKeys {
+		if cmp := compareType(v1[pk], v2[pk]); cmp != 0 {
+			return cmp
+		}
+	}
+	return compareRows(ei, v1, v2)
+}
+
+// byPrimaryKey sorts the rows of an entity by primary key
+type byPrimaryKey struct {
+	ei   *dosa.EntityInfo
+	rows []map[string]dosa.FieldValue
+}
+
+func (b byPrimaryKey) Len() int      { return len(b.rows) }
+func (b byPrimaryKey) Swap(i, j int) { b.rows[i], b.rows[j] = b.rows[j], b.rows[i] }
+func (b byPrimaryKey) Less(i, j int) bool {
+	return comparePrimaryKeys(b.ei, b.rows[i], b.rows[j]) < 0
+}
+
+// encodeSearchToken encodes the primary key of a row, in the format of snapshots
+func encodeSearchToken(ei *dosa.EntityInfo, values map[string]dosa.FieldValue) (string, error) {
+	key := make(map[string]dosa.FieldValue)
+	for name := range ei.Def.KeySet() {
+		key[name] = values[name]
+	}
+	row, err := snapshotRow(key)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot encode token")
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot encode token")
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decodeSearchToken decodes the primary key encoded by encodeSearchToken
+func decodeSearchToken(ei *dosa.EntityInfo, token string) (map[string]dosa.FieldValue, error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token")
+	}
+	var row map[string]*snapshotValue
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, errors.Wrap(err, "invalid token")
+	}
+	key, err := restoreRow(row)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token")
+	}
+	for name := range ei.Def.KeySet() {
+		if key[name] == nil {
+			return nil, errors.Errorf("invalid token: missing key column %q", name)
+		}
+	}
+	return key, nil
+}
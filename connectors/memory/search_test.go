@@ -0,0 +1,194 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+var searchableEi = &dosa.EntityInfo{
+	Ref: &testSchemaRef,
+	Def: &dosa.EntityDefinition{
+		Name: "searchable",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"f1"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "c1"}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "f1", Type: dosa.String},
+			{Name: "c1", Type: dosa.Int64},
+			{Name: "email", Type: dosa.String, Tags: map[string]string{searchableTag: ""}},
+			{Name: "name", Type: dosa.String},
+		},
+	},
+}
+
+func searchEmail(sut *Connector, email string, minimumFields []string, token string, limit int) ([]map[string]dosa.FieldValue, string, error) {
+	return sut.Search(context.TODO(), searchableEi, dosa.FieldNameValuePair{Name: "email", Value: email}, minimumFields, token, limit)
+}
+
+func TestConnector_Search(t *testing.T) {
+	sut := newScopedConnector()
+	for x := 0; x < 5; x++ {
+		assert.NoError(t, sut.Upsert(context.TODO(), searchableEi, map[string]dosa.FieldValue{
+			"f1": "p", "c1": int64(x), "email": "shared@example.com", "name": "n"}))
+	}
+	assert.NoError(t, sut.Upsert(context.TODO(), searchableEi, map[string]dosa.FieldValue{
+		"f1": "other", "c1": int64(0), "email": "other@example.com", "name": "o"}))
+
+	values, token, err := searchEmail(sut, "other@example.com", nil, "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"f1": "other", "c1": int64(0), "email": "other@example.com", "name": "o"}}, values)
+
+	// results are paged, and projected
+	var found []int64
+	for token = ""; ; {
+		values, token, err = searchEmail(sut, "shared@example.com", []string{"c1"}, token, 2)
+		assert.NoError(t, err)
+		for _, v := range values {
+			assert.Len(t, v, 1)
+			found = append(found, v["c1"].(int64))
+		}
+		if token == "" {
+			break
+		}
+	}
+	// rows are returned in primary key order
+	assert.Equal(t, []int64{0, 1, 2, 3, 4}, found)
+
+	// changing the searchable column moves the row to the new value
+	assert.NoError(t, sut.Upsert(context.TODO(), searchableEi, map[string]dosa.FieldValue{
+		"f1": "p", "c1": int64(0), "email": "other@example.com"}))
+	values, _, err = searchEmail(sut, "other@example.com", dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	values, _, err = searchEmail(sut, "shared@example.com", dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, values, 4)
+
+	// a failed create leaves the index alone
+	err = sut.CreateIfNotExists(context.TODO(), searchableEi, map[string]dosa.FieldValue{
+		"f1": "p", "c1": int64(1), "email": "new@example.com"})
+	assert.True(t, dosa.ErrorIsAlreadyExists(err))
+	_, _, err = searchEmail(sut, "new@example.com", dosa.All(), "", 0)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+
+	// removed rows are no longer found
+	assert.NoError(t, sut.Remove(context.TODO(), searchableEi, map[string]dosa.FieldValue{"f1": "other", "c1": int64(0)}))
+	assert.NoError(t, sut.RemoveRange(context.TODO(), searchableEi, map[string][]*dosa.Condition{
+		"f1": {{Op: dosa.Eq, Value: "p"}},
+		"c1": {{Op: dosa.GtOrEq, Value: int64(2)}},
+	}))
+	values, _, err = searchEmail(sut, "shared@example.com", dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"f1": "p", "c1": int64(1), "email": "shared@example.com", "name": "n"}}, values)
+	values, _, err = searchEmail(sut, "other@example.com", dosa.All(), "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+
+	// and neither are truncated ones
+	assert.NoError(t, sut.TruncateScope(context.TODO(), testSchemaRef.Scope))
+	_, _, err = searchEmail(sut, "shared@example.com", dosa.All(), "", 0)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+}
+
+func TestConnector_SearchOrder(t *testing.T) {
+	sut := newScopedConnector()
+	ei := &dosa.EntityInfo{
+		Ref: &testSchemaRef,
+		Def: &dosa.EntityDefinition{
+			Name: "descending",
+			Key: &dosa.PrimaryKey{
+				PartitionKeys:  []string{"f1"},
+				ClusteringKeys: []*dosa.ClusteringKey{{Name: "c1", Descending: true}},
+			},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "f1", Type: dosa.String},
+				{Name: "c1", Type: dosa.Int64},
+				{Name: "tag", Type: dosa.String, Tags: map[string]string{searchableTag: ""}},
+			},
+		},
+	}
+	_, err := sut.UpsertSchema(context.TODO(), testSchemaRef.Scope, testSchemaRef.NamePrefix, []*dosa.EntityDefinition{testEi.Def, ei.Def})
+	assert.NoError(t, err)
+	ref := testSchemaRef
+	ref.Version = 2
+	ei.Ref = &ref
+
+	// gob encodes integers as 0, -1, 1, -2, 2..., so the order of the encoded
+	// keys differs from the order of the values
+	for _, f1 := range []string{"b", "a"} {
+		for _, c1 := range []int64{-2, 1, -1, 2, 0} {
+			assert.NoError(t, sut.Upsert(context.TODO(), ei, map[string]dosa.FieldValue{"f1": f1, "c1": c1, "tag": "x"}))
+		}
+	}
+
+	var found []string
+	for token := ""; ; {
+		values, next, err := sut.Search(context.TODO(), ei, dosa.FieldNameValuePair{Name: "tag", Value: "x"}, dosa.All(), token, 3)
+		assert.NoError(t, err)
+		for _, v := range values {
+			found = append(found, fmt.Sprintf("%s%d", v["f1"], v["c1"]))
+		}
+		if token = next; token == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a2", "a1", "a0", "a-1", "a-2", "b2", "b1", "b0", "b-1", "b-2"}, found)
+}
+
+func TestConnector_SearchErrors(t *testing.T) {
+	sut := newScopedConnector()
+	_, _, err := sut.Search(context.TODO(), searchableEi, dosa.FieldNameValuePair{Name: "name", Value: "n"}, nil, "", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not searchable")
+	}
+	_, _, err = sut.Search(context.TODO(), searchableEi, dosa.FieldNameValuePair{Name: "missing", Value: "n"}, nil, "", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not found")
+	}
+	_, _, err = searchEmail(sut, "shared@example.com", nil, "not base64!", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid token")
+	}
+	_, _, err = searchEmail(NewConnector(), "shared@example.com", nil, "", 0)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+}
+
+func TestConnector_SearchRestored(t *testing.T) {
+	sut := newScopedConnector()
+	assert.NoError(t, sut.Upsert(context.TODO(), searchableEi, map[string]dosa.FieldValue{
+		"f1": "p", "c1": int64(1), "email": "shared@example.com"}))
+	snapshot := &bytes.Buffer{}
+	assert.NoError(t, sut.Snapshot(snapshot))
+	restored := NewConnector()
+	assert.NoError(t, restored.Restore(snapshot))
+	values, _, err := searchEmail(restored, "shared@example.com", []string{"c1"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]dosa.FieldValue{{"c1": int64(1)}}, values)
+}
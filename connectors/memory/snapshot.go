@@ -0,0 +1,342 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// A snapshot is a stream of JSON objects, one per line. Each scope starts
+// with a line naming it, followed by one line per schema version. For each
+// entity in the scope, a line holding its definition is followed by one line
+// per row, ordered by partition and clustering key. Scopes are ordered by
+// name, schema versions by name prefix and version, and entities by name
+// prefix and name, so snapshots of the same data are identical. For example:
+//
+//	{"scope":"s1"}
+//	{"scope":"s1","namePrefix":"p","version":1,"schema":[{"Name":"t1","Key":{...},"Columns":[...]}]}
+//	{"scope":"s1","namePrefix":"p","entity":"t1","definition":{"Name":"t1","Key":{...},"Columns":[...]}}
+//	{"scope":"s1","namePrefix":"p","entity":"t1","row":{"f1":{"type":"String","value":"data"},"c1":{"type":"Int64","value":"1"}}}
+type snapshotLine struct {
+	Scope      string                    `json:"scope"`
+	NamePrefix string                    `json:"namePrefix,omitempty"`
+	Version    int32                     `json:"version,omitempty"`
+	Schema     []*dosa.EntityDefinition  `json:"schema,omitempty"`
+	Entity     string                    `json:"entity,omitempty"`
+	Definition *dosa.EntityDefinition    `json:"definition,omitempty"`
+	Row        map[string]*snapshotValue `json:"row,omitempty"`
+}
+
+// snapshotValue is a field value along with its type. The value is always a
+// string, so that it can be restored exactly.
+type snapshotValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Snapshot writes all the scopes and rows stored in the connector to w
+func (c *Connector) Snapshot(w io.Writer) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	encoder := json.NewEncoder(w)
+	scopes := make([]string, 0, len(c.data))
+	for scope := range c.data {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		if err := encoder.Encode(&snapshotLine{Scope: scope}); err != nil {
+			return errors.Wrapf(err, "failed to write scope %q", scope)
+		}
+		if err := c.data[scope].snapshot(encoder, scope); err != nil {
+			return errors.Wrapf(err, "scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func (s *scopeData) snapshot(encoder *json.Encoder, scope string) error {
+	namePrefixes := make([]string, 0, len(s.schemas))
+	for namePrefix := range s.schemas {
+		namePrefixes = append(namePrefixes, namePrefix)
+	}
+	sort.Strings(namePrefixes)
+	for _, namePrefix := range namePrefixes {
+		for _, sv := range s.schemas[namePrefix] {
+			line := snapshotLine{Scope: scope, NamePrefix: namePrefix, Version: sv.Version, Schema: sv.Entities}
+			if err := encoder.Encode(&line); err != nil {
+				return errors.Wrapf(err, "failed to write version %d of schema %q", sv.Version, namePrefix)
+			}
+		}
+	}
+
+	keys := make([]tableKey, 0, len(s.tables))
+	for key := range s.tables {
+		keys = append(keys, key)
+	}
+	sort.Sort(byTableKey(keys))
+	for _, key := range keys {
+		def := s.defs[key]
+		if def == nil {
+			return errors.Errorf("no definition for entity %q", key.entityName)
+		}
+		line := snapshotLine{Scope: scope, NamePrefix: key.namePrefix, Entity: key.entityName, Definition: def}
+		if err := encoder.Encode(&line); err != nil {
+			return errors.Wrapf(err, "failed to write definition of entity %q", key.entityName)
+		}
+		line.Definition = nil
+		entityRef := s.tables[key]
+		partitionKeys := make([]string, 0, len(entityRef))
+		for partitionKey := range entityRef {
+			partitionKeys = append(partitionKeys, partitionKey)
+		}
+		sort.Strings(partitionKeys)
+		for _, partitionKey := range partitionKeys {
+			for _, values := range entityRef[partitionKey] {
+				row, err := snapshotRow(values)
+				if err != nil {
+					return errors.Wrapf(err, "entity %q", key.entityName)
+				}
+				line.Row = row
+				if err := encoder.Encode(&line); err != nil {
+					return errors.Wrapf(err, "failed to write row of entity %q", key.entityName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type byTableKey []tableKey
+
+func (k byTableKey) Len() int      { return len(k) }
+func (k byTableKey) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byTableKey) Less(i, j int) bool {
+	if k[i].namePrefix != k[j].namePrefix {
+		return k[i].namePrefix < k[j].namePrefix
+	}
+	return k[i].entityName < k[j].entityName
+}
+
+// Restore replaces all the data stored in the connector with the snapshot read from r
+func (c *Connector) Restore(r io.Reader) error {
+	restored := NewConnector()
+	scanner := bufio.NewScanner(r)
+	// rows with large blobs can be much longer than the default limit
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line snapshotLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return errors.Wrapf(err, "invalid snapshot line %d", lineNum)
+		}
+		if line.Entity == "" && line.Version == 0 {
+			if _, ok := restored.data[line.Scope]; ok {
+				return errors.Errorf("duplicate scope %q on snapshot line %d", line.Scope, lineNum)
+			}
+			restored.data[line.Scope] = newScopeData()
+			continue
+		}
+		s := restored.data[line.Scope]
+		if s == nil {
+			return errors.Errorf("snapshot line %d before its scope %q", lineNum, line.Scope)
+		}
+		if line.Version != 0 {
+			if err := s.restoreSchema(&line); err != nil {
+				return errors.Wrapf(err, "invalid schema on snapshot line %d", lineNum)
+			}
+			continue
+		}
+		key := tableKey{namePrefix: line.NamePrefix, entityName: line.Entity}
+		if line.Definition != nil {
+			if err := line.Definition.EnsureValid(); err != nil {
+				return errors.Wrapf(err, "invalid definition on snapshot line %d", lineNum)
+			}
+			if line.Definition.Name != line.Entity {
+				return errors.Errorf("definition of entity %q named %q on snapshot line %d", line.Entity, line.Definition.Name, lineNum)
+			}
+			s.defs[key] = line.Definition
+			continue
+		}
+		def := s.defs[key]
+		if def == nil {
+			return errors.Errorf("row of entity %q before its definition on snapshot line %d", line.Entity, lineNum)
+		}
+		values, err := restoreRow(line.Row)
+		if err != nil {
+			return errors.Wrapf(err, "invalid row on snapshot line %d", lineNum)
+		}
+		ei := &dosa.EntityInfo{
+			Ref: &dosa.SchemaRef{Scope: line.Scope, NamePrefix: line.NamePrefix, EntityName: line.Entity},
+			Def: def,
+		}
+		err = s.insert(ei, values, func(into, from map[string]dosa.FieldValue) error {
+			return errors.Errorf("duplicate row of entity %q on snapshot line %d", line.Entity, lineNum)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read snapshot")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.data = restored.data
+	return nil
+}
+
+// restoreSchema adds the schema version of a snapshot line to the scope
+func (s *scopeData) restoreSchema(line *snapshotLine) error {
+	if expected := int32(len(s.schemas[line.NamePrefix]) + 1); line.Version != expected {
+		return errors.Errorf("version %d of schema %q out of order, expected version %d", line.Version, line.NamePrefix, expected)
+	}
+	for _, ed := range line.Schema {
+		if err := ed.EnsureValid(); err != nil {
+			return err
+		}
+	}
+	s.schemas[line.NamePrefix] = append(s.schemas[line.NamePrefix], &dosa.SchemaVersion{Version: line.Version, Entities: line.Schema})
+	return nil
+}
+
+// RestoreFile replaces all the data stored in the connector with the snapshot in a file
+func (c *Connector) RestoreFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open snapshot")
+	}
+	defer func() { _ = f.Close() }()
+	return c.Restore(f)
+}
+
+func snapshotRow(values map[string]dosa.FieldValue) (map[string]*snapshotValue, error) {
+	row := make(map[string]*snapshotValue, len(values))
+	for name, value := range values {
+		if value == nil {
+			continue
+		}
+		sv, err := encodeSnapshotValue(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q", name)
+		}
+		row[name] = sv
+	}
+	return row, nil
+}
+
+func restoreRow(row map[string]*snapshotValue) (map[string]dosa.FieldValue, error) {
+	values := make(map[string]dosa.FieldValue, len(row))
+	for name, sv := range row {
+		if sv == nil {
+			continue
+		}
+		value, err := decodeSnapshotValue(sv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q", name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+func encodeSnapshotValue(value dosa.FieldValue) (*snapshotValue, error) {
+	switch v := value.(type) {
+	case dosa.UUID:
+		return &snapshotValue{Type: dosa.TUUID.String(), Value: string(v)}, nil
+	case string:
+		return &snapshotValue{Type: dosa.String.String(), Value: v}, nil
+	case int32:
+		return &snapshotValue{Type: dosa.Int32.String(), Value: strconv.FormatInt(int64(v), 10)}, nil
+	case int64:
+		return &snapshotValue{Type: dosa.Int64.String(), Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return &snapshotValue{Type: dosa.Double.String(), Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case []byte:
+		return &snapshotValue{Type: dosa.Blob.String(), Value: base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return &snapshotValue{Type: dosa.Timestamp.String(), Value: v.Format(time.RFC3339Nano)}, nil
+	case bool:
+		return &snapshotValue{Type: dosa.Bool.String(), Value: strconv.FormatBool(v)}, nil
+	case dosa.CounterValue:
+		return &snapshotValue{Type: dosa.Counter.String(), Value: strconv.FormatInt(int64(v), 10)}, nil
+	case dosa.Decimal:
+		return &snapshotValue{Type: dosa.TDecimal.String(), Value: string(v)}, nil
+	case dosa.Date:
+		return &snapshotValue{Type: dosa.TDate.String(), Value: v.String()}, nil
+	}
+	if t := dosa.TypeOf(value); t.IsCollection() {
+		encoded, err := dosa.MarshalCollection(value)
+		if err != nil {
+			return nil, err
+		}
+		return &snapshotValue{Type: t.String(), Value: string(encoded)}, nil
+	}
+	return nil, errors.Errorf("unsupported type %T", value)
+}
+
+func decodeSnapshotValue(sv *snapshotValue) (dosa.FieldValue, error) {
+	t := dosa.FromString(sv.Type)
+	if t.IsCollection() {
+		return dosa.UnmarshalCollection(t, []byte(sv.Value))
+	}
+	switch t {
+	case dosa.TUUID:
+		return dosa.UUID(sv.Value), nil
+	case dosa.String:
+		return sv.Value, nil
+	case dosa.Int32:
+		v, err := strconv.ParseInt(sv.Value, 10, 32)
+		return int32(v), err
+	case dosa.Int64:
+		return strconv.ParseInt(sv.Value, 10, 64)
+	case dosa.Double:
+		return strconv.ParseFloat(sv.Value, 64)
+	case dosa.Blob:
+		return base64.StdEncoding.DecodeString(sv.Value)
+	case dosa.Timestamp:
+		return time.Parse(time.RFC3339Nano, sv.Value)
+	case dosa.Bool:
+		return strconv.ParseBool(sv.Value)
+	case dosa.Counter:
+		v, err := strconv.ParseInt(sv.Value, 10, 64)
+		return dosa.CounterValue(v), err
+	case dosa.TDecimal:
+		return dosa.ParseDecimal(sv.Value)
+	case dosa.TDate:
+		return dosa.ParseDate(sv.Value)
+	}
+	return nil, errors.Errorf("unsupported type %q", sv.Type)
+}
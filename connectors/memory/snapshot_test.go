@@ -0,0 +1,160 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func TestConnector_SnapshotRestore(t *testing.T) {
+	sut := newScopedConnector()
+	now := time.Unix(1500000000, 123456789).UTC()
+	for x := 0; x < 5; x++ {
+		assert.NoError(t, sut.Upsert(context.TODO(), clusteredEi, map[string]dosa.FieldValue{
+			"f1": "data", "c1": int64(x), "c2": math.MaxFloat64 / float64(x+1), "c3": "line\n\"quoted\"",
+			"c4": []byte{0, byte(x)}, "c5": x%2 == 0, "c6": int32(-x), "c7": dosa.NewUUID(),
+		}))
+	}
+	// entities are identified by name prefix and name, so use a distinct one
+	otherDef := *testEi.Def
+	otherDef.Name = "t2"
+	otherEi := &dosa.EntityInfo{Ref: testEi.Ref, Def: &otherDef}
+	assert.NoError(t, sut.Upsert(context.TODO(), otherEi, map[string]dosa.FieldValue{"f1": "other", "c1": int64(math.MinInt64)}))
+	tsEi := &dosa.EntityInfo{Ref: testEi.Ref, Def: &dosa.EntityDefinition{
+		Name:    "ts",
+		Key:     &dosa.PrimaryKey{PartitionKeys: []string{"t"}},
+		Columns: []*dosa.ColumnDefinition{{Name: "t", Type: dosa.Timestamp}},
+	}}
+	assert.NoError(t, sut.Upsert(context.TODO(), tsEi, map[string]dosa.FieldValue{"t": now}))
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, sut.Snapshot(&snapshot))
+	// one line for the scope and its schema, one definition line per entity, and one line per row
+	assert.Equal(t, 2+3+5+1+1, strings.Count(snapshot.String(), "\n"))
+
+	restored := NewConnector()
+	assert.NoError(t, restored.Restore(bytes.NewReader(snapshot.Bytes())))
+	for _, ei := range []*dosa.EntityInfo{clusteredEi, otherEi, tsEi} {
+		expected, _, err := sut.Scan(context.TODO(), ei, dosa.All(), "", 0)
+		assert.NoError(t, err)
+		actual, _, err := restored.Scan(context.TODO(), ei, dosa.All(), "", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// snapshots are stable
+	var again bytes.Buffer
+	assert.NoError(t, restored.Snapshot(&again))
+	assert.Equal(t, snapshot.String(), again.String())
+
+	// restoring replaces existing data, including scopes
+	assert.NoError(t, restored.Restore(strings.NewReader("")))
+	exists, err := restored.ScopeExists(context.TODO(), testSchemaRef.Scope)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestConnector_SnapshotScopes(t *testing.T) {
+	sut := newScopedConnector()
+	assert.NoError(t, sut.CreateScope(context.TODO(), "empty"))
+	other := *testEi.Ref
+	other.NamePrefix = "other"
+	otherEi := &dosa.EntityInfo{Ref: &other, Def: testEi.Def}
+	_, err := sut.UpsertSchema(context.TODO(), other.Scope, other.NamePrefix, []*dosa.EntityDefinition{testEi.Def})
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Upsert(context.TODO(), testEi, map[string]dosa.FieldValue{"f1": "data", "c1": int64(1)}))
+	assert.NoError(t, sut.Upsert(context.TODO(), otherEi, map[string]dosa.FieldValue{"f1": "data", "c1": int64(2)}))
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, sut.Snapshot(&snapshot))
+	restored := NewConnector()
+	assert.NoError(t, restored.Restore(&snapshot))
+
+	// empty scopes survive
+	exists, err := restored.ScopeExists(context.TODO(), "empty")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	// and so do schema versions and name prefixes
+	version, err := restored.CheckSchema(context.TODO(), other.Scope, other.NamePrefix, []*dosa.EntityDefinition{testEi.Def})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), version)
+	for ei, expected := range map[*dosa.EntityInfo]int64{testEi: 1, otherEi: 2} {
+		values, err := restored.Read(context.TODO(), ei, map[string]dosa.FieldValue{"f1": "data"}, dosa.All())
+		assert.NoError(t, err)
+		assert.Equal(t, expected, values["c1"])
+	}
+}
+
+func TestConnector_RestoreErrors(t *testing.T) {
+	def := `{"scope":"s"}` + "\n" + `{"scope":"s","entity":"t1","definition":{"Name":"t1","Key":{"PartitionKeys":["f1"]},"Columns":[{"Name":"f1","Type":2}]}}`
+	tcs := map[string]string{
+		"invalid snapshot line 1":      `not json`,
+		"before its scope":             `{"scope":"s","entity":"t1","row":{"f1":{"type":"String","value":"x"}}}`,
+		"before its definition":        `{"scope":"s"}` + "\n" + `{"scope":"s","entity":"t1","row":{"f1":{"type":"String","value":"x"}}}`,
+		"duplicate scope":              `{"scope":"s"}` + "\n" + `{"scope":"s"}`,
+		"out of order":                 `{"scope":"s"}` + "\n" + `{"scope":"s","version":2,"schema":[]}`,
+		"invalid schema":               `{"scope":"s"}` + "\n" + `{"scope":"s","version":1,"schema":[{"Name":"t1"}]}`,
+		"named \"t3\"":                 def + "\n" + `{"scope":"s","entity":"t2","definition":{"Name":"t3","Key":{"PartitionKeys":["f1"]},"Columns":[{"Name":"f1","Type":2}]}}`,
+		"invalid definition":           `{"scope":"s"}` + "\n" + `{"scope":"s","entity":"t1","definition":{"Name":"t1","Key":{"PartitionKeys":["nope"]},"Columns":[{"Name":"f1","Type":2}]}}`,
+		`unsupported type "Float"`:     def + "\n" + `{"scope":"s","entity":"t1","row":{"f1":{"type":"Float","value":"1"}}}`,
+		"invalid row on snapshot line": def + "\n" + `{"scope":"s","entity":"t1","row":{"f1":{"type":"Int32","value":"99999999999"}}}`,
+		"duplicate row":                def + "\n" + `{"scope":"s","entity":"t1","row":{"f1":{"type":"String","value":"x"}}}` + "\n" + `{"scope":"s","entity":"t1","row":{"f1":{"type":"String","value":"x"}}}`,
+	}
+	for expected, snapshot := range tcs {
+		sut := NewConnector()
+		err := sut.Restore(strings.NewReader(snapshot))
+		if assert.Error(t, err, expected) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+}
+
+func TestConnector_SnapshotCreationArg(t *testing.T) {
+	f, err := ioutil.TempFile("", "dosa-snapshot")
+	assert.NoError(t, err)
+	defer func() { _ = os.Remove(f.Name()) }()
+	_, err = f.WriteString(`{"scope":"scope1"}
+{"scope":"scope1","namePrefix":"namePrefix","version":1,"schema":[{"Name":"t1","Key":{"PartitionKeys":["f1"]},"Columns":[{"Name":"f1","Type":2},{"Name":"c1","Type":4}]}]}
+{"scope":"scope1","namePrefix":"namePrefix","entity":"t1","definition":{"Name":"t1","Key":{"PartitionKeys":["f1"]},"Columns":[{"Name":"f1","Type":2},{"Name":"c1","Type":4}]}}
+{"scope":"scope1","namePrefix":"namePrefix","entity":"t1","row":{"c1":{"type":"Int64","value":"42"},"f1":{"type":"String","value":"seeded"}}}
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	conn, err := dosa.GetConnector("memory", dosa.CreationArgs{"snapshot": f.Name()})
+	assert.NoError(t, err)
+	values, err := conn.Read(context.TODO(), testEi, map[string]dosa.FieldValue{"f1": "seeded"}, dosa.All())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), values["c1"])
+
+	_, err = dosa.GetConnector("memory", dosa.CreationArgs{"snapshot": f.Name() + ".missing"})
+	assert.Error(t, err)
+}
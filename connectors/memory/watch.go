@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"context"
+
+	"github.com/uber-go/dosa"
+)
+
+// Watch streams the changes made to the rows of an entity, until ctx is done.
+// Rows removed by truncating or dropping their scope are not reported.
+func (c *Connector) Watch(ctx context.Context, ei *dosa.EntityInfo, opts *dosa.WatchOptions) (<-chan *dosa.RowChange, error) {
+	c.lock.RLock()
+	_, err := c.scopeFor(ei)
+	c.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return c.feed.Watch(ctx, ei, opts)
+}
+
+// publishRemoved reports the removal of rows to the watchers of their entity.
+// The write lock must be held, and the rows not removed yet.
+func (c *Connector) publishRemoved(ei *dosa.EntityInfo, rows []map[string]dosa.FieldValue) {
+	if !c.feed.Watching(ei) {
+		return
+	}
+	for _, row := range rows {
+		c.feed.Publish(ei, &dosa.RowChange{Type: dosa.Delete, Before: project(row, nil)})
+	}
+}
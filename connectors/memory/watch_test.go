@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func TestConnector_Watch(t *testing.T) {
+	sut := newScopedConnector()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := sut.Watch(ctx, rangedEi, nil)
+	assert.NoError(t, err)
+
+	row := func(a, b int64, c []byte) map[string]dosa.FieldValue {
+		return map[string]dosa.FieldValue{"f1": "data", "a": a, "b": b, "c": c}
+	}
+	assert.NoError(t, sut.Upsert(ctx, rangedEi, row(1, 1, []byte{1})))
+	assert.NoError(t, sut.Upsert(ctx, rangedEi, row(1, 1, []byte{2})))
+	// failed writes are not reported
+	assert.Error(t, sut.CreateIfNotExists(ctx, rangedEi, row(1, 1, nil)))
+	assert.NoError(t, sut.CreateIfNotExists(ctx, rangedEi, row(1, 2, nil)))
+	assert.NoError(t, sut.CreateIfNotExists(ctx, rangedEi, row(2, 1, nil)))
+	// neither are the writes to other entities
+	assert.NoError(t, sut.Upsert(ctx, testEi, map[string]dosa.FieldValue{"f1": "data"}))
+	assert.NoError(t, sut.Remove(ctx, rangedEi, row(1, 1, nil)))
+	// nor the removal of missing rows
+	assert.NoError(t, sut.Remove(ctx, rangedEi, row(3, 1, nil)))
+	assert.NoError(t, sut.RemoveRange(ctx, rangedEi, map[string][]*dosa.Condition{
+		"f1": {{Op: dosa.Eq, Value: "data"}},
+		"a":  {{Op: dosa.GtOrEq, Value: int64(1)}},
+	}))
+
+	expected := []*dosa.RowChange{
+		{Type: dosa.Insert, After: row(1, 1, []byte{1})},
+		{Type: dosa.Update, Before: row(1, 1, []byte{1}), After: row(1, 1, []byte{2})},
+		{Type: dosa.Insert, After: row(1, 2, nil)},
+		{Type: dosa.Insert, After: row(2, 1, nil)},
+		{Type: dosa.Delete, Before: row(1, 1, []byte{2})},
+		// b is descending
+		{Type: dosa.Delete, Before: row(1, 2, nil)},
+		{Type: dosa.Delete, Before: row(2, 1, nil)},
+	}
+	for _, change := range expected {
+		assert.Equal(t, change, <-changes)
+	}
+	assert.Len(t, changes, 0)
+}
+
+func TestConnector_WatchErrors(t *testing.T) {
+	sut := NewConnector()
+	_, err := sut.Watch(context.TODO(), testEi, nil)
+	assert.True(t, dosa.ErrorIsNotFound(err))
+}
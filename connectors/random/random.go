@@ -23,6 +23,7 @@ package random
 import (
 	"context"
 
+	"math/big"
 	"math/rand"
 	"time"
 
@@ -87,9 +88,18 @@ func Data(ei *dosa.EntityInfo, minimumFields []string) map[string]dosa.FieldValu
 			v = dosa.FieldValue(time.Unix(0, rand.Int63()/2))
 		case dosa.TUUID:
 			v = dosa.FieldValue(uuid.NewV4())
+		case dosa.Counter:
+			v = dosa.FieldValue(dosa.CounterValue(rand.Int63()))
+		case dosa.TDecimal:
+			v = dosa.FieldValue(dosa.NewDecimal(big.NewRat(rand.Int63(), rand.Int63n(1000)+1), 3))
+		case dosa.TDate:
+			v = dosa.FieldValue(dosa.DateFromDays(rand.Int31n(1 << 16)))
 		default:
-			panic("invalid type " + cd.Type.String())
-
+			if !cd.Type.IsCollection() {
+				panic("invalid type " + cd.Type.String())
+			}
+			// lists, sets and maps are empty
+			v, _ = dosa.UnmarshalCollection(cd.Type, []byte("[]"))
 		}
 		result[field] = v
 	}
@@ -184,6 +194,16 @@ func (c *Connector) CheckSchemaStatus(ctx context.Context, scope, namePrefix str
 	}, nil
 }
 
+// GetSchema always returns an empty schema
+func (c *Connector) GetSchema(ctx context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	return []*dosa.EntityDefinition{}, nil
+}
+
+// ListSchemaVersions always returns version 1 with ACCEPTED status, matching CheckSchemaStatus
+func (c *Connector) ListSchemaVersions(ctx context.Context, scope, namePrefix string) ([]*dosa.SchemaStatus, error) {
+	return []*dosa.SchemaStatus{{Version: int32(1), Status: "ACCEPTED"}}, nil
+}
+
 // CreateScope returns success
 func (c *Connector) CreateScope(ctx context.Context, scope string) error {
 	return nil
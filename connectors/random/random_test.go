@@ -43,6 +43,9 @@ type AllTypes struct {
 	BlobType    []byte
 	TimeType    time.Time
 	UUIDType    dosa.UUID
+	ListType    []string
+	DecimalType dosa.Decimal
+	DateType    dosa.Date
 }
 
 var (
@@ -59,7 +62,7 @@ var (
 	testPairs       = dosa.FieldNameValuePair{}
 	testValues      = make(map[string]dosa.FieldValue)
 	testMultiValues = make([]map[string]dosa.FieldValue, 50)
-	minimumFields   = []string{"booltype", "int32type", "int64type", "doubletype", "stringtype", "blobtype", "timetype", "uuidtype"}
+	minimumFields   = []string{"booltype", "int32type", "int64type", "doubletype", "stringtype", "blobtype", "timetype", "uuidtype", "listtype", "decimaltype", "datetype"}
 	ctx             = context.Background()
 )
 
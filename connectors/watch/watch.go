@@ -0,0 +1,250 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package watch provides a connector that wraps any other connector, and
+// lets clients watch the changes made through it within the same process.
+//
+// Changes made by other processes, or directly through the wrapped
+// connector, are not seen. Whether an upsert inserted or updated a row, and
+// the values a row held before being changed, are found by reading the row
+// first, which is only done while someone is watching. Concurrent writes to
+// the same row can make these reads stale.
+package watch
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/base"
+)
+
+// rangePageSize is the number of rows read per call when finding the rows
+// removed by RemoveRange
+const rangePageSize = 128
+
+// rangeRemover is implemented by the connectors that support RemoveRange
+type rangeRemover interface {
+	RemoveRange(ctx context.Context, ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) error
+}
+
+// Connector reports the writes made through it to its watchers
+type Connector struct {
+	base.Connector
+	feed dosa.ChangeFeed
+}
+
+// NewConnector wraps a connector, so that the changes made through it can be watched
+func NewConnector(next dosa.Connector) *Connector {
+	return &Connector{Connector: base.Connector{Next: next}}
+}
+
+// Watch streams the changes made through this connector to the rows of an entity,
+// until ctx is done
+func (c *Connector) Watch(ctx context.Context, ei *dosa.EntityInfo, opts *dosa.WatchOptions) (<-chan *dosa.RowChange, error) {
+	return c.feed.Watch(ctx, ei, opts)
+}
+
+// CreateIfNotExists calls Next, and reports the new row
+func (c *Connector) CreateIfNotExists(ctx context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue) error {
+	if err := c.Connector.CreateIfNotExists(ctx, ei, values); err != nil {
+		return err
+	}
+	if c.feed.Watching(ei) {
+		c.feed.Publish(ei, &dosa.RowChange{Type: dosa.Insert, After: merge(nil, values)})
+	}
+	return nil
+}
+
+// Upsert calls Next, and reports whether the row was inserted or updated
+func (c *Connector) Upsert(ctx context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue) error {
+	if !c.feed.Watching(ei) {
+		return c.Connector.Upsert(ctx, ei, values)
+	}
+	before, found := c.before(ctx, ei, values)
+	if err := c.Connector.Upsert(ctx, ei, values); err != nil {
+		return err
+	}
+	c.feed.Publish(ei, upserted(before, found, values))
+	return nil
+}
+
+// MultiUpsert calls Next, and reports the rows that were written
+func (c *Connector) MultiUpsert(ctx context.Context, ei *dosa.EntityInfo, multiValues []map[string]dosa.FieldValue) ([]error, error) {
+	if !c.feed.Watching(ei) {
+		return c.Connector.MultiUpsert(ctx, ei, multiValues)
+	}
+	befores := make([]map[string]dosa.FieldValue, len(multiValues))
+	founds := make([]bool, len(multiValues))
+	for i, values := range multiValues {
+		befores[i], founds[i] = c.before(ctx, ei, values)
+	}
+	result, err := c.Connector.MultiUpsert(ctx, ei, multiValues)
+	if err != nil {
+		return result, err
+	}
+	for i, values := range multiValues {
+		if i < len(result) && result[i] != nil {
+			continue
+		}
+		c.feed.Publish(ei, upserted(befores[i], founds[i], values))
+	}
+	return result, nil
+}
+
+// Increment calls Next, if Next can increment counters, and reports the new value of the counter
+func (c *Connector) Increment(ctx context.Context, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue, column string, delta int64) (dosa.CounterValue, error) {
+	if c.Next == nil {
+		return 0, base.ErrNoMoreConnector{}
+	}
+	next, ok := c.Next.(dosa.Incrementer)
+	if !ok {
+		return 0, errors.Errorf("Increment: connector %T cannot increment counters", c.Next)
+	}
+	if !c.feed.Watching(ei) {
+		return next.Increment(ctx, ei, keys, column, delta)
+	}
+	before, found := c.before(ctx, ei, keys)
+	value, err := next.Increment(ctx, ei, keys, column, delta)
+	if err != nil {
+		return 0, err
+	}
+	values := map[string]dosa.FieldValue{column: value}
+	for name := range ei.Def.KeySet() {
+		values[name] = keys[name]
+	}
+	c.feed.Publish(ei, upserted(before, found, values))
+	return value, nil
+}
+
+// Remove calls Next, and reports the row if it existed
+func (c *Connector) Remove(ctx context.Context, ei *dosa.EntityInfo, keys map[string]dosa.FieldValue) error {
+	if !c.feed.Watching(ei) {
+		return c.Connector.Remove(ctx, ei, keys)
+	}
+	before, found := c.before(ctx, ei, keys)
+	if err := c.Connector.Remove(ctx, ei, keys); err != nil {
+		return err
+	}
+	if found {
+		c.feed.Publish(ei, &dosa.RowChange{Type: dosa.Delete, Before: before})
+	}
+	return nil
+}
+
+// MultiRemove calls Next, and reports the rows that existed
+func (c *Connector) MultiRemove(ctx context.Context, ei *dosa.EntityInfo, multiKeys []map[string]dosa.FieldValue) ([]error, error) {
+	if !c.feed.Watching(ei) {
+		return c.Connector.MultiRemove(ctx, ei, multiKeys)
+	}
+	befores := make([]map[string]dosa.FieldValue, len(multiKeys))
+	founds := make([]bool, len(multiKeys))
+	for i, keys := range multiKeys {
+		befores[i], founds[i] = c.before(ctx, ei, keys)
+	}
+	result, err := c.Connector.MultiRemove(ctx, ei, multiKeys)
+	if err != nil {
+		return result, err
+	}
+	for i := range multiKeys {
+		if (i < len(result) && result[i] != nil) || !founds[i] {
+			continue
+		}
+		c.feed.Publish(ei, &dosa.RowChange{Type: dosa.Delete, Before: befores[i]})
+	}
+	return result, nil
+}
+
+// RemoveRange calls Next, and reports every row in the range
+func (c *Connector) RemoveRange(ctx context.Context, ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) error {
+	if !c.feed.Watching(ei) {
+		return c.removeRange(ctx, ei, columnConditions)
+	}
+	var removed []map[string]dosa.FieldValue
+	token := ""
+	for {
+		rows, next, err := c.Connector.Range(ctx, ei, columnConditions, dosa.All(), token, rangePageSize)
+		if err != nil && !dosa.ErrorIsNotFound(err) {
+			return err
+		}
+		removed = append(removed, rows...)
+		if next == "" || err != nil {
+			break
+		}
+		token = next
+	}
+	if err := c.removeRange(ctx, ei, columnConditions); err != nil {
+		return err
+	}
+	for _, row := range removed {
+		c.feed.Publish(ei, &dosa.RowChange{Type: dosa.Delete, Before: merge(nil, row)})
+	}
+	return nil
+}
+
+// removeRange calls RemoveRange on Next, if Next supports it
+func (c *Connector) removeRange(ctx context.Context, ei *dosa.EntityInfo, columnConditions map[string][]*dosa.Condition) error {
+	if c.Next == nil {
+		return base.ErrNoMoreConnector{}
+	}
+	next, ok := c.Next.(rangeRemover)
+	if !ok {
+		return errors.Errorf("RemoveRange: connector %T cannot remove ranges", c.Next)
+	}
+	return next.RemoveRange(ctx, ei, columnConditions)
+}
+
+// before reads the current values of a row. If the row cannot be read, it
+// returns only its primary key, and reports it as found.
+func (c *Connector) before(ctx context.Context, ei *dosa.EntityInfo, values map[string]dosa.FieldValue) (map[string]dosa.FieldValue, bool) {
+	keys := make(map[string]dosa.FieldValue)
+	for name := range ei.Def.KeySet() {
+		keys[name] = values[name]
+	}
+	current, err := c.Connector.Read(ctx, ei, keys, dosa.All())
+	if dosa.ErrorIsNotFound(err) {
+		return nil, false
+	}
+	if err != nil {
+		return keys, true
+	}
+	// key columns may not be returned by reads
+	return merge(keys, current), true
+}
+
+// upserted builds the change made by upserting values over a row
+func upserted(before map[string]dosa.FieldValue, found bool, values map[string]dosa.FieldValue) *dosa.RowChange {
+	if !found {
+		return &dosa.RowChange{Type: dosa.Insert, After: merge(nil, values)}
+	}
+	return &dosa.RowChange{Type: dosa.Update, Before: before, After: merge(before, values)}
+}
+
+// merge returns a new row, holding the values of from over the ones of into
+func merge(into, from map[string]dosa.FieldValue) map[string]dosa.FieldValue {
+	merged := make(map[string]dosa.FieldValue, len(into)+len(from))
+	for name, value := range into {
+		merged[name] = value
+	}
+	for name, value := range from {
+		merged[name] = value
+	}
+	return merged
+}
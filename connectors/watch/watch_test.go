@@ -0,0 +1,202 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/memory"
+	"github.com/uber-go/dosa/connectors/watch"
+	"github.com/uber-go/dosa/mocks"
+)
+
+var testEi = &dosa.EntityInfo{
+	Ref: &dosa.SchemaRef{
+		Scope:      "testScope",
+		NamePrefix: "testPrefix",
+		EntityName: "test_entity",
+		Version:    1,
+	},
+	Def: &dosa.EntityDefinition{
+		Name: "test_entity",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"p"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "c"}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "p", Type: dosa.String},
+			{Name: "c", Type: dosa.Int64},
+			{Name: "v", Type: dosa.String},
+		},
+	},
+}
+
+func row(c int64, v string) map[string]dosa.FieldValue {
+	return map[string]dosa.FieldValue{"p": "data", "c": c, "v": v}
+}
+
+func newMemoryConnector() *watch.Connector {
+	next := memory.NewConnector()
+	_ = next.CreateScope(context.TODO(), testEi.Ref.Scope)
+	_, _ = next.UpsertSchema(context.TODO(), testEi.Ref.Scope, testEi.Ref.NamePrefix, []*dosa.EntityDefinition{testEi.Def})
+	return watch.NewConnector(next)
+}
+
+func TestConnector_Watch(t *testing.T) {
+	sut := newMemoryConnector()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := sut.Watch(ctx, testEi, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, sut.CreateIfNotExists(ctx, testEi, row(1, "a")))
+	// failed writes are not reported
+	assert.Error(t, sut.CreateIfNotExists(ctx, testEi, row(1, "a")))
+	assert.NoError(t, sut.Upsert(ctx, testEi, map[string]dosa.FieldValue{"p": "data", "c": int64(1), "v": "b"}))
+	assert.NoError(t, sut.Upsert(ctx, testEi, row(2, "c")))
+	assert.NoError(t, sut.Upsert(ctx, testEi, row(3, "d")))
+	assert.NoError(t, sut.Remove(ctx, testEi, map[string]dosa.FieldValue{"p": "data", "c": int64(2)}))
+	// nor the removal of missing rows
+	assert.NoError(t, sut.Remove(ctx, testEi, map[string]dosa.FieldValue{"p": "data", "c": int64(2)}))
+	assert.NoError(t, sut.RemoveRange(ctx, testEi, map[string][]*dosa.Condition{
+		"p": {{Op: dosa.Eq, Value: "data"}},
+	}))
+
+	expected := []*dosa.RowChange{
+		{Type: dosa.Insert, After: row(1, "a")},
+		{Type: dosa.Update, Before: row(1, "a"), After: row(1, "b")},
+		{Type: dosa.Insert, After: row(2, "c")},
+		{Type: dosa.Insert, After: row(3, "d")},
+		{Type: dosa.Delete, Before: row(2, "c")},
+		{Type: dosa.Delete, Before: row(1, "b")},
+		{Type: dosa.Delete, Before: row(3, "d")},
+	}
+	for _, change := range expected {
+		assert.Equal(t, change, <-changes)
+	}
+	assert.Len(t, changes, 0)
+}
+
+func TestConnector_NotWatching(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	next := mocks.NewMockConnector(ctrl)
+	sut := watch.NewConnector(next)
+
+	// nothing is read before writing
+	next.EXPECT().Upsert(gomock.Any(), testEi, row(1, "a")).Return(nil)
+	next.EXPECT().MultiUpsert(gomock.Any(), testEi, gomock.Any()).Return(nil, nil)
+	next.EXPECT().Remove(gomock.Any(), testEi, row(1, "a")).Return(nil)
+	next.EXPECT().MultiRemove(gomock.Any(), testEi, gomock.Any()).Return(nil, nil)
+	assert.NoError(t, sut.Upsert(context.TODO(), testEi, row(1, "a")))
+	_, err := sut.MultiUpsert(context.TODO(), testEi, []map[string]dosa.FieldValue{row(1, "a")})
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Remove(context.TODO(), testEi, row(1, "a")))
+	_, err = sut.MultiRemove(context.TODO(), testEi, []map[string]dosa.FieldValue{row(1, "a")})
+	assert.NoError(t, err)
+
+	// the mock cannot remove ranges
+	err = sut.RemoveRange(context.TODO(), testEi, nil)
+	assert.Contains(t, err.Error(), "cannot remove ranges")
+}
+
+func TestConnector_Multi(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	next := mocks.NewMockConnector(ctrl)
+	sut := watch.NewConnector(next)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := sut.Watch(ctx, testEi, nil)
+	assert.NoError(t, err)
+
+	key := func(c int64) map[string]dosa.FieldValue {
+		return map[string]dosa.FieldValue{"p": "data", "c": c}
+	}
+	next.EXPECT().Read(gomock.Any(), testEi, key(1), gomock.Any()).Return(map[string]dosa.FieldValue{"v": "a"}, nil).Times(2)
+	next.EXPECT().Read(gomock.Any(), testEi, key(2), gomock.Any()).Return(nil, &dosa.ErrNotFound{}).Times(2)
+	next.EXPECT().Read(gomock.Any(), testEi, key(3), gomock.Any()).Return(map[string]dosa.FieldValue{"v": "c"}, nil).Times(2)
+
+	multiValues := []map[string]dosa.FieldValue{row(1, "b"), row(2, "b"), row(3, "b")}
+	next.EXPECT().MultiUpsert(gomock.Any(), testEi, multiValues).Return([]error{nil, nil, errors.New("failed")}, nil)
+	result, err := sut.MultiUpsert(ctx, testEi, multiValues)
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+
+	multiKeys := []map[string]dosa.FieldValue{key(1), key(2), key(3)}
+	next.EXPECT().MultiRemove(gomock.Any(), testEi, multiKeys).Return([]error{nil, nil, errors.New("failed")}, nil)
+	result, err = sut.MultiRemove(ctx, testEi, multiKeys)
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+
+	expected := []*dosa.RowChange{
+		{Type: dosa.Update, Before: row(1, "a"), After: row(1, "b")},
+		{Type: dosa.Insert, After: row(2, "b")},
+		{Type: dosa.Delete, Before: row(1, "a")},
+	}
+	for _, change := range expected {
+		assert.Equal(t, change, <-changes)
+	}
+	assert.Len(t, changes, 0)
+}
+
+func TestConnector_Increment(t *testing.T) {
+	counterEi := &dosa.EntityInfo{
+		Ref: testEi.Ref,
+		Def: &dosa.EntityDefinition{
+			Name: "counters",
+			Key:  &dosa.PrimaryKey{PartitionKeys: []string{"p"}},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "p", Type: dosa.String},
+				{Name: "hits", Type: dosa.Counter},
+			},
+		},
+	}
+	sut := newMemoryConnector()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keys := map[string]dosa.FieldValue{"p": "data"}
+	value, err := sut.Increment(ctx, counterEi, keys, "hits", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.CounterValue(1), value)
+
+	changes, err := sut.Watch(ctx, counterEi, nil)
+	assert.NoError(t, err)
+	value, err = sut.Increment(ctx, counterEi, keys, "hits", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, dosa.CounterValue(3), value)
+	assert.Equal(t, &dosa.RowChange{
+		Type:   dosa.Update,
+		Before: map[string]dosa.FieldValue{"p": "data", "hits": dosa.CounterValue(1)},
+		After:  map[string]dosa.FieldValue{"p": "data", "hits": dosa.CounterValue(3)},
+	}, <-changes)
+
+	// the mock cannot increment counters
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	_, err = watch.NewConnector(mocks.NewMockConnector(ctrl)).Increment(ctx, counterEi, keys, "hits", 1)
+	assert.Contains(t, err.Error(), "cannot increment counters")
+}
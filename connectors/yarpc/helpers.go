@@ -21,6 +21,7 @@
 package yarpc
 
 import (
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -31,6 +32,10 @@ import (
 // RawValueAsInterface converts a value from the wire to an object implementing the interface
 // based on the dosa type. For example, a TUUID type will get a dosa.UUID object
 func RawValueAsInterface(val dosarpc.RawValue, typ dosa.Type) interface{} {
+	if typ.IsCollection() {
+		collection, _ := dosa.UnmarshalCollection(typ, val.BinaryValue) // TODO: should we handle this error?
+		return collection
+	}
 	switch typ {
 	case dosa.TUUID:
 		uuid, _ := dosa.BytesToUUID(val.BinaryValue) // TODO: should we handle this error?
@@ -49,6 +54,12 @@ func RawValueAsInterface(val dosarpc.RawValue, typ dosa.Type) interface{} {
 		return time.Unix(0, *val.Int64Value)
 	case dosa.Bool:
 		return *val.BoolValue
+	case dosa.TDecimal:
+		return dosa.Decimal(*val.StringValue)
+	case dosa.TDate:
+		return dosa.DateFromDays(*val.Int32Value)
+	case dosa.Counter:
+		return dosa.CounterValue(*val.Int64Value)
 	}
 	panic("bad type")
 }
@@ -60,6 +71,9 @@ func RawValueFromInterface(i interface{}) (*dosarpc.RawValue, error) {
 	// TODO: Do we do type compatibility checks here? We should know the schema,
 	// but the callers are all well known and should match the types
 	switch v := i.(type) {
+	case nil:
+		// the RawValue union has no member for nulls
+		return nil, errors.New("null values are not supported")
 	case string:
 		return &dosarpc.RawValue{StringValue: &v}, nil
 	case bool:
@@ -86,13 +100,45 @@ func RawValueFromInterface(i interface{}) (*dosarpc.RawValue, error) {
 			return nil, err
 		}
 		return &dosarpc.RawValue{BinaryValue: bytes}, nil
+	case dosa.Decimal:
+		if _, err := v.Rat(); err != nil {
+			return nil, err
+		}
+		s := string(v)
+		return &dosarpc.RawValue{StringValue: &s}, nil
+	case dosa.Date:
+		days := v.Days()
+		return &dosarpc.RawValue{Int32Value: &days}, nil
+	case dosa.CounterValue:
+		count := int64(v)
+		return &dosarpc.RawValue{Int64Value: &count}, nil
+	}
+	// lists, sets and maps are sent as blobs
+	if dosa.TypeOf(i).IsCollection() {
+		bytes, err := dosa.MarshalCollection(i)
+		if err != nil {
+			return nil, err
+		}
+		return &dosarpc.RawValue{BinaryValue: bytes}, nil
 	}
 	panic("bad type")
 }
 
 // RPCTypeFromClientType returns the RPC ElemType from a DOSA Type
 func RPCTypeFromClientType(t dosa.Type) dosarpc.ElemType {
+	// the gateway stores lists, sets and maps as blobs, encoded by dosa.MarshalCollection,
+	// decimals as strings, dates as the number of days since 1970-01-01, and counters
+	// as int64s
+	if t.IsCollection() {
+		return dosarpc.ElemTypeBlob
+	}
 	switch t {
+	case dosa.Counter:
+		return dosarpc.ElemTypeInt64
+	case dosa.TDecimal:
+		return dosarpc.ElemTypeString
+	case dosa.TDate:
+		return dosarpc.ElemTypeInt32
 	case dosa.Bool:
 		return dosarpc.ElemTypeBool
 	case dosa.Blob:
@@ -136,6 +182,11 @@ func RPCTypeToClientType(t dosarpc.ElemType) dosa.Type {
 	panic("bad type")
 }
 
+// dosaTypeTag is the name of the field tag that carries the DOSA type of the columns
+// whose RPC ElemType maps back to a different type, like counters, collections,
+// decimals and dates. The other field tags are the column tags.
+const dosaTypeTag = "dosaType"
+
 // EntityDefinitionToThrift converts the client EntityDefinition to the RPC EntityDefinition
 func EntityDefinitionToThrift(ed *dosa.EntityDefinition) *dosarpc.EntityDefinition {
 	ck := make([]*dosarpc.ClusteringKey, len(ed.Key.ClusteringKeys))
@@ -149,12 +200,28 @@ func EntityDefinitionToThrift(ed *dosa.EntityDefinition) *dosarpc.EntityDefiniti
 	fd := make(map[string]*dosarpc.FieldDesc, len(ed.Columns))
 	for _, column := range ed.Columns {
 		rpcType := RPCTypeFromClientType(column.Type)
-		fd[column.Name] = &dosarpc.FieldDesc{Type: &rpcType}
+		desc := &dosarpc.FieldDesc{Type: &rpcType}
+		tagNames := make([]string, 0, len(column.Tags))
+		for name := range column.Tags {
+			tagNames = append(tagNames, name)
+		}
+		sort.Strings(tagNames)
+		for _, name := range tagNames {
+			desc.Tags = append(desc.Tags, fieldTag(name, column.Tags[name]))
+		}
+		if RPCTypeToClientType(rpcType) != column.Type {
+			desc.Tags = append(desc.Tags, fieldTag(dosaTypeTag, column.Type.String()))
+		}
+		fd[column.Name] = desc
 	}
 	name := ed.Name
 	return &dosarpc.EntityDefinition{PrimaryKey: &pk, FieldDescs: fd, Name: &name}
 }
 
+func fieldTag(name, value string) *dosarpc.FieldTag {
+	return &dosarpc.FieldTag{Name: &name, Value: &value}
+}
+
 // FromThriftToEntityDefinition converts the RPC EntityDefinition to client EntityDefinition
 func FromThriftToEntityDefinition(ed *dosarpc.EntityDefinition) *dosa.EntityDefinition {
 	fields := make([]*dosa.ColumnDefinition, len(ed.FieldDescs))
@@ -163,7 +230,23 @@ func FromThriftToEntityDefinition(ed *dosarpc.EntityDefinition) *dosa.EntityDefi
 		fields[i] = &dosa.ColumnDefinition{
 			Name: k,
 			Type: RPCTypeToClientType(*v.Type),
-			// TODO Tag
+		}
+		for _, tag := range v.Tags {
+			if tag.Name == nil {
+				continue
+			}
+			var value string
+			if tag.Value != nil {
+				value = *tag.Value
+			}
+			if *tag.Name == dosaTypeTag {
+				fields[i].Type = dosa.FromString(value)
+				continue
+			}
+			if fields[i].Tags == nil {
+				fields[i].Tags = make(map[string]string)
+			}
+			fields[i].Tags[*tag.Name] = value
 		}
 		i++
 	}
@@ -208,7 +291,12 @@ func decodeResults(ei *dosa.EntityInfo, invals dosarpc.FieldValueMap) map[string
 	for name, value := range invals {
 		for _, col := range ei.Def.Columns {
 			if col.Name == name {
-				result[name] = RawValueAsInterface(*value.ElemValue, col.Type)
+				if value == nil || value.ElemValue == nil {
+					// null values are set as nil pointers
+					result[name] = nil
+				} else {
+					result[name] = RawValueAsInterface(*value.ElemValue, col.Type)
+				}
 				break
 			}
 		}
@@ -243,6 +331,11 @@ func entityInfoToSchemaRef(ei *dosa.EntityInfo) *dosarpc.SchemaRef {
 func fieldValueMapFromClientMap(values map[string]dosa.FieldValue) (dosarpc.FieldValueMap, error) {
 	fields := dosarpc.FieldValueMap{}
 	for name, value := range values {
+		if value == nil {
+			// nil pointer fields are sent as a Value with no RawValue set
+			fields[name] = &dosarpc.Value{}
+			continue
+		}
 		rv, err := RawValueFromInterface(value)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Error encoding field %q", name)
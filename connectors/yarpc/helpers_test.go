@@ -21,7 +21,10 @@
 package yarpc
 
 import (
+	"fmt"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/uber-go/dosa"
@@ -68,6 +71,33 @@ func TestRawValueFromInterfaceNilBlob(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestFieldValueMapNullRoundTrip(t *testing.T) {
+	ei := &dosa.EntityInfo{Def: &dosa.EntityDefinition{Columns: []*dosa.ColumnDefinition{
+		{Name: "id", Type: dosa.String},
+		{Name: "email", Type: dosa.String, IsPointer: true},
+	}}}
+	values := map[string]dosa.FieldValue{"id": "name", "email": nil}
+	fields, err := fieldValueMapFromClientMap(values)
+	assert.NoError(t, err)
+	assert.Nil(t, fields["email"].ElemValue)
+	_, err = fields["email"].ToWire()
+	assert.NoError(t, err)
+	assert.Equal(t, values, decodeResults(ei, fields))
+}
+
+func TestDecodeResultsNull(t *testing.T) {
+	name := "name"
+	ei := &dosa.EntityInfo{Def: &dosa.EntityDefinition{Columns: []*dosa.ColumnDefinition{
+		{Name: "id", Type: dosa.String},
+		{Name: "email", Type: dosa.String, IsPointer: true},
+	}}}
+	values := decodeResults(ei, dosarpc.FieldValueMap{
+		"id":    {ElemValue: &dosarpc.RawValue{StringValue: &name}},
+		"email": {},
+	})
+	assert.Equal(t, map[string]dosa.FieldValue{"id": "name", "email": nil}, values)
+}
+
 func TestRawValueConversionError(t *testing.T) {
 	data := []struct {
 		input  interface{}
@@ -90,6 +120,70 @@ func TestRawValueConversionError(t *testing.T) {
 	assert.NotNil(t, v)
 }
 
+func TestRawValueDecimalDate(t *testing.T) {
+	raw, err := RawValueFromInterface(dosa.Decimal("-12.340"))
+	assert.NoError(t, err)
+	assert.Equal(t, "-12.340", *raw.StringValue)
+	assert.Equal(t, dosa.Decimal("-12.340"), RawValueAsInterface(*raw, dosa.TDecimal))
+	assert.Equal(t, dosarpc.ElemTypeString, RPCTypeFromClientType(dosa.TDecimal))
+
+	date := dosa.NewDate(1969, time.December, 31)
+	raw, err = RawValueFromInterface(date)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(-1), *raw.Int32Value)
+	assert.Equal(t, date, RawValueAsInterface(*raw, dosa.TDate))
+	assert.Equal(t, dosarpc.ElemTypeInt32, RPCTypeFromClientType(dosa.TDate))
+
+	_, err = RawValueFromInterface(dosa.Decimal("12,34"))
+	assert.Contains(t, err.Error(), "invalid decimal")
+}
+
+func TestRawValueCounter(t *testing.T) {
+	raw, err := RawValueFromInterface(dosa.CounterValue(-3))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-3), *raw.Int64Value)
+	assert.Equal(t, dosa.CounterValue(-3), RawValueAsInterface(*raw, dosa.Counter))
+	assert.Equal(t, dosarpc.ElemTypeInt64, RPCTypeFromClientType(dosa.Counter))
+}
+
+func TestEntityDefinitionConvertCounter(t *testing.T) {
+	counterEntity := &dosa.EntityDefinition{
+		Name: "counters",
+		Key:  &dosa.PrimaryKey{PartitionKeys: []string{"id"}, ClusteringKeys: []*dosa.ClusteringKey{}},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "id", Type: dosa.String},
+			{Name: "count", Type: dosa.Counter},
+		},
+	}
+	rpcEd := EntityDefinitionToThrift(counterEntity)
+	assert.Equal(t, dosarpc.ElemTypeInt64, *rpcEd.FieldDescs["count"].Type)
+	ed := FromThriftToEntityDefinition(rpcEd)
+	for _, c := range ed.Columns {
+		assert.Equal(t, counterEntity.FindColumnDefinition(c.Name).Type, c.Type, c.Name)
+	}
+}
+
+func TestRawValueCollections(t *testing.T) {
+	data := []struct {
+		value dosa.FieldValue
+		typ   dosa.Type
+	}{
+		{[]string{"b", "a"}, dosa.ListOf(dosa.String)},
+		{map[int64]dosa.Set{1: {}}, dosa.SetOf(dosa.Int64)},
+		{map[string][]byte{"a": {1}}, dosa.MapOf(dosa.String, dosa.Blob)},
+	}
+	for _, d := range data {
+		raw, err := RawValueFromInterface(d.value)
+		assert.NoError(t, err)
+		assert.NotNil(t, raw.BinaryValue)
+		assert.Equal(t, d.value, RawValueAsInterface(*raw, d.typ))
+		assert.Equal(t, dosarpc.ElemTypeBlob, RPCTypeFromClientType(d.typ))
+	}
+
+	_, err := RawValueFromInterface(map[bool]float64{true: math.Inf(1)})
+	assert.Error(t, err)
+}
+
 // TODO: add additional happy path unit tests here. The helpers currently get
 // good coverage from the connectors though.
 
@@ -174,6 +268,29 @@ func TestEntityDefinitionConvert(t *testing.T) {
 	assert.Equal(t, edCols, testCols)
 }
 
+func TestEntityDefinitionConvertTypesAndTags(t *testing.T) {
+	types := []dosa.Type{
+		dosa.TUUID, dosa.String, dosa.Int32, dosa.Int64, dosa.Double, dosa.Blob, dosa.Timestamp,
+		dosa.Bool, dosa.Counter, dosa.TDecimal, dosa.TDate,
+		dosa.ListOf(dosa.String), dosa.ListOf(dosa.Blob), dosa.SetOf(dosa.Int64), dosa.SetOf(dosa.TDecimal),
+		dosa.MapOf(dosa.String, dosa.Int32), dosa.MapOf(dosa.TDate, dosa.Blob),
+	}
+	ed := &dosa.EntityDefinition{
+		Name: "alltypes",
+		Key:  &dosa.PrimaryKey{PartitionKeys: []string{"c0"}, ClusteringKeys: []*dosa.ClusteringKey{}},
+	}
+	for i, typ := range types {
+		ed.Columns = append(ed.Columns, &dosa.ColumnDefinition{Name: fmt.Sprintf("c%d", i), Type: typ})
+	}
+	ed.Columns[1].Tags = map[string]string{"pii": "", "searchable": "true"}
+
+	converted := FromThriftToEntityDefinition(EntityDefinitionToThrift(ed))
+	assert.Len(t, converted.Columns, len(ed.Columns))
+	for _, c := range converted.Columns {
+		assert.Equal(t, ed.FindColumnDefinition(c.Name), c, c.Name)
+	}
+}
+
 func TestEncodeOperator(t *testing.T) {
 	data := []struct {
 		dop   dosa.Operator
@@ -468,6 +468,18 @@ func (c *Connector) CheckSchemaStatus(ctx context.Context, scope, namePrefix str
 	}, nil
 }
 
+// GetSchema is not supported: the gateway API has no call returning the
+// entity definitions of a schema version
+func (c *Connector) GetSchema(ctx context.Context, scope, namePrefix string, version int32) ([]*dosa.EntityDefinition, error) {
+	return nil, errors.Wrapf(&dosa.ErrNotImplemented{}, "YARPC GetSchema failed: the gateway cannot return schema %q of scope %q", namePrefix, scope)
+}
+
+// ListSchemaVersions is not supported: the gateway API has no call listing
+// the versions of a schema
+func (c *Connector) ListSchemaVersions(ctx context.Context, scope, namePrefix string) ([]*dosa.SchemaStatus, error) {
+	return nil, errors.Wrapf(&dosa.ErrNotImplemented{}, "YARPC ListSchemaVersions failed: the gateway cannot list the versions of schema %q of scope %q", namePrefix, scope)
+}
+
 // CreateScope creates the scope specified
 func (c *Connector) CreateScope(ctx context.Context, scope string) error {
 	request := &dosarpc.CreateScopeRequest{
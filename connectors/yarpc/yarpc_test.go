@@ -539,6 +539,20 @@ func TestClient_CheckSchemaStatus(t *testing.T) {
 	assert.Equal(t, version, sr.Version)
 }
 
+func TestClient_ListSchemaVersions(t *testing.T) {
+	sut := yarpc.Connector{}
+	_, err := sut.ListSchemaVersions(ctx, "scope", "prefix")
+	assert.True(t, dosa.ErrorIsNotImplemented(err))
+	assert.Contains(t, err.Error(), "the gateway cannot list the versions")
+}
+
+func TestClient_GetSchema(t *testing.T) {
+	sut := yarpc.Connector{}
+	_, err := sut.GetSchema(ctx, "scope", "prefix", dosa.LatestVersion)
+	assert.True(t, dosa.ErrorIsNotImplemented(err))
+	assert.Contains(t, err.Error(), "the gateway cannot return schema")
+}
+
 func TestClient_UpsertSchema(t *testing.T) {
 	// build a mock RPC client
 	ctrl := gomock.NewController(t)
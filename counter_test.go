@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	dosaRenamed "github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/memory"
+	"github.com/uber-go/dosa/mocks"
+)
+
+type ClientTestCounters struct {
+	dosaRenamed.Entity `dosa:"primaryKey=(ID)"`
+	ID                 int64
+	Hits               dosaRenamed.CounterValue
+}
+
+func TestClient_Increment(t *testing.T) {
+	reg, err := dosaRenamed.NewRegistrar("test", "myteam.myservice", &ClientTestCounters{}, cte1)
+	assert.NoError(t, err)
+	conn := memory.NewConnector()
+	assert.NoError(t, conn.CreateScope(context.TODO(), "test"))
+	res, err := reg.FindAll()
+	assert.NoError(t, err)
+	var defs []*dosaRenamed.EntityDefinition
+	for _, re := range res {
+		defs = append(defs, re.EntityDefinition())
+	}
+	_, err = conn.UpsertSchema(context.TODO(), "test", "myteam.myservice", defs)
+	assert.NoError(t, err)
+
+	c := dosaRenamed.NewClient(reg, conn)
+	counters := &ClientTestCounters{ID: 1}
+	assert.IsType(t, &dosaRenamed.ErrNotInitialized{}, c.Increment(ctx, counters, "Hits", 1))
+	assert.NoError(t, c.Initialize(context.TODO()))
+
+	assert.NoError(t, c.Increment(ctx, counters, "Hits", 1))
+	assert.Equal(t, dosaRenamed.CounterValue(1), counters.Hits)
+	assert.NoError(t, c.Increment(ctx, &ClientTestCounters{ID: 1}, "Hits", 2))
+	assert.NoError(t, c.Read(ctx, dosaRenamed.All(), counters))
+	assert.Equal(t, dosaRenamed.CounterValue(3), counters.Hits)
+
+	err = c.Increment(ctx, counters, "Misses", 1)
+	assert.Contains(t, err.Error(), "not a valid field")
+	err = c.Increment(ctx, counters, "ID", 1)
+	assert.Contains(t, err.Error(), "ID is not a counter field of ClientTestCounters")
+	err = c.Increment(ctx, cte2, "Color", 1)
+	assert.Error(t, err)
+}
+
+func TestClient_IncrementUnsupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	reg, err := dosaRenamed.NewRegistrar("test", "myteam.myservice", &ClientTestCounters{})
+	assert.NoError(t, err)
+	mockConn := mocks.NewMockConnector(ctrl)
+	mockConn.EXPECT().CheckSchema(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int32(1), nil)
+	c := dosaRenamed.NewClient(reg, mockConn)
+	assert.NoError(t, c.Initialize(context.TODO()))
+	err = c.Increment(ctx, &ClientTestCounters{ID: 1}, "Hits", 1)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "cannot increment counters")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dateLayout    = "2006-01-02"
+	secondsPerDay = 24 * 60 * 60
+)
+
+// Date holds the value of a Date column, a calendar date without a time of
+// day or a time zone.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDate is a helper for returning a dosa.Date value. Out of range months
+// and days are normalized like time.Date does, so the 32nd of January is
+// the 1st of February.
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the date of a time, in the location of the time
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date in the 2006-01-02 format
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, errors.Wrapf(err, "invalid date %q", s)
+	}
+	return DateOf(t), nil
+}
+
+// DateFromDays returns the date a number of days after 1970-01-01
+func DateFromDays(days int32) Date {
+	return DateOf(time.Unix(int64(days)*secondsPerDay, 0).UTC())
+}
+
+// Days returns the number of days since 1970-01-01, which is negative for
+// earlier dates
+func (d Date) Days() int32 {
+	return int32(d.In(time.UTC).Unix() / secondsPerDay)
+}
+
+// In returns the time of the start of the date in a location
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Before reports whether the date d is before other
+func (d Date) Before(other Date) bool {
+	return d.Days() < other.Days()
+}
+
+// After reports whether the date d is after other
+func (d Date) After(other Date) bool {
+	return d.Days() > other.Days()
+}
+
+// String returns the date in the 2006-01-02 format
+func (d Date) String() string {
+	return d.In(time.UTC).Format(dateLayout)
+}
+
+// MarshalText encodes a Date in the 2006-01-02 format, which is also used
+// for the dates of lists, sets and maps
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a Date from the 2006-01-02 format
+func (d *Date) UnmarshalText(data []byte) error {
+	date, err := ParseDate(string(data))
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDate(t *testing.T) {
+	assert.Equal(t, Date{Year: 2017, Month: time.February, Day: 1}, NewDate(2017, time.January, 32))
+	assert.Equal(t, Date{Year: 2016, Month: time.December, Day: 31}, NewDate(2017, time.January, 0))
+}
+
+func TestDateOf(t *testing.T) {
+	loc := time.FixedZone("UTC-8", -8*60*60)
+	ts := time.Date(2017, time.March, 1, 23, 0, 0, 0, loc)
+	assert.Equal(t, NewDate(2017, time.March, 1), DateOf(ts))
+	assert.Equal(t, NewDate(2017, time.March, 2), DateOf(ts.UTC()))
+}
+
+func TestParseDate(t *testing.T) {
+	d, err := ParseDate("2017-07-04")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDate(2017, time.July, 4), d)
+	assert.Equal(t, "2017-07-04", d.String())
+
+	_, err = ParseDate("07/04/2017")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid date")
+}
+
+func TestDateDays(t *testing.T) {
+	data := []struct {
+		date Date
+		days int32
+	}{
+		{NewDate(1970, time.January, 1), 0},
+		{NewDate(1970, time.January, 2), 1},
+		{NewDate(1969, time.December, 31), -1},
+		{NewDate(2000, time.March, 1), 11017},
+	}
+	for _, d := range data {
+		assert.Equal(t, d.days, d.date.Days(), d.date.String())
+		assert.Equal(t, d.date, DateFromDays(d.days))
+	}
+}
+
+func TestDateBeforeAfter(t *testing.T) {
+	a := NewDate(2016, time.December, 31)
+	b := NewDate(2017, time.January, 1)
+	assert.True(t, a.Before(b))
+	assert.False(t, b.Before(a))
+	assert.True(t, b.After(a))
+	assert.False(t, a.After(a))
+}
+
+func TestDateJSON(t *testing.T) {
+	d := NewDate(2017, time.May, 9)
+	data, err := json.Marshal(d)
+	assert.NoError(t, err)
+	assert.Equal(t, `"2017-05-09"`, string(data))
+
+	var decoded Date
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, d, decoded)
+	assert.Error(t, json.Unmarshal([]byte(`"tomorrow"`), &decoded))
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Decimal stores a string format of an arbitrary precision decimal number,
+// such as "-12.340". Like UUID, validation is done before saving to
+// datastore, and the digits after the decimal point are kept as is.
+type Decimal string
+
+var decimalPattern = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+
+// ParseDecimal validates a decimal number
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return "", errors.Errorf("invalid decimal %q", s)
+	}
+	return Decimal(strings.TrimPrefix(s, "+")), nil
+}
+
+// Canonical returns the shortest form of a decimal number, without a plus
+// sign or superfluous zeros, so that equal decimals like "1.5" and "1.50"
+// have the same form. Connectors use it to encode keys.
+func (d Decimal) Canonical() (Decimal, error) {
+	parsed, err := ParseDecimal(string(d))
+	if err != nil {
+		return "", err
+	}
+	s := string(parsed)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	s = strings.TrimLeft(s, "0")
+	if s == "" || s[0] == '.' {
+		s = "0" + s
+	}
+	if negative && s != "0" {
+		s = "-" + s
+	}
+	return Decimal(s), nil
+}
+
+// NewDecimal is a helper for returning the dosa.Decimal value of a rational
+// number, rounded to the given number of digits after the decimal point
+func NewDecimal(r *big.Rat, scale int) Decimal {
+	return Decimal(r.FloatString(scale))
+}
+
+// Rat gets the rational number of a Decimal
+func (d Decimal) Rat() (*big.Rat, error) {
+	if _, err := ParseDecimal(string(d)); err != nil {
+		return nil, err
+	}
+	r, _ := new(big.Rat).SetString(string(d))
+	return r, nil
+}
+
+// Cmp compares two decimal numbers, returning -1, 0 or 1. Decimals with
+// different numbers of digits after the decimal point can be equal, like
+// "1.5" and "1.50". Invalid decimals are compared as strings.
+func (d Decimal) Cmp(other Decimal) int {
+	a, errA := d.Rat()
+	b, errB := other.Rat()
+	if errA != nil || errB != nil {
+		return strings.Compare(string(d), string(other))
+	}
+	return a.Cmp(b)
+}
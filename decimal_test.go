@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	data := []struct {
+		input    string
+		expected Decimal
+		err      bool
+	}{
+		{input: "0", expected: "0"},
+		{input: "-12.340", expected: "-12.340"},
+		{input: "+1.5", expected: "1.5"},
+		{input: "", err: true},
+		{input: "1.", err: true},
+		{input: ".5", err: true},
+		{input: "1e10", err: true},
+		{input: "one", err: true},
+	}
+	for _, d := range data {
+		dec, err := ParseDecimal(d.input)
+		if d.err {
+			assert.Error(t, err, d.input)
+			assert.Contains(t, err.Error(), "invalid decimal")
+			continue
+		}
+		assert.NoError(t, err, d.input)
+		assert.Equal(t, d.expected, dec)
+	}
+}
+
+func TestNewDecimal(t *testing.T) {
+	assert.Equal(t, Decimal("0.33"), NewDecimal(big.NewRat(1, 3), 2))
+	assert.Equal(t, Decimal("-2.500"), NewDecimal(big.NewRat(-5, 2), 3))
+	assert.Equal(t, Decimal("7"), NewDecimal(big.NewRat(7, 1), 0))
+}
+
+func TestDecimalRat(t *testing.T) {
+	r, err := Decimal("-1.25").Rat()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Cmp(big.NewRat(-5, 4)))
+
+	_, err = Decimal("abc").Rat()
+	assert.Error(t, err)
+}
+
+func TestDecimalCanonical(t *testing.T) {
+	data := []struct {
+		input    Decimal
+		expected Decimal
+	}{
+		{"1.5", "1.5"},
+		{"1.50", "1.5"},
+		{"+01.50", "1.5"},
+		{"100", "100"},
+		{"100.00", "100"},
+		{"0.050", "0.05"},
+		{"-0.0", "0"},
+		{"-007", "-7"},
+	}
+	for _, d := range data {
+		canonical, err := d.input.Canonical()
+		assert.NoError(t, err)
+		assert.Equal(t, d.expected, canonical, "canonical form of %s", d.input)
+	}
+	_, err := Decimal("1,5").Canonical()
+	assert.Error(t, err)
+}
+
+func TestDecimalCmp(t *testing.T) {
+	data := []struct {
+		a, b     Decimal
+		expected int
+	}{
+		{"1.5", "1.50", 0},
+		{"9.99", "10", -1},
+		{"-1", "-1.01", 1},
+		{"abc", "abd", -1},
+	}
+	for _, d := range data {
+		assert.Equal(t, d.expected, d.a.Cmp(d.b), "%s cmp %s", d.a, d.b)
+	}
+}
@@ -93,8 +93,9 @@ func (pk PrimaryKey) String() string {
 
 // ColumnDefinition stores information about a column
 type ColumnDefinition struct {
-	Name string // normalized column name
-	Type Type
+	Name      string // normalized column name
+	IsPointer bool   // used by client only to indicate whether this field is pointer
+	Type      Type
 	// TODO: change as need to support tags like pii, searchable, etc
 	// currently it's in the form of a map from tag name to (optional) tag value
 	Tags map[string]string
@@ -137,7 +138,7 @@ func (e *EntityDefinition) EnsureValid() error {
 		if _, ok := columnNamesSeen[c.Name]; ok {
 			return errors.Errorf("duplicated column found: %q", c.Name)
 		}
-		if c.Type == Invalid {
+		if !c.Type.isValid() {
 			return errors.Errorf("invalid type for column: %q", c.Name)
 		}
 		columnNamesSeen[c.Name] = struct{}{}
@@ -177,6 +178,28 @@ func (e *EntityDefinition) EnsureValid() error {
 		keyNamesSeen[c.Name] = struct{}{}
 	}
 
+	// counters, collections and nullable columns cannot be keys and, as in Cassandra, a table holding
+	// counters can only hold counters besides its key
+	var counter, other string
+	for _, c := range e.Columns {
+		_, isKey := keyNamesSeen[c.Name]
+		switch {
+		case c.Type == Counter && isKey:
+			return errors.Errorf("a counter column cannot be used in key: %q", c.Name)
+		case c.Type.IsCollection() && isKey:
+			return errors.Errorf("a collection column cannot be used in key: %q", c.Name)
+		case c.IsPointer && isKey:
+			return errors.Errorf("a nullable column cannot be used in key: %q", c.Name)
+		case c.Type == Counter && counter == "":
+			counter = c.Name
+		case c.Type != Counter && !isKey && other == "":
+			other = c.Name
+		}
+	}
+	if counter != "" && other != "" {
+		return errors.Errorf("counter column %q cannot be mixed with non-counter column %q", counter, other)
+	}
+
 	return nil
 }
 
@@ -265,6 +288,65 @@ func (e *EntityDefinition) IsCompatible(e2 *EntityDefinition) error {
 	return nil
 }
 
+// SchemaVersion is one stored version of the entities of a scope and name
+// prefix. The connectors storing schemas use it to check and upsert them.
+type SchemaVersion struct {
+	Version  int32
+	Entities []*EntityDefinition
+}
+
+// FindEntity returns the definition of an entity in this version, or nil
+func (sv *SchemaVersion) FindEntity(name string) *EntityDefinition {
+	for _, ed := range sv.Entities {
+		if ed.Name == name {
+			return ed
+		}
+	}
+	return nil
+}
+
+// Matches returns true if every provided entity is part of this version with
+// the same keys and columns. Entities of the version not provided are ignored.
+func (sv *SchemaVersion) Matches(eds []*EntityDefinition) bool {
+	for _, ed := range eds {
+		stored := sv.FindEntity(ed.Name)
+		if stored == nil || stored.IsCompatible(ed) != nil || ed.IsCompatible(stored) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Same returns true if the provided entities are exactly those of this
+// version, in which case upserting them does not create a new version
+func (sv *SchemaVersion) Same(eds []*EntityDefinition) bool {
+	return len(sv.Entities) == len(eds) && sv.Matches(eds)
+}
+
+// EnsureCompatible checks that the provided definitions can replace the ones
+// of this version: existing entities may only gain new columns
+func (sv *SchemaVersion) EnsureCompatible(eds []*EntityDefinition) error {
+	for _, ed := range eds {
+		if stored := sv.FindEntity(ed.Name); stored != nil {
+			if err := ed.IsCompatible(stored); err != nil {
+				return errors.Wrapf(err, "entity %q is not compatible with version %d", ed.Name, sv.Version)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchingSchemaVersion returns the latest of the versions, given oldest first,
+// that matches the provided entities, or nil if none does
+func MatchingSchemaVersion(versions []*SchemaVersion, eds []*EntityDefinition) *SchemaVersion {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Matches(eds) {
+			return versions[i]
+		}
+	}
+	return nil
+}
+
 // FindColumnDefinition finds the column definition by the column name
 func (e *EntityDefinition) FindColumnDefinition(name string) *ColumnDefinition {
 	for _, cd := range e.Columns {
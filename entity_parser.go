@@ -44,7 +44,9 @@ var (
 	primaryKeyPattern2 = regexp.MustCompile(`\(\s*([^,\s]+),?(.*)\)`)
 	primaryKeyPattern3 = regexp.MustCompile(`^\s*([^(),\s]+)\s*$`)
 
-	namePattern0 = regexp.MustCompile(`name\s*=\s*(\S*)`)
+	namePattern0   = regexp.MustCompile(`name\s*=\s*(\S*)`)
+	typePattern0   = regexp.MustCompile(`type\s*=\s*(\S*)`)
+	prefixPattern0 = regexp.MustCompile(`prefix\s*=\s*(\S*)`)
 )
 
 // parseClusteringKeys func parses the clustering key of DOSA object
@@ -189,14 +191,8 @@ func TableFromInstance(object DomainObject) (*Table, error) {
 			if t.EntityDefinition.Name, t.Key, err = parseEntityTag(t.StructName, tag); err != nil {
 				return nil, err
 			}
-		} else {
-			cd, err := parseFieldTag(structField, tag)
-			if err != nil {
-				return nil, errors.Wrapf(err, "column %q had invalid type", name)
-			}
-			t.Columns = append(t.Columns, cd)
-			t.ColToField[cd.Name] = name
-			t.FieldToCol[name] = cd.Name
+		} else if err := t.addStructField(elem, structField, tag, ""); err != nil {
+			return nil, err
 		}
 	}
 
@@ -213,6 +209,81 @@ func TableFromInstance(object DomainObject) (*Table, error) {
 	return t, nil
 }
 
+// addStructField adds the column of a struct field to the table. Anonymous
+// embedded structs are flattened into columns, whose names start with the
+// prefix of the embedded field's tag.
+func (t *Table) addStructField(entityType reflect.Type, structField reflect.StructField, tag, prefix string) error {
+	if isEmbeddedStruct(structField) {
+		embeddedPrefix, err := parseEmbeddedTag(structField.Name, tag)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < structField.Type.NumField(); i++ {
+			field := structField.Type.Field(i)
+			if len(field.PkgPath) > 0 { // skip unexported fields
+				continue
+			}
+			fieldTag := strings.TrimSpace(field.Tag.Get(dosaTagKey))
+			if fieldTag == "-" { // skip explicitly ignored fields
+				continue
+			}
+			if field.Type == dosaEntityType {
+				return errors.Errorf("embedded struct %s cannot declare a dosa.Entity", structField.Name)
+			}
+			field.Index = append(append([]int{}, structField.Index...), field.Index...)
+			if err := t.addStructField(entityType, field, fieldTag, prefix+embeddedPrefix); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	name := structField.Name
+	cd, err := parseFieldTag(structField, tag)
+	if err != nil {
+		return errors.Wrapf(err, "column %q had invalid type", name)
+	}
+	if prefix != "" {
+		if cd.Name, err = NormalizeName(prefix + cd.Name); err != nil {
+			return errors.Wrapf(err, "field %s has an invalid prefixed column name", name)
+		}
+	}
+	if err := t.addColumn(cd, name); err != nil {
+		return err
+	}
+	// the values of the fields of embedded structs are accessed by name, so
+	// they must not be shadowed by other fields
+	if promoted, _ := entityType.FieldByName(name); !reflect.DeepEqual(promoted.Index, structField.Index) {
+		return errors.Errorf("field %s is declared more than once", name)
+	}
+	return nil
+}
+
+// isEmbeddedStruct checks if a struct field is an anonymous struct that is not
+// a column type itself, such as time.Time
+func isEmbeddedStruct(structField reflect.StructField) bool {
+	if !structField.Anonymous || structField.Type.Kind() != reflect.Struct {
+		return false
+	}
+	_, err := typify(structField.Type)
+	return err != nil
+}
+
+// addColumn adds the column of a field to the table, checking that neither
+// the column nor the field was added before
+func (t *Table) addColumn(cd *ColumnDefinition, fieldName string) error {
+	if other, ok := t.ColToField[cd.Name]; ok {
+		return errors.Errorf("column %q of field %s conflicts with field %s", cd.Name, fieldName, other)
+	}
+	if _, ok := t.FieldToCol[fieldName]; ok {
+		return errors.Errorf("field %s is declared more than once", fieldName)
+	}
+	t.Columns = append(t.Columns, cd)
+	t.ColToField[cd.Name] = fieldName
+	t.FieldToCol[fieldName] = cd.Name
+	return nil
+}
+
 // primaryKeyNameMatch translate the primary keys to the internal column name based on the maping
 // between fields and columns.
 func translateKeyName(t *Table) {
@@ -255,6 +326,35 @@ func parseNameTag(tag, defaultName string) (string, string, error) {
 	return fullNameTag, name, nil
 }
 
+// parseTypeTag function parses DOSA "type" tag, returning Invalid when the
+// tag has none
+func parseTypeTag(tag string) (string, Type, error) {
+	matches := typePattern0.FindStringSubmatch(tag)
+	if len(matches) != 2 {
+		return "", Invalid, nil
+	}
+	typ := FromString(strings.TrimRight(matches[1], " ,"))
+	if !typ.isValid() {
+		return "", Invalid, errors.Errorf("unknown type %q", matches[1])
+	}
+	return matches[0], typ, nil
+}
+
+// parseEmbeddedTag function parses DOSA tag on anonymous embedded structs,
+// returning the prefix of their column names
+func parseEmbeddedTag(name, tag string) (string, error) {
+	prefix := ""
+	matches := prefixPattern0.FindStringSubmatch(tag)
+	if len(matches) == 2 {
+		prefix = strings.TrimRight(matches[1], " ,")
+		tag = strings.Replace(tag, matches[0], "", 1)
+	}
+	if strings.TrimSpace(tag) != "" {
+		return "", fmt.Errorf("embedded struct %s with an invalid dosa tag: %s", name, tag)
+	}
+	return prefix, nil
+}
+
 // parseEntityTag function parses DOSA tag on the "Entity" field
 func parseEntityTag(structName, dosaAnnotation string) (string, *PrimaryKey, error) {
 	tag := dosaAnnotation
@@ -292,7 +392,12 @@ func parseFieldTag(structField reflect.StructField, dosaAnnotation string) (*Col
 	if err != nil {
 		return nil, err
 	}
-	return parseField(typ, structField.Name, dosaAnnotation)
+	cd, err := parseField(typ, structField.Name, dosaAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	cd.IsPointer = structField.Type.Kind() == reflect.Ptr
+	return cd, nil
 }
 
 func parseField(typ Type, name string, tag string) (*ColumnDefinition, error) {
@@ -303,6 +408,21 @@ func parseField(typ Type, name string, tag string) (*ColumnDefinition, error) {
 	}
 
 	tag = strings.Replace(tag, fullNameTag, "", 1)
+
+	// parse type tag, which is required for the custom types the finder
+	// cannot resolve
+	fullTypeTag, tagType, err := parseTypeTag(tag)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid type tag: %s", tag)
+	}
+	if tagType != Invalid {
+		if typ != Invalid && typ != tagType {
+			return nil, fmt.Errorf("field %s has type %v but its type tag says %v", name, typ, tagType)
+		}
+		typ = tagType
+	}
+
+	tag = strings.Replace(tag, fullTypeTag, "", 1)
 	if strings.TrimSpace(tag) != "" {
 		return nil, fmt.Errorf("field %s with an invalid dosa field tag: %s", name, tag)
 	}
@@ -311,17 +431,55 @@ func parseField(typ Type, name string, tag string) (*ColumnDefinition, error) {
 }
 
 var (
-	uuidType      = reflect.TypeOf(UUID(""))
-	blobType      = reflect.TypeOf([]byte{})
-	timestampType = reflect.TypeOf(time.Time{})
-	int32Type     = reflect.TypeOf(int32(0))
-	int64Type     = reflect.TypeOf(int64(0))
-	doubleType    = reflect.TypeOf(float64(0.0))
-	stringType    = reflect.TypeOf("")
-	boolType      = reflect.TypeOf(true)
+	uuidType       = reflect.TypeOf(UUID(""))
+	blobType       = reflect.TypeOf([]byte{})
+	timestampType  = reflect.TypeOf(time.Time{})
+	int32Type      = reflect.TypeOf(int32(0))
+	int64Type      = reflect.TypeOf(int64(0))
+	doubleType     = reflect.TypeOf(float64(0.0))
+	stringType     = reflect.TypeOf("")
+	boolType       = reflect.TypeOf(true)
+	counterType    = reflect.TypeOf(CounterValue(0))
+	decimalType    = reflect.TypeOf(Decimal(""))
+	dateType       = reflect.TypeOf(Date{})
+	dosaEntityType = reflect.TypeOf(Entity{})
+
+	// numericTypes are the Go numeric types of entity fields that are stored
+	// as a wider DOSA type, and converted back when read
+	numericTypes = map[reflect.Type]Type{
+		reflect.TypeOf(int(0)):     Int64,
+		reflect.TypeOf(int8(0)):    Int32,
+		reflect.TypeOf(int16(0)):   Int32,
+		reflect.TypeOf(uint8(0)):   Int32,
+		reflect.TypeOf(uint16(0)):  Int32,
+		reflect.TypeOf(uint32(0)):  Int64,
+		reflect.TypeOf(float32(0)): Double,
+	}
 )
 
 func typify(f reflect.Type) (Type, error) {
+	if t, ok := marshalerDOSAType(f); ok {
+		if !t.isValid() || t == Counter {
+			return Invalid, fmt.Errorf("Invalid type %v: it is stored as %v", f, t)
+		}
+		return t, nil
+	}
+	if f.Kind() == reflect.Ptr {
+		// pointers to the types of other columns are nullable columns
+		t, err := typify(f.Elem())
+		if err != nil || t == Counter || t.IsCollection() || f.Elem().Kind() == reflect.Ptr {
+			return Invalid, fmt.Errorf("Invalid type %v", f)
+		}
+		return t, nil
+	}
+	if t, ok := numericTypes[f]; ok {
+		return t, nil
+	}
+	return storageType(f)
+}
+
+// storageType returns the DOSA type of the values connectors use
+func storageType(f reflect.Type) (Type, error) {
 	switch f {
 	case uuidType:
 		return TUUID, nil
@@ -339,8 +497,31 @@ func typify(f reflect.Type) (Type, error) {
 		return String, nil
 	case boolType:
 		return Bool, nil
+	case counterType:
+		return Counter, nil
+	case decimalType:
+		return TDecimal, nil
+	case dateType:
+		return TDate, nil
 	}
 
+	var t Type
+	switch f.Kind() {
+	case reflect.Slice:
+		elem, _ := storageType(f.Elem())
+		t = ListOf(elem)
+	case reflect.Map:
+		key, _ := storageType(f.Key())
+		if f.Elem() == setValueType {
+			t = SetOf(key)
+		} else {
+			value, _ := storageType(f.Elem())
+			t = MapOf(key, value)
+		}
+	}
+	if t.isValid() {
+		return t, nil
+	}
 	return Invalid, fmt.Errorf("Invalid type %v", f)
 }
 
@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type AuditFields struct {
+	CreatedAt time.Time
+	CreatedBy string `dosa:"name=creator"`
+	Comment   string `dosa:"-"`
+	note      string
+}
+
+type TrackedFields struct {
+	AuditFields `dosa:"prefix=audit_"`
+	Version     int64
+}
+
+type EmbeddedFields struct {
+	Entity        `dosa:"primaryKey=(ID, CreatedAt)"`
+	ID            int64
+	TrackedFields `dosa:"prefix=t_"`
+	time.Time
+}
+
+func TestEmbeddedFields(t *testing.T) {
+	table, err := TableFromInstance(&EmbeddedFields{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnDefinition{
+		{Name: "id", Type: Int64},
+		{Name: "t_audit_createdat", Type: Timestamp},
+		{Name: "t_audit_creator", Type: String},
+		{Name: "t_version", Type: Int64},
+		{Name: "time", Type: Timestamp},
+	}, table.Columns)
+	assert.Equal(t, map[string]string{
+		"ID":        "id",
+		"CreatedAt": "t_audit_createdat",
+		"CreatedBy": "t_audit_creator",
+		"Version":   "t_version",
+		"Time":      "time",
+	}, table.FieldToCol)
+	assert.Equal(t, []*ClusteringKey{{Name: "t_audit_createdat"}}, table.Key.ClusteringKeys)
+
+	now := time.Now()
+	re := NewRegisteredEntity("test", "team.service", table)
+	entity := &EmbeddedFields{ID: 1}
+	entity.CreatedAt = now
+	entity.CreatedBy = "me"
+	values, err := re.OnlyFieldValues(entity, []string{"CreatedAt", "CreatedBy", "Version"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]FieldValue{"t_audit_createdat": now, "t_audit_creator": "me", "t_version": int64(0)}, values)
+	keys, err := re.KeyFieldValues(entity)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]FieldValue{"id": int64(1), "t_audit_createdat": now}, keys)
+
+	read := &EmbeddedFields{}
+	assert.NoError(t, re.SetFieldValues(read, map[string]FieldValue{"id": int64(1), "t_audit_createdat": now, "t_audit_creator": "me"}, nil))
+	assert.Equal(t, entity, read)
+}
+
+type ShadowedEmbeddedField struct {
+	Entity    `dosa:"primaryKey=(ID)"`
+	ID        int64
+	CreatedAt time.Time
+	AuditFields
+}
+
+type ConflictingEmbeddedFields struct {
+	Entity `dosa:"primaryKey=(ID)"`
+	ID     int64
+	AuditFields
+	TrackedFields `dosa:"prefix=tracked_"`
+}
+
+type InvalidEmbeddedTag struct {
+	Entity      `dosa:"primaryKey=(ID)"`
+	ID          int64
+	AuditFields `dosa:"name=audit"`
+}
+
+type EmbeddedEntity struct {
+	Entity `dosa:"primaryKey=(ID)"`
+	ID     int64
+	EmbeddableEntity
+}
+
+type EmbeddableEntity struct {
+	Entity `dosa:"primaryKey=(ID)"`
+	ID     int64
+}
+
+func TestEmbeddedFieldsConflicts(t *testing.T) {
+	_, err := TableFromInstance(&ShadowedEmbeddedField{})
+	assert.Contains(t, err.Error(), `column "createdat" of field CreatedAt conflicts with field CreatedAt`)
+	_, err = TableFromInstance(&ConflictingEmbeddedFields{})
+	assert.Contains(t, err.Error(), "field CreatedAt is declared more than once")
+	_, err = TableFromInstance(&InvalidEmbeddedTag{})
+	assert.Contains(t, err.Error(), "embedded struct AuditFields with an invalid dosa tag: name=audit")
+	_, err = TableFromInstance(&EmbeddedEntity{})
+	assert.Contains(t, err.Error(), "embedded struct EmbeddableEntity cannot declare a dosa.Entity")
+}
+
+func TestFindEmbeddedFieldsConflicts(t *testing.T) {
+	_, errs, err := FindEntities([]string{"."}, []string{})
+	assert.NoError(t, err)
+
+	// the finder reports the same errors, and TestParser checks that it
+	// finds the same columns
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	for _, msg := range []string{
+		`column "createdat" of field CreatedAt conflicts with field CreatedAt`,
+		"field CreatedAt is declared more than once",
+		"embedded struct AuditFields with an invalid dosa tag: name=audit",
+		"embedded struct EmbeddableEntity cannot declare a dosa.Entity",
+	} {
+		assert.Contains(t, messages, msg)
+	}
+}
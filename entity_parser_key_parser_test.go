@@ -328,7 +328,8 @@ func TestNameTag(t *testing.T) {
 
 func TestFieldParse(t *testing.T) {
 	validFieldType := reflect.StructField{Name: "valid", Type: uuidType}
-	invalidFieldType := reflect.StructField{Name: "invalid", Type: reflect.TypeOf([]string{})}
+	invalidFieldType := reflect.StructField{Name: "invalid", Type: reflect.TypeOf([][]string{})}
+	listFieldType := reflect.StructField{Name: "list", Type: reflect.TypeOf([]string{})}
 
 	data := []struct {
 		StructField reflect.StructField
@@ -339,7 +340,15 @@ func TestFieldParse(t *testing.T) {
 		{
 			StructField: invalidFieldType,
 			Tag:         "",
-			Error:       errors.New("Invalid type []string"),
+			Error:       errors.New("Invalid type [][]string"),
+		},
+		{
+			StructField: listFieldType,
+			Tag:         "",
+			Column: &ColumnDefinition{
+				Name: "list",
+				Type: ListOf(String),
+			},
 		},
 		{
 			StructField: validFieldType,
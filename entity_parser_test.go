@@ -241,17 +241,45 @@ func TestAllTypes(t *testing.T) {
 	}
 }
 
+type CounterType struct {
+	Entity     `dosa:"primaryKey=StringType"`
+	StringType string
+	Hits       CounterValue
+	Misses     CounterValue
+}
+
+func TestCounterType(t *testing.T) {
+	dosaTable, err := TableFromInstance(&CounterType{})
+	assert.NoError(t, err)
+	assert.Equal(t, String, dosaTable.Columns[0].Type)
+	assert.Equal(t, Counter, dosaTable.Columns[1].Type)
+	assert.Equal(t, Counter, dosaTable.Columns[2].Type)
+}
+
+type MixedCounterType struct {
+	Entity     `dosa:"primaryKey=StringType"`
+	StringType string
+	Hits       CounterValue
+	Int64Type  int64
+}
+
+func TestMixedCounterType(t *testing.T) {
+	dosaTable, err := TableFromInstance(&MixedCounterType{})
+	assert.Nil(t, dosaTable)
+	assert.Contains(t, err.Error(), "cannot be mixed with non-counter column")
+}
+
 type UnsupportedType struct {
 	Entity    `dosa:"primaryKey=BoolType"`
 	BoolType  bool
-	UnsupType float32
+	UnsupType uint64
 }
 
 func TestUnsupportedType(t *testing.T) {
 	dosaTable, err := TableFromInstance(&UnsupportedType{})
 	assert.Nil(t, dosaTable)
 	assert.NotNil(t, err)
-	assert.Contains(t, err.Error(), "float32")
+	assert.Contains(t, err.Error(), "uint64")
 	assert.Contains(t, err.Error(), "UnsupType")
 }
 
@@ -386,3 +414,183 @@ func TestRenameStructToValidName(t *testing.T) {
 	assert.NotNil(t, table)
 	assert.NoError(t, err)
 }
+
+func TestTypeTag(t *testing.T) {
+	cd, err := parseField(Int64, "Foo", "type=Int64")
+	assert.NoError(t, err)
+	assert.Equal(t, &ColumnDefinition{Name: "foo", Type: Int64}, cd)
+
+	// the finder relies on type tags for the types it cannot resolve
+	cd, err = parseField(Invalid, "Foo", "name=bar type=Map<String,Int64>")
+	assert.NoError(t, err)
+	assert.Equal(t, &ColumnDefinition{Name: "bar", Type: MapOf(String, Int64)}, cd)
+
+	_, err = parseField(Int64, "Foo", "type=Int32")
+	assert.Contains(t, err.Error(), "field foo has type Int64 but its type tag says Int32")
+	_, err = parseField(Invalid, "Foo", "type=Bogus")
+	assert.Contains(t, err.Error(), `invalid type tag: type=Bogus: unknown type "Bogus"`)
+	_, err = parseField(Int64, "Foo", "type=Int64 bogus")
+	assert.Contains(t, err.Error(), "invalid dosa field tag")
+}
+
+type NumericTypes struct {
+	Entity      `dosa:"primaryKey=IntType"`
+	IntType     int
+	Int8Type    int8
+	Int16Type   int16
+	Uint8Type   uint8
+	Uint16Type  uint16
+	Uint32Type  uint32
+	Float32Type float32
+}
+
+// AliasTypes uses the byte and rune aliases, which the finder must map like
+// reflection does
+type AliasTypes struct {
+	Entity       `dosa:"primaryKey=ByteType"`
+	ByteType     byte
+	RuneType     rune
+	BytePtrType  *byte
+	RuneListType []rune
+	Uint8List    []uint8
+	RuneMapType  map[rune]string
+}
+
+func TestNumericTypes(t *testing.T) {
+	table, err := TableFromInstance(&NumericTypes{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnDefinition{
+		{Name: "inttype", Type: Int64},
+		{Name: "int8type", Type: Int32},
+		{Name: "int16type", Type: Int32},
+		{Name: "uint8type", Type: Int32},
+		{Name: "uint16type", Type: Int32},
+		{Name: "uint32type", Type: Int64},
+		{Name: "float32type", Type: Double},
+	}, table.Columns)
+
+	re := NewRegisteredEntity("test", "team.service", table)
+	entity := &NumericTypes{
+		IntType:     -1,
+		Int8Type:    -8,
+		Int16Type:   -16,
+		Uint8Type:   8,
+		Uint16Type:  16,
+		Uint32Type:  32,
+		Float32Type: 1.5,
+	}
+	values, err := re.OnlyFieldValues(entity, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]FieldValue{
+		"inttype":     int64(-1),
+		"int8type":    int32(-8),
+		"int16type":   int32(-16),
+		"uint8type":   int32(8),
+		"uint16type":  int32(16),
+		"uint32type":  int64(32),
+		"float32type": float64(1.5),
+	}, values)
+	keys, err := re.KeyFieldValues(entity)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]FieldValue{"inttype": int64(-1)}, keys)
+
+	read := &NumericTypes{}
+	assert.NoError(t, re.SetFieldValues(read, values, nil))
+	assert.Equal(t, entity, read)
+
+	// values are checked for overflows when read
+	for column, value := range map[string]FieldValue{
+		"int8type":    int32(128),
+		"int16type":   int32(-32769),
+		"uint8type":   int32(-1),
+		"uint16type":  int32(65536),
+		"uint32type":  int64(1 << 32),
+		"float32type": float64(1e39),
+	} {
+		err := re.SetFieldValues(read, map[string]FieldValue{column: value}, nil)
+		assert.Contains(t, err.Error(), "overflows", column)
+	}
+	err = re.SetFieldValues(read, map[string]FieldValue{"int8type": "1"}, nil)
+	assert.Contains(t, err.Error(), "cannot set a string on a int8")
+}
+
+type PointerTypes struct {
+	Entity     `dosa:"primaryKey=ID"`
+	ID         int64
+	StringType *string
+	IntType    *int
+	TimeType   *time.Time
+	BlobType   *[]byte
+}
+
+func TestPointerTypes(t *testing.T) {
+	table, err := TableFromInstance(&PointerTypes{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnDefinition{
+		{Name: "id", Type: Int64},
+		{Name: "stringtype", Type: String, IsPointer: true},
+		{Name: "inttype", Type: Int64, IsPointer: true},
+		{Name: "timetype", Type: Timestamp, IsPointer: true},
+		{Name: "blobtype", Type: Blob, IsPointer: true},
+	}, table.Columns)
+
+	re := NewRegisteredEntity("test", "team.service", table)
+	s, i := "foo", 42
+	values, err := re.OnlyFieldValues(&PointerTypes{ID: 1, StringType: &s, IntType: &i}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]FieldValue{
+		"id":         int64(1),
+		"stringtype": "foo",
+		"inttype":    int64(42),
+		"timetype":   nil,
+		"blobtype":   nil,
+	}, values)
+
+	read := &PointerTypes{TimeType: &time.Time{}}
+	assert.NoError(t, re.SetFieldValues(read, values, nil))
+	assert.Equal(t, &PointerTypes{ID: 1, StringType: &s, IntType: &i}, read)
+
+	type NullableKey struct {
+		Entity `dosa:"primaryKey=ID"`
+		ID     *int64
+	}
+	_, err = TableFromInstance(&NullableKey{})
+	assert.Contains(t, err.Error(), `a nullable column cannot be used in key: "id"`)
+
+	type NullableCounter struct {
+		Entity `dosa:"primaryKey=ID"`
+		ID     int64
+		Hits   *CounterValue
+	}
+	_, err = TableFromInstance(&NullableCounter{})
+	assert.Contains(t, err.Error(), "Invalid type *dosa.CounterValue")
+
+	type NullableList struct {
+		Entity `dosa:"primaryKey=ID"`
+		ID     int64
+		Tags   *[]string
+	}
+	_, err = TableFromInstance(&NullableList{})
+	assert.Contains(t, err.Error(), "Invalid type *[]string")
+}
+
+type DecimalDateTypes struct {
+	Entity `dosa:"primaryKey=(ID, Day)"`
+	ID     int64
+	Day    Date
+	Price  Decimal
+	Due    *Date
+	Prices map[Date]Decimal
+}
+
+func TestDecimalDateTypes(t *testing.T) {
+	table, err := TableFromInstance(&DecimalDateTypes{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnDefinition{
+		{Name: "id", Type: Int64},
+		{Name: "day", Type: TDate},
+		{Name: "price", Type: TDecimal},
+		{Name: "due", Type: TDate, IsPointer: true},
+		{Name: "prices", Type: MapOf(TDate, TDecimal)},
+	}, table.Columns)
+}
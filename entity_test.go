@@ -73,6 +73,16 @@ func TestEntityDefinitionEnsureValid(t *testing.T) {
 	noClusteringKey := getValidEntityDefinition()
 	noClusteringKey.Key.ClusteringKeys = []*dosa.ClusteringKey{}
 
+	counterKey := getValidEntityDefinition()
+	counterKey.Columns[1].Type = dosa.Counter
+
+	mixedCounters := getValidEntityDefinition()
+	mixedCounters.Columns = append(mixedCounters.Columns, &dosa.ColumnDefinition{Name: "hits", Type: dosa.Counter})
+
+	onlyCounters := getValidEntityDefinition()
+	onlyCounters.Columns[2].Type = dosa.Counter
+	onlyCounters.Columns = append(onlyCounters.Columns, &dosa.ColumnDefinition{Name: "hits", Type: dosa.Counter})
+
 	data := []testData{
 		{
 			e:     nil,
@@ -169,6 +179,21 @@ func TestEntityDefinitionEnsureValid(t *testing.T) {
 			valid: false,
 			msg:   "nil clustering key",
 		},
+		{
+			e:     counterKey,
+			valid: false,
+			msg:   "a counter column cannot be used in key: \"bar\"",
+		},
+		{
+			e:     mixedCounters,
+			valid: false,
+			msg:   "counter column \"hits\" cannot be mixed with non-counter column \"qux\"",
+		},
+		{
+			e:     onlyCounters,
+			valid: true,
+			msg:   "counters are ok besides the key",
+		},
 	}
 
 	for _, entry := range data {
@@ -343,6 +368,41 @@ func TestEntityDefinitionIsCompatible(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSchemaVersion(t *testing.T) {
+	ed := getValidEntityDefinition()
+	other := getValidEntityDefinition()
+	other.Name = "other"
+	added := getValidEntityDefinition()
+	added.Columns = append(added.Columns, &dosa.ColumnDefinition{Name: "col", Type: dosa.Bool})
+	retyped := getValidEntityDefinition()
+	retyped.Columns[2].Type = dosa.String
+
+	v1 := &dosa.SchemaVersion{Version: 1, Entities: []*dosa.EntityDefinition{ed}}
+	v2 := &dosa.SchemaVersion{Version: 2, Entities: []*dosa.EntityDefinition{added, other}}
+	versions := []*dosa.SchemaVersion{v1, v2}
+
+	assert.Equal(t, other, v2.FindEntity("other"))
+	assert.Nil(t, v1.FindEntity("other"))
+
+	// entities of the version not provided are ignored, but not the other way around
+	assert.True(t, v2.Matches([]*dosa.EntityDefinition{other}))
+	assert.False(t, v2.Same([]*dosa.EntityDefinition{other}))
+	assert.True(t, v2.Same([]*dosa.EntityDefinition{other, added}))
+	assert.False(t, v1.Matches([]*dosa.EntityDefinition{ed, other}))
+	assert.False(t, v1.Matches([]*dosa.EntityDefinition{added}))
+
+	// the latest matching version is returned
+	assert.Equal(t, v1, dosa.MatchingSchemaVersion(versions, []*dosa.EntityDefinition{ed}))
+	assert.Equal(t, v2, dosa.MatchingSchemaVersion(versions, []*dosa.EntityDefinition{added}))
+	assert.Nil(t, dosa.MatchingSchemaVersion(versions, []*dosa.EntityDefinition{retyped}))
+
+	// existing entities may only gain columns
+	assert.NoError(t, v1.EnsureCompatible([]*dosa.EntityDefinition{added, other}))
+	err := v2.EnsureCompatible([]*dosa.EntityDefinition{ed})
+	assert.Contains(t, err.Error(), `entity "testentity" is not compatible with version 2`)
+	assert.Error(t, v1.EnsureCompatible([]*dosa.EntityDefinition{retyped}))
+}
+
 func TestEntityDefinition_FindColumnDefinition(t *testing.T) {
 	ed := getValidEntityDefinition()
 
@@ -23,11 +23,14 @@ package dosa
 import (
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -41,6 +44,7 @@ import (
 func FindEntities(paths, excludes []string) ([]*Table, []error, error) {
 	var entities []*Table
 	var warnings []error
+	importer := &structImporter{packages: make(map[string]map[string]packageStruct)}
 	for _, path := range paths {
 		fileSet := token.NewFileSet()
 		packages, err := parser.ParseDir(fileSet, path, func(fileInfo os.FileInfo) bool {
@@ -57,13 +61,19 @@ func FindEntities(paths, excludes []string) ([]*Table, []error, error) {
 		if err != nil {
 			return nil, nil, err
 		}
+		dir, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, err
+		}
 		erv := new(EntityRecordingVisitor)
 		for _, pkg := range packages { // go through all the packages
-			for _, file := range pkg.Files { // go through all the files
+			_, scopes := packageStructs(pkg, dir, importer)
+			for fileName, file := range pkg.Files { // go through all the files
 				packagePrefix, hasDosa := findDosaPackage(file)
 				//if erv.PackageName != "" { // skip packages that don't import 'dosa'
 				if hasDosa {
 					erv.PackagePrefix = packagePrefix
+					erv.scope = scopes[fileName]
 					for _, decl := range file.Decls { // go through all the declarations
 						ast.Walk(erv, decl)
 					}
@@ -101,6 +111,131 @@ func findDosaPackage(file *ast.File) (string, bool) {
 	return "", false
 }
 
+// packageStruct is a struct type declared at the top level of a package,
+// which entities can embed
+type packageStruct struct {
+	structType *ast.StructType
+	scope      *structScope
+}
+
+// structScope is what the fields of a struct can refer to: the dosa package
+// prefix and the imports of the file declaring the struct, and the structs
+// declared in its package
+type structScope struct {
+	packagePrefix string
+	// imports maps the names of the packages imported by the file to their paths
+	imports  map[string]string
+	structs  map[string]packageStruct
+	dir      string
+	importer *structImporter
+}
+
+// packageStructs finds the struct types declared at the top level of a
+// package, and returns them along with the scope of each of its files, by
+// file name
+func packageStructs(pkg *ast.Package, dir string, importer *structImporter) (map[string]packageStruct, map[string]*structScope) {
+	structs := make(map[string]packageStruct)
+	scopes := make(map[string]*structScope)
+	for fileName, file := range pkg.Files {
+		packagePrefix, _ := findDosaPackage(file)
+		scope := &structScope{
+			packagePrefix: packagePrefix,
+			imports:       fileImports(file),
+			structs:       structs,
+			dir:           dir,
+			importer:      importer,
+		}
+		scopes[fileName] = scope
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+					structs[typeSpec.Name.Name] = packageStruct{structType, scope}
+				}
+			}
+		}
+	}
+	return structs, scopes
+}
+
+// fileImports maps the names of the packages imported by a file to their
+// paths. Packages that are not renamed are assumed to be named after the last
+// element of their path.
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, impspec := range file.Imports {
+		importPath, err := strconv.Unquote(impspec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if impspec.Name != nil {
+			name = impspec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	return imports
+}
+
+// findStruct finds the struct type named by the kind of an embedded field,
+// either declared in the same package or in a package imported by the file
+func (s *structScope) findStruct(kind string) (packageStruct, bool, error) {
+	dot := strings.Index(kind, ".")
+	if dot < 0 {
+		embedded, ok := s.structs[kind]
+		return embedded, ok, nil
+	}
+	importPath, ok := s.imports[kind[:dot]]
+	if !ok {
+		return packageStruct{}, false, nil
+	}
+	structs, err := s.importer.structs(importPath, s.dir)
+	if err != nil {
+		return packageStruct{}, false, errors.Wrapf(err, "cannot find embedded struct %s", kind)
+	}
+	embedded, ok := structs[kind[dot+1:]]
+	return embedded, ok, nil
+}
+
+// structImporter parses the packages that declare the structs embedded in
+// entities, each of them once
+type structImporter struct {
+	// packages are the structs of each parsed package, by directory
+	packages map[string]map[string]packageStruct
+}
+
+// structs returns the struct types declared at the top level of an imported
+// package
+func (i *structImporter) structs(importPath, srcDir string) (map[string]packageStruct, error) {
+	buildPkg, err := build.Import(importPath, srcDir, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	if structs, ok := i.packages[buildPkg.Dir]; ok {
+		return structs, nil
+	}
+	fileSet := token.NewFileSet()
+	packages, err := parser.ParseDir(fileSet, buildPkg.Dir, func(fileInfo os.FileInfo) bool {
+		return !strings.HasSuffix(fileInfo.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	structs := make(map[string]packageStruct)
+	for _, pkg := range packages {
+		pkgStructs, _ := packageStructs(pkg, buildPkg.Dir, i)
+		for name, embedded := range pkgStructs {
+			structs[name] = embedded
+		}
+	}
+	i.packages[buildPkg.Dir] = structs
+	return structs, nil
+}
+
 // EntityRecordingVisitor is a visitor that records entities it finds
 // It also keeps track of all failed entities that pass the basic "looks like a DOSA object" test
 // (see isDosaEntity to understand that test)
@@ -108,6 +243,7 @@ type EntityRecordingVisitor struct {
 	Entities      []*Table
 	Warnings      []error
 	PackagePrefix string
+	scope         *structScope
 }
 
 // Visit records all the entities seen into the EntityRecordingVisitor structure
@@ -119,7 +255,7 @@ func (f *EntityRecordingVisitor) Visit(n ast.Node) ast.Visitor {
 		if structType, ok := n.Type.(*ast.StructType); ok {
 			// look for a Entity with a dosa annotation
 			if isDosaEntity(structType) {
-				table, err := tableFromStructType(n.Name.Name, structType, f.PackagePrefix)
+				table, err := tableFromStructType(n.Name.Name, structType, f.scope)
 				if err == nil {
 					f.Entities = append(f.Entities, table)
 				} else {
@@ -164,7 +300,7 @@ func isDosaEntity(structType *ast.StructType) bool {
 }
 
 // tableFromStructType takes an ast StructType and converts it into a Table object
-func tableFromStructType(structName string, structType *ast.StructType, packagePrefix string) (*Table, error) {
+func tableFromStructType(structName string, structType *ast.StructType, scope *structScope) (*Table, error) {
 	normalizedName, err := NormalizeName(structName)
 	if err != nil {
 		// TODO: This isn't correct, someone could override the name later
@@ -181,57 +317,17 @@ func tableFromStructType(structName string, structType *ast.StructType, packageP
 		FieldToCol: map[string]string{},
 	}
 	for _, field := range structType.Fields.List {
-		var dosaTag string
-		if field.Tag != nil {
-			entityTag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
-			dosaTag = strings.TrimSpace(entityTag.Get(dosaTagKey))
-		}
+		dosaTag := fieldDosaTag(field)
 		if dosaTag == "-" { // skip explicitly ignored fields
 			continue
 		}
-		var kind string
-		switch typeName := field.Type.(type) {
-		case *ast.Ident:
-			kind = typeName.Name
-			// not an Entity type, perhaps another primitive type
-		case *ast.ArrayType:
-			// only dosa allowed array type is []byte
-			if typeName, ok := typeName.Elt.(*ast.Ident); ok {
-				if typeName.Name == "byte" {
-					kind = "[]byte"
-				}
-			}
-		case *ast.SelectorExpr:
-			// only dosa allowed selector is time.Time
-			if innerName, ok := typeName.X.(*ast.Ident); ok {
-				kind = innerName.Name + "." + typeName.Sel.Name
-			}
-		}
-		if kind == packagePrefix+"."+entityName || (packagePrefix == "" && kind == entityName) {
+		if isEntityKind(typeKind(field.Type), scope.packagePrefix) {
 			var err error
 			if t.EntityDefinition.Name, t.Key, err = parseEntityTag(structName, dosaTag); err != nil {
 				return nil, err
 			}
-		} else {
-			for _, fieldName := range field.Names {
-				name := fieldName.Name
-				firstRune, _ := utf8.DecodeRuneInString(name)
-				if unicode.IsLower(firstRune) {
-					// skip unexported fields
-					continue
-				}
-				typ := stringToDosaType(kind, packagePrefix)
-				if typ == Invalid {
-					return nil, fmt.Errorf("Column %q has invalid type %q", name, kind)
-				}
-				cd, err := parseField(typ, name, dosaTag)
-				if err != nil {
-					return nil, errors.Wrapf(err, "column %q", name)
-				}
-				t.Columns = append(t.Columns, cd)
-				t.ColToField[cd.Name] = name
-				t.FieldToCol[name] = cd.Name
-			}
+		} else if err := t.addASTField(field, dosaTag, "", scope); err != nil {
+			return nil, err
 		}
 	}
 
@@ -246,11 +342,168 @@ func tableFromStructType(structName string, structType *ast.StructType, packageP
 	return t, nil
 }
 
+// addASTField adds the columns of an ast field to the table. Anonymous
+// embedded structs declared in the same package or in an imported one are
+// flattened into columns, whose names start with the prefix of the embedded
+// field's tag.
+func (t *Table) addASTField(field *ast.Field, dosaTag, prefix string, scope *structScope) error {
+	kind := typeKind(field.Type)
+	var names []string
+	for _, fieldName := range field.Names {
+		names = append(names, fieldName.Name)
+	}
+	if len(field.Names) == 0 {
+		if kind == "" {
+			return errors.New("embedded fields must be structs or have a DOSA type")
+		}
+		// embedded fields are named after their type
+		name := strings.TrimPrefix(kind[strings.LastIndex(kind, ".")+1:], "*")
+		if stringToDosaType(kind, scope.packagePrefix) == Invalid {
+			embedded, ok, err := scope.findStruct(kind)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if !ast.IsExported(name) {
+					return nil
+				}
+				return t.addEmbeddedASTFields(name, embedded, dosaTag, prefix)
+			}
+		}
+		names = []string{name}
+	}
+
+	for _, name := range names {
+		firstRune, _ := utf8.DecodeRuneInString(name)
+		if unicode.IsLower(firstRune) {
+			// skip unexported fields
+			continue
+		}
+		cd, err := parseField(stringToDosaType(kind, scope.packagePrefix), name, dosaTag)
+		if err != nil {
+			return errors.Wrapf(err, "column %q", name)
+		}
+		if cd.Type == Invalid {
+			return fmt.Errorf("Column %q has invalid type %q", name, kind)
+		}
+		cd.IsPointer = strings.HasPrefix(kind, "*")
+		if prefix != "" {
+			if cd.Name, err = NormalizeName(prefix + cd.Name); err != nil {
+				return errors.Wrapf(err, "field %s has an invalid prefixed column name", name)
+			}
+		}
+		if err := t.addColumn(cd, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addEmbeddedASTFields adds the columns of the fields of an embedded struct
+func (t *Table) addEmbeddedASTFields(name string, embedded packageStruct, dosaTag, prefix string) error {
+	embeddedPrefix, err := parseEmbeddedTag(name, dosaTag)
+	if err != nil {
+		return err
+	}
+	for _, field := range embedded.structType.Fields.List {
+		fieldTag := fieldDosaTag(field)
+		if fieldTag == "-" { // skip explicitly ignored fields
+			continue
+		}
+		if isEntityKind(typeKind(field.Type), embedded.scope.packagePrefix) {
+			return errors.Errorf("embedded struct %s cannot declare a dosa.Entity", name)
+		}
+		if err := t.addASTField(field, fieldTag, prefix+embeddedPrefix, embedded.scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldDosaTag returns the trimmed dosa tag of an ast field
+func fieldDosaTag(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	entityTag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+	return strings.TrimSpace(entityTag.Get(dosaTagKey))
+}
+
+// isEntityKind checks if a field type is dosa.Entity
+func isEntityKind(kind, packagePrefix string) bool {
+	return kind == packagePrefix+"."+entityName || (packagePrefix == "" && kind == entityName)
+}
+
+// typeKind returns the name of a field type, such as "int64", "*time.Time",
+// "[]string" or "map[string]int64", or an empty string for unsupported types
+func typeKind(expr ast.Expr) string {
+	switch typeName := expr.(type) {
+	case *ast.Ident:
+		return typeName.Name
+	case *ast.ArrayType:
+		// arrays are not supported, only slices
+		if typeName.Len != nil {
+			return ""
+		}
+		if elem := typeKind(typeName.Elt); elem != "" {
+			return "[]" + elem
+		}
+	case *ast.MapType:
+		key := typeKind(typeName.Key)
+		value := typeKind(typeName.Value)
+		if key != "" && value != "" {
+			return "map[" + key + "]" + value
+		}
+	case *ast.SelectorExpr:
+		if innerName, ok := typeName.X.(*ast.Ident); ok {
+			return innerName.Name + "." + typeName.Sel.Name
+		}
+	case *ast.StarExpr:
+		if elem := typeKind(typeName.X); elem != "" {
+			return "*" + elem
+		}
+	}
+	return ""
+}
+
+// typeAliases are the predeclared aliases of type names, which reflection
+// does not tell apart from the types they name
+var typeAliases = map[string]string{
+	"byte": "uint8",
+	"rune": "int32",
+}
+
 func stringToDosaType(inType string, packagePrefix string) Type {
+	if alias, ok := typeAliases[inType]; ok {
+		inType = alias
+	}
+	// like typify, only fields can use the numeric types that are widened,
+	// not the elements of collections
+	for f, t := range numericTypes {
+		if inType == f.String() {
+			return t
+		}
+	}
+	if strings.HasPrefix(inType, "*") {
+		// like typify, pointers to the types of other columns are nullable columns
+		t := stringToDosaType(inType[len("*"):], packagePrefix)
+		if t == Counter || t.IsCollection() || strings.HasPrefix(inType, "**") {
+			return Invalid
+		}
+		return t
+	}
+	return storageTypeFromString(inType, packagePrefix)
+}
+
+// storageTypeFromString returns the DOSA type of a type name, like storageType
+func storageTypeFromString(inType string, packagePrefix string) Type {
+	if alias, ok := typeAliases[inType]; ok {
+		inType = alias
+	}
 	switch inType {
 	case "string":
 		return String
-	case "[]byte":
+	case "[]byte", "[]uint8":
 		return Blob
 	case "bool":
 		return Bool
@@ -264,7 +517,30 @@ func stringToDosaType(inType string, packagePrefix string) Type {
 		return Timestamp
 	case packagePrefix + ".UUID":
 		return TUUID
-	default:
-		return Invalid
+	case packagePrefix + ".CounterValue":
+		return Counter
+	case packagePrefix + ".Decimal":
+		return TDecimal
+	case packagePrefix + ".Date":
+		return TDate
+	}
+
+	var t Type
+	switch {
+	case strings.HasPrefix(inType, "[]"):
+		t = ListOf(storageTypeFromString(inType[len("[]"):], packagePrefix))
+	case strings.HasPrefix(inType, "map[") && strings.Contains(inType, "]"):
+		// keys cannot be slices or maps, so the first ] closes the key type
+		end := strings.Index(inType, "]")
+		key := storageTypeFromString(inType[len("map["):end], packagePrefix)
+		if value := inType[end+1:]; value == packagePrefix+".Set" {
+			t = SetOf(key)
+		} else {
+			t = MapOf(key, storageTypeFromString(value, packagePrefix))
+		}
+	}
+	if t.isValid() {
+		return t
 	}
+	return Invalid
 }
@@ -54,8 +54,8 @@ func TestNonExistentDirectory(t *testing.T) {
 
 func TestParser(t *testing.T) {
 	entities, errs, err := FindEntities([]string{"."}, []string{})
-	assert.Equal(t, 13, len(entities), fmt.Sprintf("%s", entities))
-	assert.Equal(t, 14, len(errs), fmt.Sprintf("%v", errs))
+	assert.Equal(t, 22, len(entities), fmt.Sprintf("%s", entities))
+	assert.Equal(t, 28, len(errs), fmt.Sprintf("%v", errs))
 	assert.Nil(t, err)
 
 	for _, entity := range entities {
@@ -79,14 +79,44 @@ func TestParser(t *testing.T) {
 			e, _ = TableFromInstance(&UnexportedFieldType{})
 		case "ignoretagtype":
 			e, _ = TableFromInstance(&IgnoreTagType{})
+		case "pointertypes":
+			e, _ = TableFromInstance(&PointerTypes{})
+		case "clienttestnullable": // skip, same as clienttestentity1
+			continue
+		case "numerictypes":
+			e, _ = TableFromInstance(&NumericTypes{})
+		case "aliastypes":
+			e, _ = TableFromInstance(&AliasTypes{})
+			assert.Equal(t, []*ColumnDefinition{
+				{Name: "bytetype", Type: Int32},
+				{Name: "runetype", Type: Int32},
+				{Name: "byteptrtype", Type: Int32, IsPointer: true},
+				{Name: "runelisttype", Type: ListOf(Int32)},
+				{Name: "uint8list", Type: Blob},
+				{Name: "runemaptype", Type: MapOf(Int32, String)},
+			}, e.Columns)
 		case "badcolnamebutrenamed":
 			e, _ = TableFromInstance(&BadColNameButRenamed{})
+		case "embeddedfields":
+			e, _ = TableFromInstance(&EmbeddedFields{})
+		case "embeddableentity":
+			e, _ = TableFromInstance(&EmbeddableEntity{})
 		case "clienttestentity1": // skip, see https://jira.uberinternal.com/browse/DOSA-788
 			continue
 		case "clienttestentity2": // skip, same as above
 			continue
 		case "registrytestvalid": // skip, same as above
 			continue
+		case "clienttestcounters": // skip, same as above
+			continue
+		case "clienttestmarshalers": // custom types are resolved from their type tags
+			assert.Equal(t, []*ColumnDefinition{
+				{Name: "id", Type: Int64},
+				{Name: "labels", Type: String},
+			}, entity.Columns)
+			continue
+		case "mismatched": // skip, the finder cannot check the types of custom types
+			continue
 		default:
 			t.Errorf("entity %s not expected", entity.Name)
 			continue
@@ -95,6 +125,28 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestStringToDosaType(t *testing.T) {
+	assert.Equal(t, Counter, stringToDosaType("dosa.CounterValue", "dosa"))
+	assert.Equal(t, Counter, stringToDosaType("renamed.CounterValue", "renamed"))
+	assert.Equal(t, Invalid, stringToDosaType("CounterValue", "dosa"))
+	assert.Equal(t, Int64, stringToDosaType("int", "dosa"))
+	assert.Equal(t, Int32, stringToDosaType("uint16", "dosa"))
+	assert.Equal(t, Double, stringToDosaType("float32", "dosa"))
+	assert.Equal(t, TUUID, stringToDosaType("*dosa.UUID", "dosa"))
+	assert.Equal(t, Int64, stringToDosaType("*int", "dosa"))
+	assert.Equal(t, Invalid, stringToDosaType("**string", "dosa"))
+	assert.Equal(t, Invalid, stringToDosaType("*[]string", "dosa"))
+	// the elements of collections are not widened
+	assert.Equal(t, Invalid, stringToDosaType("[]int", "dosa"))
+	// byte and rune are uint8 and int32
+	assert.Equal(t, Int32, stringToDosaType("byte", "dosa"))
+	assert.Equal(t, Int32, stringToDosaType("*rune", "dosa"))
+	assert.Equal(t, ListOf(Int32), stringToDosaType("[]rune", "dosa"))
+	assert.Equal(t, Invalid, stringToDosaType("[]int8", "dosa"))
+	assert.Equal(t, Invalid, stringToDosaType("map[string]byte", "dosa"))
+	assert.Equal(t, Blob, stringToDosaType("[]uint8", "dosa"))
+}
+
 func TestExclusion(t *testing.T) {
 	entities, errs, err := FindEntities([]string{"."}, []string{"*_test.go"})
 	assert.Equal(t, 0, len(entities))
@@ -108,6 +160,62 @@ func TestFindEntitiesInOtherPkg(t *testing.T) {
 	assert.Empty(t, warnings)
 }
 
+func TestFindEntitiesEmbeddingOtherPkg(t *testing.T) {
+	// the packages must be importable, so they cannot be hidden in a dot directory
+	const tmpdir = "testgenembedded"
+	defer os.RemoveAll(tmpdir)
+	for _, dir := range []string{tmpdir + "/audit", tmpdir + "/entity"} {
+		if err := os.MkdirAll(dir, 0770); err != nil {
+			t.Fatalf("can't create %s: %s", dir, err)
+		}
+	}
+	files := map[string]string{
+		tmpdir + "/audit/audit.go": `package audit
+import "time"
+type Fields struct {
+	CreatedBy string
+	CreatedAt time.Time
+}
+`,
+		tmpdir + "/entity/entity.go": `package entity
+import (
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/` + tmpdir + `/audit"
+	missing "github.com/uber-go/dosa/` + tmpdir + `/missing"
+)
+type Audited struct {
+	dosa.Entity ` + "`dosa:\"primaryKey=ID\"`" + `
+	ID int64
+	audit.Fields ` + "`dosa:\"prefix=audit_\"`" + `
+}
+type Broken struct {
+	dosa.Entity ` + "`dosa:\"primaryKey=ID\"`" + `
+	ID int64
+	missing.Fields
+}
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("can't create %s: %s", name, err)
+		}
+	}
+
+	entities, warnings, err := FindEntities([]string{tmpdir + "/entity"}, []string{})
+	assert.NoError(t, err)
+	if assert.Len(t, entities, 1) {
+		assert.Equal(t, "audited", entities[0].Name)
+		assert.Equal(t, map[string]string{
+			"id":              "ID",
+			"audit_createdby": "CreatedBy",
+			"audit_createdat": "CreatedAt",
+		}, entities[0].ColToField)
+	}
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0].Error(), "cannot find embedded struct missing.Fields")
+	}
+}
+
 func BenchmarkFinder(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		FindEntities([]string{"."}, []string{})
@@ -0,0 +1,193 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gateway
+
+import (
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/yarpc"
+	dosarpc "github.com/uber/dosa-idl/.gen/dosa"
+)
+
+// entityDefinitionFromThrift converts and validates an entity definition sent by a client
+func entityDefinitionFromThrift(red *dosarpc.EntityDefinition) (ed *dosa.EntityDefinition, err error) {
+	// the conversion helpers panic on missing fields or unknown types, which
+	// must not take down the server
+	defer func() {
+		if r := recover(); r != nil {
+			ed, err = nil, errors.Errorf("malformed entity definition: %v", r)
+		}
+	}()
+	if red == nil {
+		return nil, errors.New("nil entity definition")
+	}
+	ed = yarpc.FromThriftToEntityDefinition(red)
+	if err := ed.EnsureValid(); err != nil {
+		return nil, err
+	}
+	return ed, nil
+}
+
+// entityDefinitionsFromThrift converts and validates a set of entity definitions
+func entityDefinitionsFromThrift(reds []*dosarpc.EntityDefinition) ([]*dosa.EntityDefinition, error) {
+	if len(reds) == 0 {
+		return nil, errors.New("no entity definitions provided")
+	}
+	eds := make([]*dosa.EntityDefinition, len(reds))
+	seen := make(map[string]struct{}, len(reds))
+	for i, red := range reds {
+		ed, err := entityDefinitionFromThrift(red)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[ed.Name]; ok {
+			return nil, errors.Errorf("duplicate entity definition %q", ed.Name)
+		}
+		seen[ed.Name] = struct{}{}
+		eds[i] = ed
+	}
+	return eds, nil
+}
+
+// decodeFieldValues converts values from the wire using the column types of the entity
+func decodeFieldValues(ed *dosa.EntityDefinition, fields dosarpc.FieldValueMap) (map[string]dosa.FieldValue, error) {
+	types := ed.ColumnTypes()
+	values := make(map[string]dosa.FieldValue, len(fields))
+	for name, value := range fields {
+		typ, ok := types[name]
+		if !ok {
+			return nil, errors.Errorf("column %q not found in entity %q", name, ed.Name)
+		}
+		v, err := decodeFieldValue(typ, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q", name)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// decodeFieldValue converts a single value from the wire
+func decodeFieldValue(typ dosa.Type, value *dosarpc.Value) (v dosa.FieldValue, err error) {
+	// RawValueAsInterface dereferences the field matching the type, so a
+	// value of the wrong type panics
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = nil, errors.Errorf("value does not match type %v", typ)
+		}
+	}()
+	if value == nil {
+		return nil, errors.New("missing value")
+	}
+	if value.ElemValue == nil {
+		// null values are sent without a member of the RawValue union
+		return nil, nil
+	}
+	return yarpc.RawValueAsInterface(*value.ElemValue, typ), nil
+}
+
+// encodeFieldValues converts values read from the connector to their wire format
+func encodeFieldValues(values map[string]dosa.FieldValue) (dosarpc.FieldValueMap, error) {
+	fields := make(dosarpc.FieldValueMap, len(values))
+	for name, value := range values {
+		if value == nil {
+			fields[name] = &dosarpc.Value{}
+			continue
+		}
+		rv, err := yarpc.RawValueFromInterface(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error encoding field %q", name)
+		}
+		fields[name] = &dosarpc.Value{ElemValue: rv}
+	}
+	return fields, nil
+}
+
+// encodeMultiValues converts a list of rows to their wire format
+func encodeMultiValues(multiValues []map[string]dosa.FieldValue) ([]dosarpc.FieldValueMap, error) {
+	entities := make([]dosarpc.FieldValueMap, len(multiValues))
+	for i, values := range multiValues {
+		fields, err := encodeFieldValues(values)
+		if err != nil {
+			return nil, err
+		}
+		entities[i] = fields
+	}
+	return entities, nil
+}
+
+// decodeConditions converts range conditions from the wire
+func decodeConditions(ed *dosa.EntityDefinition, rcs []*dosarpc.Condition) (map[string][]*dosa.Condition, error) {
+	types := ed.ColumnTypes()
+	conditions := make(map[string][]*dosa.Condition)
+	for _, rc := range rcs {
+		if rc == nil || rc.Op == nil || rc.Field == nil || rc.Field.Name == nil {
+			return nil, errors.New("malformed condition")
+		}
+		name := *rc.Field.Name
+		typ, ok := types[name]
+		if !ok {
+			return nil, errors.Errorf("column %q not found in entity %q", name, ed.Name)
+		}
+		op, err := decodeOperator(*rc.Op)
+		if err != nil {
+			return nil, err
+		}
+		v, err := decodeFieldValue(typ, rc.Field.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "condition on column %q", name)
+		}
+		if v == nil {
+			return nil, errors.Errorf("condition on column %q: null values are not supported", name)
+		}
+		conditions[name] = append(conditions[name], &dosa.Condition{Op: op, Value: v})
+	}
+	return conditions, nil
+}
+
+// decodeOperator is the inverse of the operator encoding done by the yarpc connector
+func decodeOperator(op dosarpc.Operator) (dosa.Operator, error) {
+	switch op {
+	case dosarpc.OperatorEq:
+		return dosa.Eq, nil
+	case dosarpc.OperatorLt:
+		return dosa.Lt, nil
+	case dosarpc.OperatorLtOrEq:
+		return dosa.LtOrEq, nil
+	case dosarpc.OperatorGt:
+		return dosa.Gt, nil
+	case dosarpc.OperatorGtOrEq:
+		return dosa.GtOrEq, nil
+	}
+	return 0, errors.Errorf("invalid operator %v", op)
+}
+
+// fieldsToRead converts the set of fields from the wire; nil means all fields
+func fieldsToRead(fields map[string]struct{}) []string {
+	if fields == nil {
+		return nil
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,502 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package gateway implements the dosa-idl RPC service on top of any
+// dosa.Connector. It keeps track of scopes and schema versions itself, so a
+// simple connector such as the in-memory one can be used as a local, hermetic
+// replacement for a real gateway during development and integration tests.
+package gateway
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/base"
+	dosarpc "github.com/uber/dosa-idl/.gen/dosa"
+	"github.com/uber/dosa-idl/.gen/dosa/dosaserver"
+)
+
+const (
+	errCodeNotFound      int32 = 404
+	errCodeAlreadyExists int32 = 409
+)
+
+// Handler implements the dosa-idl service by forwarding requests to a connector
+type Handler struct {
+	conn     dosa.Connector
+	registry *registry
+	// ddl serializes scope and schema changes
+	ddl sync.Mutex
+}
+
+var _ dosaserver.Interface = (*Handler)(nil)
+
+// NewHandler creates a handler serving the data stored by the given connector
+func NewHandler(conn dosa.Connector) *Handler {
+	return &Handler{
+		conn:     conn,
+		registry: newRegistry(),
+	}
+}
+
+// CreateIfNotExists creates a row, failing if it already exists
+func (h *Handler) CreateIfNotExists(ctx context.Context, request *dosarpc.CreateRequest) error {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return err
+	}
+	values, err := decodeFieldValues(ei.Def, request.EntityValues)
+	if err != nil {
+		return badRequest(err)
+	}
+	return rpcError(h.conn.CreateIfNotExists(ctx, ei, values))
+}
+
+// Upsert creates or updates a row
+func (h *Handler) Upsert(ctx context.Context, request *dosarpc.UpsertRequest) error {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return err
+	}
+	values, err := decodeFieldValues(ei.Def, request.EntityValues)
+	if err != nil {
+		return badRequest(err)
+	}
+	return rpcError(h.conn.Upsert(ctx, ei, values))
+}
+
+// Read fetches a row by primary key
+func (h *Handler) Read(ctx context.Context, request *dosarpc.ReadRequest) (*dosarpc.ReadResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := decodeFieldValues(ei.Def, request.KeyValues)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	values, err := h.conn.Read(ctx, ei, keys, fieldsToRead(request.FieldsToRead))
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	fields, err := encodeFieldValues(values)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &dosarpc.ReadResponse{EntityValues: fields}, nil
+}
+
+// MultiRead fetches several rows by primary key
+func (h *Handler) MultiRead(ctx context.Context, request *dosarpc.MultiReadRequest) (*dosarpc.MultiReadResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := decodeMultiValues(ei.Def, request.KeyValues)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	results, err := h.conn.MultiRead(ctx, ei, keys, fieldsToRead(request.FieldsToRead))
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	response := &dosarpc.MultiReadResponse{Results: make([]*dosarpc.EntityOrError, len(results))}
+	for i, result := range results {
+		if result.Error != nil {
+			response.Results[i] = &dosarpc.EntityOrError{Error: rpcResultError(result.Error)}
+			continue
+		}
+		fields, err := encodeFieldValues(result.Values)
+		if err != nil {
+			response.Results[i] = &dosarpc.EntityOrError{Error: rpcResultError(err)}
+			continue
+		}
+		response.Results[i] = &dosarpc.EntityOrError{EntityValues: fields}
+	}
+	return response, nil
+}
+
+// MultiUpsert creates or updates several rows
+func (h *Handler) MultiUpsert(ctx context.Context, request *dosarpc.MultiUpsertRequest) (*dosarpc.MultiUpsertResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	multiValues, err := decodeMultiValues(ei.Def, request.Entities)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	errs, err := h.conn.MultiUpsert(ctx, ei, multiValues)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &dosarpc.MultiUpsertResponse{Errors: rpcResultErrors(errs)}, nil
+}
+
+// Remove deletes a row by primary key
+func (h *Handler) Remove(ctx context.Context, request *dosarpc.RemoveRequest) error {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return err
+	}
+	keys, err := decodeFieldValues(ei.Def, request.KeyValues)
+	if err != nil {
+		return badRequest(err)
+	}
+	return rpcError(h.conn.Remove(ctx, ei, keys))
+}
+
+// MultiRemove deletes several rows by primary key
+func (h *Handler) MultiRemove(ctx context.Context, request *dosarpc.MultiRemoveRequest) (*dosarpc.MultiRemoveResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	multiKeys, err := decodeMultiValues(ei.Def, request.KeyValues)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	errs, err := h.conn.MultiRemove(ctx, ei, multiKeys)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &dosarpc.MultiRemoveResponse{Errors: rpcResultErrors(errs)}, nil
+}
+
+// Range fetches the rows of a partition matching a set of conditions
+func (h *Handler) Range(ctx context.Context, request *dosarpc.RangeRequest) (*dosarpc.RangeResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := decodeConditions(ei.Def, request.Conditions)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	if err := dosa.EnsureValidRangeConditions(ei.Def, conditions, func(name string) string { return name }); err != nil {
+		return nil, badRequest(err)
+	}
+	multiValues, token, err := h.conn.Range(ctx, ei, conditions, fieldsToRead(request.FieldsToRead), stringOrEmpty(request.Token), limitOrZero(request.Limit))
+	entities, token, err := pageResponse(multiValues, token, err)
+	if err != nil {
+		return nil, err
+	}
+	return &dosarpc.RangeResponse{Entities: entities, NextToken: &token}, nil
+}
+
+// Search fetches the rows with a given value for a searchable column
+func (h *Handler) Search(ctx context.Context, request *dosarpc.SearchRequest) (*dosarpc.SearchResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if request.SearchBy == nil || request.SearchBy.Name == nil {
+		return nil, badRequest(errors.New("missing search field"))
+	}
+	name := *request.SearchBy.Name
+	column := ei.Def.FindColumnDefinition(name)
+	if column == nil {
+		return nil, badRequest(errors.Errorf("column %q not found in entity %q", name, ei.Def.Name))
+	}
+	value, err := decodeFieldValue(column.Type, request.SearchBy.Value)
+	if err != nil {
+		return nil, badRequest(errors.Wrapf(err, "column %q", name))
+	}
+	if value == nil {
+		return nil, badRequest(errors.Errorf("column %q: null values are not supported", name))
+	}
+	pair := dosa.FieldNameValuePair{Name: name, Value: value}
+	multiValues, token, err := h.conn.Search(ctx, ei, pair, fieldsToRead(request.FieldsToRead), stringOrEmpty(request.Token), limitOrZero(request.Limit))
+	entities, token, err := pageResponse(multiValues, token, err)
+	if err != nil {
+		return nil, err
+	}
+	return &dosarpc.SearchResponse{Entities: entities, NextToken: &token}, nil
+}
+
+// Scan fetches all the rows of an entity
+func (h *Handler) Scan(ctx context.Context, request *dosarpc.ScanRequest) (*dosarpc.ScanResponse, error) {
+	ei, err := h.entityInfo(request.Ref)
+	if err != nil {
+		return nil, err
+	}
+	multiValues, token, err := h.conn.Scan(ctx, ei, fieldsToRead(request.FieldsToRead), stringOrEmpty(request.Token), limitOrZero(request.Limit))
+	entities, token, err := pageResponse(multiValues, token, err)
+	if err != nil {
+		return nil, err
+	}
+	return &dosarpc.ScanResponse{Entities: entities, NextToken: &token}, nil
+}
+
+// CheckSchema returns the latest version registered with exactly the provided entity definitions
+func (h *Handler) CheckSchema(ctx context.Context, request *dosarpc.CheckSchemaRequest) (*dosarpc.CheckSchemaResponse, error) {
+	scope, namePrefix := stringOrEmpty(request.Scope), stringOrEmpty(request.NamePrefix)
+	eds, err := entityDefinitionsFromThrift(request.EntityDefs)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	version, err := h.registry.check(scope, namePrefix, eds)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &dosarpc.CheckSchemaResponse{Version: &version}, nil
+}
+
+// UpsertSchema registers a new version of the schema, provided it is compatible
+// with the latest one. Upserting the latest schema again does not create a new version.
+func (h *Handler) UpsertSchema(ctx context.Context, request *dosarpc.UpsertSchemaRequest) (*dosarpc.UpsertSchemaResponse, error) {
+	scope, namePrefix := stringOrEmpty(request.Scope), stringOrEmpty(request.NamePrefix)
+	if _, err := dosa.ToFQN(namePrefix); err != nil {
+		return nil, badRequest(errors.Wrapf(err, "invalid name prefix %q", namePrefix))
+	}
+	eds, err := entityDefinitionsFromThrift(request.EntityDefs)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	h.ddl.Lock()
+	defer h.ddl.Unlock()
+
+	latest, err := h.registry.latest(scope, namePrefix)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	if latest != nil {
+		if latest.Same(eds) {
+			return schemaResponse(latest.Version), nil
+		}
+		if err := latest.EnsureCompatible(eds); err != nil {
+			return nil, badRequest(err)
+		}
+	}
+
+	var connectorVersion int32
+	status, err := h.conn.UpsertSchema(ctx, scope, namePrefix, eds)
+	switch {
+	case err == nil:
+		connectorVersion = status.Version
+	case unsupported(err):
+		// the connector does no schema management, the gateway version will do
+		connectorVersion = dosa.InvalidVersion
+	default:
+		return nil, rpcError(err)
+	}
+	sv, err := h.registry.add(scope, namePrefix, eds, connectorVersion)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return schemaResponse(sv.Version), nil
+}
+
+// CheckSchemaStatus returns the status of a schema version
+func (h *Handler) CheckSchemaStatus(ctx context.Context, request *dosarpc.CheckSchemaStatusRequest) (*dosarpc.CheckSchemaStatusResponse, error) {
+	if request.Version == nil {
+		return nil, badRequest(errors.New("missing version"))
+	}
+	sv, err := h.registry.find(stringOrEmpty(request.Scope), stringOrEmpty(request.NamePrefix), *request.Version)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	// the gateway applies schema changes synchronously
+	version, status := sv.Version, dosa.SchemaStatusCompleted
+	return &dosarpc.CheckSchemaStatusResponse{Version: &version, Status: &status}, nil
+}
+
+// CreateScope creates a new scope
+func (h *Handler) CreateScope(ctx context.Context, request *dosarpc.CreateScopeRequest) error {
+	scope := stringOrEmpty(request.Name)
+	if err := dosa.IsValidName(scope); err != nil {
+		return badRequest(err)
+	}
+
+	h.ddl.Lock()
+	defer h.ddl.Unlock()
+
+	if h.registry.scopeExists(scope) {
+		return rpcError(errors.Wrapf(&dosa.ErrAlreadyExists{}, "scope %q", scope))
+	}
+	if err := h.conn.CreateScope(ctx, scope); err != nil && !unsupported(err) {
+		return rpcError(err)
+	}
+	return rpcError(h.registry.createScope(scope))
+}
+
+// TruncateScope removes all the data of a scope, keeping its schema
+func (h *Handler) TruncateScope(ctx context.Context, request *dosarpc.TruncateScopeRequest) error {
+	scope := stringOrEmpty(request.Name)
+
+	h.ddl.Lock()
+	defer h.ddl.Unlock()
+
+	if !h.registry.scopeExists(scope) {
+		return rpcError(errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope))
+	}
+	if err := h.conn.TruncateScope(ctx, scope); err != nil && !unsupported(err) {
+		return rpcError(err)
+	}
+	return nil
+}
+
+// DropScope removes a scope along with its schema and data
+func (h *Handler) DropScope(ctx context.Context, request *dosarpc.DropScopeRequest) error {
+	scope := stringOrEmpty(request.Name)
+
+	h.ddl.Lock()
+	defer h.ddl.Unlock()
+
+	if !h.registry.scopeExists(scope) {
+		return rpcError(errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope))
+	}
+	if err := h.conn.DropScope(ctx, scope); err != nil && !unsupported(err) {
+		return rpcError(err)
+	}
+	return rpcError(h.registry.dropScope(scope))
+}
+
+// entityInfo resolves a schema reference against the registered schema versions
+func (h *Handler) entityInfo(ref *dosarpc.SchemaRef) (*dosa.EntityInfo, error) {
+	if ref == nil || ref.Scope == nil || ref.NamePrefix == nil || ref.EntityName == nil || ref.Version == nil {
+		return nil, badRequest(errors.New("incomplete schema reference"))
+	}
+	sv, err := h.registry.find(*ref.Scope, *ref.NamePrefix, *ref.Version)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	ed := sv.FindEntity(*ref.EntityName)
+	if ed == nil {
+		return nil, rpcError(errors.Wrapf(&dosa.ErrNotFound{}, "entity %q in version %d", *ref.EntityName, *ref.Version))
+	}
+	return &dosa.EntityInfo{
+		Ref: &dosa.SchemaRef{
+			Scope:      *ref.Scope,
+			NamePrefix: *ref.NamePrefix,
+			EntityName: *ref.EntityName,
+			Version:    sv.connectorVersion,
+		},
+		Def: ed,
+	}, nil
+}
+
+// decodeMultiValues converts a list of rows or keys from the wire
+func decodeMultiValues(ed *dosa.EntityDefinition, multiFields []dosarpc.FieldValueMap) ([]map[string]dosa.FieldValue, error) {
+	multiValues := make([]map[string]dosa.FieldValue, len(multiFields))
+	for i, fields := range multiFields {
+		values, err := decodeFieldValues(ed, fields)
+		if err != nil {
+			return nil, errors.Wrapf(err, "entity %d", i)
+		}
+		multiValues[i] = values
+	}
+	return multiValues, nil
+}
+
+// pageResponse encodes one page of results. Connectors may report an empty
+// page as not found, which is not an error for the RPC.
+func pageResponse(multiValues []map[string]dosa.FieldValue, token string, err error) ([]dosarpc.FieldValueMap, string, error) {
+	if dosa.ErrorIsNotFound(err) {
+		return []dosarpc.FieldValueMap{}, "", nil
+	}
+	if err != nil {
+		return nil, "", rpcError(err)
+	}
+	entities, err := encodeMultiValues(multiValues)
+	if err != nil {
+		return nil, "", rpcError(err)
+	}
+	return entities, token, nil
+}
+
+func schemaResponse(version int32) *dosarpc.UpsertSchemaResponse {
+	status := dosa.SchemaStatusCompleted
+	return &dosarpc.UpsertSchemaResponse{Version: &version, Status: &status}
+}
+
+// unsupported returns true if the connector does not implement an operation
+func unsupported(err error) bool {
+	_, ok := errors.Cause(err).(base.ErrNoMoreConnector)
+	return ok
+}
+
+// badRequest reports an invalid request to the client
+func badRequest(err error) error {
+	msg := err.Error()
+	return &dosarpc.BadRequestError{Msg: &msg}
+}
+
+// rpcError converts a connector error to the error expected by the client:
+// not found and already exists errors are bad requests with a well known code
+func rpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case dosa.ErrorIsNotFound(err):
+		code := errCodeNotFound
+		return &dosarpc.BadRequestError{Msg: &msg, ErrorCode: &code}
+	case dosa.ErrorIsAlreadyExists(err):
+		code := errCodeAlreadyExists
+		return &dosarpc.BadRequestError{Msg: &msg, ErrorCode: &code}
+	}
+	return &dosarpc.InternalServerError{Msg: &msg}
+}
+
+// rpcResultError converts the error for a single row of a multi operation
+func rpcResultError(err error) *dosarpc.Error {
+	msg := err.Error()
+	rpcErr := &dosarpc.Error{Msg: &msg}
+	switch {
+	case dosa.ErrorIsNotFound(err):
+		code := errCodeNotFound
+		rpcErr.ErrCode = &code
+	case dosa.ErrorIsAlreadyExists(err):
+		code := errCodeAlreadyExists
+		rpcErr.ErrCode = &code
+	}
+	return rpcErr
+}
+
+// rpcResultErrors converts the per-row errors of a multi operation; rows
+// without error are reported as nil
+func rpcResultErrors(errs []error) []*dosarpc.Error {
+	rpcErrs := make([]*dosarpc.Error, len(errs))
+	for i, err := range errs {
+		if err != nil {
+			rpcErrs[i] = rpcResultError(err)
+		}
+	}
+	return rpcErrs
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func limitOrZero(limit *int32) int {
+	if limit == nil {
+		return 0
+	}
+	return int(*limit)
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/base"
+	"github.com/uber-go/dosa/connectors/memory"
+	"github.com/uber-go/dosa/connectors/yarpc"
+	dosarpc "github.com/uber/dosa-idl/.gen/dosa"
+)
+
+var (
+	ctx        = context.Background()
+	testScope  = "scope1"
+	testPrefix = "name.prefix"
+	testEntity = "t1"
+)
+
+func testEntityDefinition() *dosa.EntityDefinition {
+	return &dosa.EntityDefinition{
+		Name: testEntity,
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"f1"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "c1", Descending: false}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "f1", Type: dosa.String},
+			{Name: "c1", Type: dosa.Int64},
+			{Name: "c2", Type: dosa.Double},
+		},
+	}
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	h := NewHandler(memory.NewConnector())
+	assert.NoError(t, h.CreateScope(ctx, &dosarpc.CreateScopeRequest{Name: &testScope}))
+	response, err := h.UpsertSchema(ctx, upsertSchemaRequest(testEntityDefinition()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *response.Version)
+	return h
+}
+
+func upsertSchemaRequest(eds ...*dosa.EntityDefinition) *dosarpc.UpsertSchemaRequest {
+	request := &dosarpc.UpsertSchemaRequest{Scope: &testScope, NamePrefix: &testPrefix}
+	for _, ed := range eds {
+		request.EntityDefs = append(request.EntityDefs, yarpc.EntityDefinitionToThrift(ed))
+	}
+	return request
+}
+
+func checkSchemaRequest(eds ...*dosa.EntityDefinition) *dosarpc.CheckSchemaRequest {
+	request := &dosarpc.CheckSchemaRequest{Scope: &testScope, NamePrefix: &testPrefix}
+	for _, ed := range eds {
+		request.EntityDefs = append(request.EntityDefs, yarpc.EntityDefinitionToThrift(ed))
+	}
+	return request
+}
+
+func testRef(version int32) *dosarpc.SchemaRef {
+	return &dosarpc.SchemaRef{
+		Scope:      &testScope,
+		NamePrefix: &testPrefix,
+		EntityName: &testEntity,
+		Version:    &version,
+	}
+}
+
+func fieldValues(t *testing.T, values map[string]dosa.FieldValue) dosarpc.FieldValueMap {
+	fields, err := encodeFieldValues(values)
+	assert.NoError(t, err)
+	return fields
+}
+
+func assertErrorCode(t *testing.T, code int32, err error) {
+	if assert.IsType(t, &dosarpc.BadRequestError{}, err) {
+		be := err.(*dosarpc.BadRequestError)
+		if assert.NotNil(t, be.ErrorCode, *be.Msg) {
+			assert.Equal(t, code, *be.ErrorCode)
+		}
+	}
+}
+
+func TestHandler_Scopes(t *testing.T) {
+	h := NewHandler(memory.NewConnector())
+	assert.NoError(t, h.CreateScope(ctx, &dosarpc.CreateScopeRequest{Name: &testScope}))
+	assertErrorCode(t, errCodeAlreadyExists, h.CreateScope(ctx, &dosarpc.CreateScopeRequest{Name: &testScope}))
+
+	invalid := "Not-Valid"
+	err := h.CreateScope(ctx, &dosarpc.CreateScopeRequest{Name: &invalid})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+	assert.Nil(t, err.(*dosarpc.BadRequestError).ErrorCode)
+
+	assert.NoError(t, h.DropScope(ctx, &dosarpc.DropScopeRequest{Name: &testScope}))
+	assertErrorCode(t, errCodeNotFound, h.DropScope(ctx, &dosarpc.DropScopeRequest{Name: &testScope}))
+	assertErrorCode(t, errCodeNotFound, h.TruncateScope(ctx, &dosarpc.TruncateScopeRequest{Name: &testScope}))
+
+	// schema is forgotten along with the scope
+	_, err = h.UpsertSchema(ctx, upsertSchemaRequest(testEntityDefinition()))
+	assertErrorCode(t, errCodeNotFound, err)
+	assert.NoError(t, h.CreateScope(ctx, &dosarpc.CreateScopeRequest{Name: &testScope}))
+	_, err = h.CheckSchema(ctx, checkSchemaRequest(testEntityDefinition()))
+	assertErrorCode(t, errCodeNotFound, err)
+}
+
+func TestHandler_UnsupportedScopes(t *testing.T) {
+	// scopes are kept by the handler when the connector has none
+	h := NewHandler(&base.Connector{})
+	assert.NoError(t, h.CreateScope(ctx, &dosarpc.CreateScopeRequest{Name: &testScope}))
+	assert.NoError(t, h.TruncateScope(ctx, &dosarpc.TruncateScopeRequest{Name: &testScope}))
+	assert.NoError(t, h.DropScope(ctx, &dosarpc.DropScopeRequest{Name: &testScope}))
+	assertErrorCode(t, errCodeNotFound, h.TruncateScope(ctx, &dosarpc.TruncateScopeRequest{Name: &testScope}))
+}
+
+func TestHandler_SchemaVersions(t *testing.T) {
+	h := newTestHandler(t)
+
+	// same schema, same version
+	response, err := h.UpsertSchema(ctx, upsertSchemaRequest(testEntityDefinition()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *response.Version)
+	assert.Equal(t, dosa.SchemaStatusCompleted, *response.Status)
+
+	// a new column is compatible
+	v2 := testEntityDefinition()
+	v2.Columns = append(v2.Columns, &dosa.ColumnDefinition{Name: "c3", Type: dosa.String})
+	response, err = h.UpsertSchema(ctx, upsertSchemaRequest(v2))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *response.Version)
+
+	// removing a column or changing a key is not
+	_, err = h.UpsertSchema(ctx, upsertSchemaRequest(testEntityDefinition()))
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+	changedKey := testEntityDefinition()
+	changedKey.Key.ClusteringKeys[0].Descending = true
+	_, err = h.UpsertSchema(ctx, upsertSchemaRequest(changedKey))
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+
+	// invalid definitions are rejected
+	invalid := testEntityDefinition()
+	invalid.Key.PartitionKeys = []string{"missing"}
+	_, err = h.UpsertSchema(ctx, upsertSchemaRequest(invalid))
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+	_, err = h.UpsertSchema(ctx, upsertSchemaRequest())
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+
+	// check returns the version matching the definitions
+	check, err := h.CheckSchema(ctx, checkSchemaRequest(v2))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *check.Version)
+	check, err = h.CheckSchema(ctx, checkSchemaRequest(testEntityDefinition()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *check.Version)
+	other := testEntityDefinition()
+	other.Name = "t2"
+	_, err = h.CheckSchema(ctx, checkSchemaRequest(other))
+	assertErrorCode(t, errCodeNotFound, err)
+
+	version := int32(2)
+	status, err := h.CheckSchemaStatus(ctx, &dosarpc.CheckSchemaStatusRequest{Scope: &testScope, NamePrefix: &testPrefix, Version: &version})
+	assert.NoError(t, err)
+	assert.Equal(t, version, *status.Version)
+	assert.Equal(t, dosa.SchemaStatusCompleted, *status.Status)
+	version = 3
+	_, err = h.CheckSchemaStatus(ctx, &dosarpc.CheckSchemaStatusRequest{Scope: &testScope, NamePrefix: &testPrefix, Version: &version})
+	assertErrorCode(t, errCodeNotFound, err)
+}
+
+func TestHandler_ReadWrite(t *testing.T) {
+	h := newTestHandler(t)
+	row := map[string]dosa.FieldValue{"f1": "key", "c1": int64(1), "c2": 1.5}
+	keys := map[string]dosa.FieldValue{"f1": "key", "c1": int64(1)}
+
+	assert.NoError(t, h.CreateIfNotExists(ctx, &dosarpc.CreateRequest{Ref: testRef(1), EntityValues: fieldValues(t, row)}))
+	err := h.CreateIfNotExists(ctx, &dosarpc.CreateRequest{Ref: testRef(1), EntityValues: fieldValues(t, row)})
+	assertErrorCode(t, errCodeAlreadyExists, err)
+
+	read, err := h.Read(ctx, &dosarpc.ReadRequest{Ref: testRef(1), KeyValues: fieldValues(t, keys)})
+	assert.NoError(t, err)
+	assert.Equal(t, fieldValues(t, row), read.EntityValues)
+
+	row["c2"] = 2.5
+	assert.NoError(t, h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: testRef(1), EntityValues: fieldValues(t, row)}))
+	read, err = h.Read(ctx, &dosarpc.ReadRequest{Ref: testRef(1), KeyValues: fieldValues(t, keys)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, *read.EntityValues["c2"].ElemValue.DoubleValue)
+
+	// nulls are sent as values with no RawValue set
+	row["c2"] = nil
+	assert.NoError(t, h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: testRef(1), EntityValues: fieldValues(t, row)}))
+	read, err = h.Read(ctx, &dosarpc.ReadRequest{Ref: testRef(1), KeyValues: fieldValues(t, keys)})
+	assert.NoError(t, err)
+	if assert.NotNil(t, read.EntityValues["c2"]) {
+		assert.Nil(t, read.EntityValues["c2"].ElemValue)
+	}
+
+	assert.NoError(t, h.Remove(ctx, &dosarpc.RemoveRequest{Ref: testRef(1), KeyValues: fieldValues(t, keys)}))
+	_, err = h.Read(ctx, &dosarpc.ReadRequest{Ref: testRef(1), KeyValues: fieldValues(t, keys)})
+	assertErrorCode(t, errCodeNotFound, err)
+}
+
+func TestHandler_RangeScan(t *testing.T) {
+	h := newTestHandler(t)
+	// an empty table is not an error
+	scan, err := h.Scan(ctx, &dosarpc.ScanRequest{Ref: testRef(1)})
+	assert.NoError(t, err)
+	assert.Empty(t, scan.Entities)
+
+	for i := 0; i < 5; i++ {
+		row := map[string]dosa.FieldValue{"f1": "key", "c1": int64(i), "c2": float64(i)}
+		assert.NoError(t, h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: testRef(1), EntityValues: fieldValues(t, row)}))
+	}
+
+	field, lower := "f1", "c1"
+	key, err := yarpc.RawValueFromInterface("key")
+	assert.NoError(t, err)
+	bound, err := yarpc.RawValueFromInterface(int64(1))
+	assert.NoError(t, err)
+	response, err := h.Range(ctx, &dosarpc.RangeRequest{
+		Ref: testRef(1),
+		Conditions: []*dosarpc.Condition{
+			{Op: dosarpc.OperatorEq.Ptr(), Field: &dosarpc.Field{Name: &field, Value: &dosarpc.Value{ElemValue: key}}},
+			{Op: dosarpc.OperatorGt.Ptr(), Field: &dosarpc.Field{Name: &lower, Value: &dosarpc.Value{ElemValue: bound}}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, response.Entities, 3)
+	assert.Equal(t, "", *response.NextToken)
+
+	// conditions must include the partition key
+	_, err = h.Range(ctx, &dosarpc.RangeRequest{
+		Ref: testRef(1),
+		Conditions: []*dosarpc.Condition{
+			{Op: dosarpc.OperatorGt.Ptr(), Field: &dosarpc.Field{Name: &lower, Value: &dosarpc.Value{ElemValue: bound}}},
+		},
+	})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+
+	// null values cannot be used in conditions
+	_, err = h.Range(ctx, &dosarpc.RangeRequest{
+		Ref: testRef(1),
+		Conditions: []*dosarpc.Condition{
+			{Op: dosarpc.OperatorEq.Ptr(), Field: &dosarpc.Field{Name: &field, Value: &dosarpc.Value{}}},
+		},
+	})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+
+	scan, err = h.Scan(ctx, &dosarpc.ScanRequest{Ref: testRef(1)})
+	assert.NoError(t, err)
+	assert.Len(t, scan.Entities, 5)
+	assert.NotNil(t, scan.NextToken)
+}
+
+func TestHandler_InvalidRequests(t *testing.T) {
+	h := newTestHandler(t)
+	row := map[string]dosa.FieldValue{"f1": "key", "c1": int64(1)}
+
+	// unknown version or entity
+	err := h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: testRef(2), EntityValues: fieldValues(t, row)})
+	assertErrorCode(t, errCodeNotFound, err)
+	ref := testRef(1)
+	other := "t2"
+	ref.EntityName = &other
+	err = h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: ref, EntityValues: fieldValues(t, row)})
+	assertErrorCode(t, errCodeNotFound, err)
+	err = h.Upsert(ctx, &dosarpc.UpsertRequest{EntityValues: fieldValues(t, row)})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+
+	// unknown column, or value of the wrong type
+	err = h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: testRef(1), EntityValues: fieldValues(t, map[string]dosa.FieldValue{"f1": "key", "c1": int64(1), "c9": "x"})})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+	err = h.Upsert(ctx, &dosarpc.UpsertRequest{Ref: testRef(1), EntityValues: fieldValues(t, map[string]dosa.FieldValue{"f1": "key", "c1": "one"})})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+
+	// malformed schema
+	_, err = h.UpsertSchema(ctx, &dosarpc.UpsertSchemaRequest{Scope: &testScope, NamePrefix: &testPrefix, EntityDefs: []*dosarpc.EntityDefinition{{}}})
+	assert.IsType(t, &dosarpc.BadRequestError{}, err)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gateway
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// schemaVersion is one registered version of the entities for a scope and name prefix
+type schemaVersion struct {
+	dosa.SchemaVersion
+	// connectorVersion is the version assigned by the backing connector; it is
+	// the one sent along with DML requests
+	connectorVersion int32
+}
+
+// registry tracks the scopes known to the gateway, and every version of the
+// schema upserted for each scope and name prefix
+type registry struct {
+	sync.RWMutex
+	// scope -> name prefix -> versions, oldest first
+	scopes map[string]map[string][]*schemaVersion
+}
+
+func newRegistry() *registry {
+	return &registry{scopes: make(map[string]map[string][]*schemaVersion)}
+}
+
+// createScope registers a new, empty scope
+func (r *registry) createScope(scope string) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.scopes[scope]; ok {
+		return &dosa.ErrAlreadyExists{}
+	}
+	r.scopes[scope] = make(map[string][]*schemaVersion)
+	return nil
+}
+
+// dropScope forgets a scope and all of its schema versions
+func (r *registry) dropScope(scope string) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.scopes[scope]; !ok {
+		return errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	delete(r.scopes, scope)
+	return nil
+}
+
+// scopeExists returns true if the scope was created and not dropped since
+func (r *registry) scopeExists(scope string) bool {
+	r.RLock()
+	defer r.RUnlock()
+	_, ok := r.scopes[scope]
+	return ok
+}
+
+// latest returns the most recent schema version for a scope and name prefix,
+// or nil if no schema has been upserted yet
+func (r *registry) latest(scope, namePrefix string) (*schemaVersion, error) {
+	r.RLock()
+	defer r.RUnlock()
+	prefixes, ok := r.scopes[scope]
+	if !ok {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	versions := prefixes[namePrefix]
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return versions[len(versions)-1], nil
+}
+
+// find returns a specific schema version for a scope and name prefix
+func (r *registry) find(scope, namePrefix string, version int32) (*schemaVersion, error) {
+	r.RLock()
+	defer r.RUnlock()
+	prefixes, ok := r.scopes[scope]
+	if !ok {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	versions := prefixes[namePrefix]
+	// versions are numbered from 1 without gaps
+	if version < 1 || int(version) > len(versions) {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "version %d of schema %q in scope %q", version, namePrefix, scope)
+	}
+	return versions[version-1], nil
+}
+
+// check returns the most recent version whose entities match the provided
+// definitions exactly. Entities registered but not provided are ignored.
+func (r *registry) check(scope, namePrefix string, eds []*dosa.EntityDefinition) (int32, error) {
+	r.RLock()
+	defer r.RUnlock()
+	prefixes, ok := r.scopes[scope]
+	if !ok {
+		return dosa.InvalidVersion, errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	for i := len(prefixes[namePrefix]) - 1; i >= 0; i-- {
+		if sv := prefixes[namePrefix][i]; sv.Matches(eds) {
+			return sv.Version, nil
+		}
+	}
+	return dosa.InvalidVersion, errors.Wrapf(&dosa.ErrNotFound{}, "no matching schema %q in scope %q", namePrefix, scope)
+}
+
+// add appends a new schema version and returns it. An invalid connector
+// version means the connector does not version schema, so the gateway's
+// version is used instead.
+func (r *registry) add(scope, namePrefix string, eds []*dosa.EntityDefinition, connectorVersion int32) (*schemaVersion, error) {
+	r.Lock()
+	defer r.Unlock()
+	prefixes, ok := r.scopes[scope]
+	if !ok {
+		return nil, errors.Wrapf(&dosa.ErrNotFound{}, "scope %q", scope)
+	}
+	sv := &schemaVersion{
+		SchemaVersion: dosa.SchemaVersion{
+			Version:  int32(len(prefixes[namePrefix]) + 1),
+			Entities: append([]*dosa.EntityDefinition(nil), eds...),
+		},
+		connectorVersion: connectorVersion,
+	}
+	if sv.connectorVersion == dosa.InvalidVersion {
+		sv.connectorVersion = sv.Version
+	}
+	prefixes[namePrefix] = append(prefixes[namePrefix], sv)
+	return sv, nil
+}
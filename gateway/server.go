@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	"github.com/uber/dosa-idl/.gen/dosa/dosaserver"
+	rpc "go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/transport/tchannel"
+)
+
+const _defaultServiceName = "dosa-gateway"
+
+// Config contains the parameters of the gateway server
+type Config struct {
+	Transport   string `yaml:"transport"`
+	Host        string `yaml:"host"`
+	Port        string `yaml:"port"`
+	ServiceName string `yaml:"serviceName"`
+}
+
+// Server serves a Handler over YARPC
+type Server struct {
+	Handler    *Handler
+	dispatcher *rpc.Dispatcher
+}
+
+// NewServer creates a server for the data stored by the connector, listening
+// on HTTP or TChannel according to the config. Call Start to start listening.
+func NewServer(cfg *Config, conn dosa.Connector) (*Server, error) {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = _defaultServiceName
+	}
+	hostPort := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
+
+	var inbound transport.Inbound
+	switch cfg.Transport {
+	case "http":
+		inbound = http.NewTransport().NewInbound(hostPort)
+	case "tchannel":
+		ts, err := tchannel.NewChannelTransport(
+			tchannel.ServiceName(cfg.ServiceName),
+			tchannel.ListenAddr(hostPort),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create tchannel transport")
+		}
+		inbound = ts.NewInbound()
+	default:
+		return nil, errors.New("invalid transport (only http or tchannel supported)")
+	}
+
+	handler := NewHandler(conn)
+	// important to note that this will panic if config contains invalid
+	// values such as service name containing invalid characters
+	dispatcher := rpc.NewDispatcher(rpc.Config{
+		Name:     cfg.ServiceName,
+		Inbounds: rpc.Inbounds{inbound},
+	})
+	dispatcher.Register(dosaserver.New(handler))
+	return &Server{
+		Handler:    handler,
+		dispatcher: dispatcher,
+	}, nil
+}
+
+// Start starts listening for requests
+func (s *Server) Start() error {
+	return errors.Wrap(s.dispatcher.Start(), "failed to start gateway")
+}
+
+// Stop stops listening for requests; the connector is not shut down
+func (s *Server) Stop() error {
+	return errors.Wrap(s.dispatcher.Stop(), "failed to stop gateway")
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa/connectors/memory"
+)
+
+func TestNewServer(t *testing.T) {
+	for _, transport := range []string{"http", "tchannel"} {
+		cfg := &Config{Transport: transport, Host: "127.0.0.1", Port: "0"}
+		s, err := NewServer(cfg, memory.NewConnector())
+		assert.NoError(t, err)
+		assert.Equal(t, _defaultServiceName, cfg.ServiceName)
+		assert.NotNil(t, s.Handler)
+		assert.NoError(t, s.Start())
+		assert.NoError(t, s.Stop())
+	}
+
+	_, err := NewServer(&Config{Transport: "carrier-pigeon"}, memory.NewConnector())
+	assert.Error(t, err)
+}
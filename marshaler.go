@@ -0,0 +1,187 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// ColumnMarshaler is implemented by custom field types that are stored as one
+// of the DOSA types. Entities can use such types for their fields, typically
+// with a pointer receiver for UnmarshalDOSA:
+//
+//	type UserID int64
+//
+//	func (UserID) DOSAType() dosa.Type { return dosa.Int64 }
+//	func (id UserID) MarshalDOSA() (dosa.FieldValue, error) { return int64(id), nil }
+//	func (id *UserID) UnmarshalDOSA(v dosa.FieldValue) error { *id = UserID(v.(int64)); return nil }
+//
+// The conversion happens when the client reads and writes entities, so
+// connectors only ever see values of the declared storage type. Since the
+// finder cannot run these methods, fields of custom types need an explicit
+// type tag for the schema commands, e.g. `dosa:"type=Int64"`.
+type ColumnMarshaler interface {
+	// DOSAType returns the type the values are stored as. It is called on
+	// a zero value.
+	DOSAType() Type
+	// MarshalDOSA converts the value to a value of its DOSA type.
+	MarshalDOSA() (FieldValue, error)
+	// UnmarshalDOSA sets the value from a value of its DOSA type.
+	UnmarshalDOSA(FieldValue) error
+}
+
+var columnMarshalerType = reflect.TypeOf((*ColumnMarshaler)(nil)).Elem()
+
+// marshalerDOSAType returns the declared type of a field type implementing
+// ColumnMarshaler (with either receiver), and false for other types.
+func marshalerDOSAType(f reflect.Type) (Type, bool) {
+	if f.Kind() == reflect.Interface || !reflect.PtrTo(f).Implements(columnMarshalerType) {
+		return Invalid, false
+	}
+	return reflect.New(f).Interface().(ColumnMarshaler).DOSAType(), true
+}
+
+// marshalField returns the value of an entity field as a FieldValue,
+// converting the values of custom types with MarshalDOSA, widening the
+// values of numeric types, and dereferencing pointers.
+func marshalField(v reflect.Value) (FieldValue, error) {
+	if v.Kind() == reflect.Ptr {
+		// nil pointers are sent as nulls
+		if v.IsNil() {
+			return nil, nil
+		}
+		return marshalField(v.Elem())
+	}
+	if t, ok := numericTypes[v.Type()]; ok {
+		return widenNumeric(v, t), nil
+	}
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	m, ok := v.Interface().(ColumnMarshaler)
+	if !ok {
+		return reflect.Indirect(v).Interface(), nil
+	}
+	value, err := m.MarshalDOSA()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot marshal %T", m)
+	}
+	if t := m.DOSAType(); TypeOf(value) != t {
+		return nil, errors.Errorf("%T marshaled to %T, not a %v", m, value, t)
+	}
+	return value, nil
+}
+
+// setField sets an entity field from a FieldValue, converting the values of
+// custom types with UnmarshalDOSA, narrowing the values of numeric types, and
+// allocating pointers.
+func setField(v reflect.Value, value FieldValue) error {
+	if value == nil {
+		// nulls are set as nil pointers, or zero values
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if _, ok := numericTypes[v.Type()]; ok {
+		return narrowNumeric(v, value)
+	}
+	if m, ok := v.Addr().Interface().(ColumnMarshaler); ok {
+		return errors.Wrapf(m.UnmarshalDOSA(value), "cannot unmarshal %T", m)
+	}
+	v.Set(reflect.ValueOf(value))
+	return nil
+}
+
+// marshalConditionValue converts the values of custom types used in range
+// conditions with MarshalDOSA.
+func marshalConditionValue(value FieldValue) (FieldValue, error) {
+	if value == nil {
+		return nil, nil
+	}
+	v := reflect.New(reflect.TypeOf(value)).Elem()
+	v.Set(reflect.ValueOf(value))
+	return marshalField(v)
+}
+
+// widenNumeric converts the value of a numeric field to its DOSA type t
+func widenNumeric(v reflect.Value, t Type) FieldValue {
+	switch v.Kind() {
+	case reflect.Float32:
+		return v.Float()
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if t == Int32 {
+			return int32(v.Uint())
+		}
+		return int64(v.Uint())
+	default:
+		if t == Int32 {
+			return int32(v.Int())
+		}
+		return v.Int()
+	}
+}
+
+// narrowNumeric sets a numeric field from a value of its DOSA type, failing
+// when the value does not fit in the field
+func narrowNumeric(v reflect.Value, value FieldValue) error {
+	var i int64
+	switch value := value.(type) {
+	case int32:
+		i = int64(value)
+	case int64:
+		i = value
+	case float64:
+		if v.Kind() != reflect.Float32 {
+			return errors.Errorf("cannot set a %T on a %v", value, v.Type())
+		}
+		if v.OverflowFloat(value) {
+			return errors.Errorf("value %v overflows %v", value, v.Type())
+		}
+		v.SetFloat(value)
+		return nil
+	default:
+		return errors.Errorf("cannot set a %T on a %v", value, v.Type())
+	}
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return errors.Errorf("value %d overflows %v", i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		if v.OverflowInt(i) {
+			return errors.Errorf("value %d overflows %v", i, v.Type())
+		}
+		v.SetInt(i)
+	default:
+		return errors.Errorf("cannot set a %T on a %v", value, v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	dosaRenamed "github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/memory"
+)
+
+type testUserID int64
+
+func (testUserID) DOSAType() dosaRenamed.Type { return dosaRenamed.Int64 }
+
+func (id testUserID) MarshalDOSA() (dosaRenamed.FieldValue, error) { return int64(id), nil }
+
+func (id *testUserID) UnmarshalDOSA(value dosaRenamed.FieldValue) error {
+	i, ok := value.(int64)
+	if !ok {
+		return errors.Errorf("%T is not an int64", value)
+	}
+	*id = testUserID(i)
+	return nil
+}
+
+// testTags are stored as a comma separated string
+type testTags []string
+
+func (testTags) DOSAType() dosaRenamed.Type { return dosaRenamed.String }
+
+func (tags testTags) MarshalDOSA() (dosaRenamed.FieldValue, error) {
+	for _, tag := range tags {
+		if strings.Contains(tag, ",") {
+			return nil, errors.Errorf("tag %q has a comma", tag)
+		}
+	}
+	return strings.Join(tags, ","), nil
+}
+
+func (tags *testTags) UnmarshalDOSA(value dosaRenamed.FieldValue) error {
+	*tags = nil
+	if s := value.(string); s != "" {
+		*tags = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// testBadMarshaler marshals to a different type than it declares
+type testBadMarshaler int64
+
+func (testBadMarshaler) DOSAType() dosaRenamed.Type { return dosaRenamed.Int64 }
+
+func (b testBadMarshaler) MarshalDOSA() (dosaRenamed.FieldValue, error) { return int32(b), nil }
+
+func (b *testBadMarshaler) UnmarshalDOSA(dosaRenamed.FieldValue) error { return nil }
+
+type ClientTestMarshalers struct {
+	dosaRenamed.Entity `dosa:"primaryKey=(ID)"`
+	ID                 testUserID `dosa:"type=Int64"`
+	Tags               testTags   `dosa:"name=labels type=String"`
+}
+
+func TestTableFromInstance_Marshalers(t *testing.T) {
+	table, err := dosaRenamed.TableFromInstance(&ClientTestMarshalers{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosaRenamed.ColumnDefinition{
+		{Name: "id", Type: dosaRenamed.Int64},
+		{Name: "labels", Type: dosaRenamed.String},
+	}, table.Columns)
+
+	type Untagged struct {
+		dosaRenamed.Entity `dosa:"primaryKey=(ID)"`
+		ID                 testUserID
+	}
+	table, err = dosaRenamed.TableFromInstance(&Untagged{})
+	assert.NoError(t, err)
+	assert.Equal(t, dosaRenamed.Int64, table.Columns[0].Type)
+
+	type Mismatched struct {
+		dosaRenamed.Entity `dosa:"primaryKey=(ID)"`
+		ID                 testUserID `dosa:"type=String"`
+	}
+	_, err = dosaRenamed.TableFromInstance(&Mismatched{})
+	assert.Contains(t, err.Error(), "field id has type Int64 but its type tag says String")
+}
+
+func TestRegisteredEntity_Marshalers(t *testing.T) {
+	table, err := dosaRenamed.TableFromInstance(&ClientTestMarshalers{})
+	assert.NoError(t, err)
+	re := dosaRenamed.NewRegisteredEntity("test", "team.service", table)
+
+	entity := &ClientTestMarshalers{ID: 7, Tags: testTags{"a", "b"}}
+	keys, err := re.KeyFieldValues(entity)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosaRenamed.FieldValue{"id": int64(7)}, keys)
+	values, err := re.OnlyFieldValues(entity, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dosaRenamed.FieldValue{"id": int64(7), "labels": "a,b"}, values)
+
+	read := &ClientTestMarshalers{}
+	assert.NoError(t, re.SetFieldValues(read, values, nil))
+	assert.Equal(t, entity, read)
+
+	err = re.SetFieldValues(read, map[string]dosaRenamed.FieldValue{"id": "7"}, nil)
+	assert.Contains(t, err.Error(), "field ID: cannot unmarshal *dosa_test.testUserID: string is not an int64")
+
+	_, err = re.OnlyFieldValues(&ClientTestMarshalers{Tags: testTags{"a,b"}}, nil)
+	assert.Contains(t, err.Error(), `field Tags: cannot marshal *dosa_test.testTags: tag "a,b" has a comma`)
+
+	type BadMarshaler struct {
+		dosaRenamed.Entity `dosa:"primaryKey=(ID)"`
+		ID                 testBadMarshaler
+	}
+	table, err = dosaRenamed.TableFromInstance(&BadMarshaler{})
+	assert.NoError(t, err)
+	re = dosaRenamed.NewRegisteredEntity("test", "team.service", table)
+	_, err = re.KeyFieldValues(&BadMarshaler{ID: 1})
+	assert.Contains(t, err.Error(), "*dosa_test.testBadMarshaler marshaled to int32, not a Int64")
+}
+
+func TestClient_Marshalers(t *testing.T) {
+	reg, err := dosaRenamed.NewRegistrar("test", "myteam.myservice", &ClientTestMarshalers{})
+	assert.NoError(t, err)
+	conn := memory.NewConnector()
+	assert.NoError(t, conn.CreateScope(context.TODO(), "test"))
+	re, err := reg.Find(&ClientTestMarshalers{})
+	assert.NoError(t, err)
+	_, err = conn.UpsertSchema(context.TODO(), "test", "myteam.myservice", []*dosaRenamed.EntityDefinition{re.EntityDefinition()})
+	assert.NoError(t, err)
+	c := dosaRenamed.NewClient(reg, conn)
+	assert.NoError(t, c.Initialize(context.TODO()))
+
+	assert.NoError(t, c.Upsert(ctx, dosaRenamed.All(), &ClientTestMarshalers{ID: 1, Tags: testTags{"x", "y"}}))
+	assert.NoError(t, c.Upsert(ctx, dosaRenamed.All(), &ClientTestMarshalers{ID: 2}))
+	err = c.Upsert(ctx, dosaRenamed.All(), &ClientTestMarshalers{ID: 3, Tags: testTags{"x,y"}})
+	assert.Contains(t, err.Error(), "has a comma")
+
+	// connectors only see the storage type
+	row, err := conn.Read(ctx, re.EntityInfo(), map[string]dosaRenamed.FieldValue{"id": int64(1)}, dosaRenamed.All())
+	assert.NoError(t, err)
+	assert.Equal(t, "x,y", row["labels"])
+
+	read := &ClientTestMarshalers{ID: 1}
+	assert.NoError(t, c.Read(ctx, dosaRenamed.All(), read))
+	assert.Equal(t, testTags{"x", "y"}, read.Tags)
+
+	objs, _, err := c.Range(ctx, dosaRenamed.NewRangeOp(&ClientTestMarshalers{}).Eq("ID", testUserID(2)))
+	assert.NoError(t, err)
+	assert.Equal(t, []dosaRenamed.DomainObject{&ClientTestMarshalers{ID: 2}}, objs)
+
+	assert.NoError(t, c.Remove(ctx, &ClientTestMarshalers{ID: 1}))
+	assert.True(t, dosaRenamed.ErrorIsNotFound(c.Read(ctx, dosaRenamed.All(), read)))
+}
@@ -64,6 +64,17 @@ func (_mr *_MockClientRecorder) CreateIfNotExists(arg0, arg1 interface{}) *gomoc
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateIfNotExists", arg0, arg1)
 }
 
+// Increment is a mock implementation of MockClient.Increment
+func (_m *MockClient) Increment(_param0 context.Context, _param1 dosa.DomainObject, _param2 string, _param3 int64) error {
+	ret := _m.ctrl.Call(_m, "Increment", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) Increment(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Increment", arg0, arg1, arg2, arg3)
+}
+
 // Initialize is a mock implementation of MockClient.Initialize
 func (_m *MockClient) Initialize(_param0 context.Context) error {
 	ret := _m.ctrl.Call(_m, "Initialize", _param0)
@@ -134,6 +145,18 @@ func (_mr *_MockClientRecorder) Upsert(arg0, arg1, arg2 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Upsert", arg0, arg1, arg2)
 }
 
+// Watch is a mock implementation of MockClient.Watch
+func (_m *MockClient) Watch(_param0 context.Context, _param1 dosa.DomainObject, _param2 *dosa.WatchOptions) (<-chan *dosa.ChangeEvent, error) {
+	ret := _m.ctrl.Call(_m, "Watch", _param0, _param1, _param2)
+	ret0, _ := ret[0].(<-chan *dosa.ChangeEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) Watch(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Watch", arg0, arg1, arg2)
+}
+
 // MockAdminClient is a mock of AdminClient interface
 type MockAdminClient struct {
 	ctrl     *gomock.Controller
@@ -237,6 +260,30 @@ func (_mr *_MockAdminClientRecorder) GetSchema() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetSchema")
 }
 
+// GetStoredSchema is a mock implementation of MockAdminClient.GetStoredSchema
+func (_m *MockAdminClient) GetStoredSchema(_param0 context.Context, _param1 string, _param2 int32) ([]*dosa.EntityDefinition, error) {
+	ret := _m.ctrl.Call(_m, "GetStoredSchema", _param0, _param1, _param2)
+	ret0, _ := ret[0].([]*dosa.EntityDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAdminClientRecorder) GetStoredSchema(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetStoredSchema", arg0, arg1, arg2)
+}
+
+// ListSchemaVersions is a mock implementation of MockAdminClient.ListSchemaVersions
+func (_m *MockAdminClient) ListSchemaVersions(_param0 context.Context, _param1 string) ([]*dosa.SchemaStatus, error) {
+	ret := _m.ctrl.Call(_m, "ListSchemaVersions", _param0, _param1)
+	ret0, _ := ret[0].([]*dosa.SchemaStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAdminClientRecorder) ListSchemaVersions(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListSchemaVersions", arg0, arg1)
+}
+
 // Scope is a mock implementation of MockAdminClient.Scope
 func (_m *MockAdminClient) Scope(_param0 string) dosa.AdminClient {
 	ret := _m.ctrl.Call(_m, "Scope", _param0)
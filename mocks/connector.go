@@ -110,6 +110,30 @@ func (_mr *_MockConnectorRecorder) DropScope(arg0, arg1 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DropScope", arg0, arg1)
 }
 
+// GetSchema is a mock implementation of MockConnector.GetSchema
+func (_m *MockConnector) GetSchema(_param0 context.Context, _param1 string, _param2 string, _param3 int32) ([]*dosa.EntityDefinition, error) {
+	ret := _m.ctrl.Call(_m, "GetSchema", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].([]*dosa.EntityDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockConnectorRecorder) GetSchema(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetSchema", arg0, arg1, arg2, arg3)
+}
+
+// ListSchemaVersions is a mock implementation of MockConnector.ListSchemaVersions
+func (_m *MockConnector) ListSchemaVersions(_param0 context.Context, _param1 string, _param2 string) ([]*dosa.SchemaStatus, error) {
+	ret := _m.ctrl.Call(_m, "ListSchemaVersions", _param0, _param1, _param2)
+	ret0, _ := ret[0].([]*dosa.SchemaStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockConnectorRecorder) ListSchemaVersions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListSchemaVersions", arg0, arg1, arg2)
+}
+
 // MultiRead is a mock implementation of MockConnector.MultiRead
 func (_m *MockConnector) MultiRead(_param0 context.Context, _param1 *dosa.EntityInfo, _param2 []map[string]dosa.FieldValue, _param3 []string) ([]*dosa.FieldValuesOrError, error) {
 	ret := _m.ctrl.Call(_m, "MultiRead", _param0, _param1, _param2, _param3)
@@ -130,13 +130,17 @@ func convertRangeOpConditions(r *RangeOp, t *Table) (map[string][]*Condition, er
 	serverConditions := map[string][]*Condition{}
 	for colName, conds := range r.conditions {
 		if scolName, ok := t.FieldToCol[colName]; ok {
-			serverConditions[scolName] = conds
 			// we need to be sure each of the types are correct for marshaling
 			cd := t.FindColumnDefinition(scolName)
 			for _, cond := range conds {
-				if err := ensureTypeMatch(cd.Type, cond.Value); err != nil {
+				value, err := marshalConditionValue(cond.Value)
+				if err != nil {
 					return nil, errors.Wrapf(err, "column %s", colName)
 				}
+				if err := ensureTypeMatch(cd.Type, value); err != nil {
+					return nil, errors.Wrapf(err, "column %s", colName)
+				}
+				serverConditions[scolName] = append(serverConditions[scolName], &Condition{Op: cond.Op, Value: value})
 			}
 		} else {
 			return nil, errors.Errorf("Cannot find column %q in struct %q", colName, t.StructName)
@@ -194,6 +194,8 @@ func compare(t Type, a, b interface{}) int {
 		return int(a.(int64) - b.(int64))
 	case Int32:
 		return int(a.(int32) - b.(int32))
+	case Counter:
+		return int(a.(CounterValue) - b.(CounterValue))
 	case String:
 		return strings.Compare(a.(string), b.(string))
 	case Blob:
@@ -228,11 +230,19 @@ func compare(t Type, a, b interface{}) int {
 			return 1
 		}
 		return 0
+	case TDecimal:
+		return a.(Decimal).Cmp(b.(Decimal))
+	case TDate:
+		return int(a.(Date).Days() - b.(Date).Days())
 	}
 	panic("invalid type") // shouldn't reach here
 }
 
 func ensureTypeMatch(t Type, v FieldValue) error {
+	if t.IsCollection() {
+		// collections cannot be keys, and have no order to compare values with
+		return errors.Errorf("conditions are not supported on %s columns: %v", t, v)
+	}
 	switch t {
 	case TUUID:
 		if _, ok := v.(UUID); !ok {
@@ -266,6 +276,22 @@ func ensureTypeMatch(t Type, v FieldValue) error {
 		if _, ok := v.(time.Time); !ok {
 			return errors.Errorf("invalid value for timestamp type: %v", v)
 		}
+	case Counter:
+		if _, ok := v.(CounterValue); !ok {
+			return errors.Errorf("invalid value for counter type: %v", v)
+		}
+	case TDecimal:
+		d, ok := v.(Decimal)
+		if !ok {
+			return errors.Errorf("invalid value for decimal type: %v", v)
+		}
+		if _, err := d.Rat(); err != nil {
+			return err
+		}
+	case TDate:
+		if _, ok := v.(Date); !ok {
+			return errors.Errorf("invalid value for date type: %v", v)
+		}
 	default:
 		// will not happen unless we have a bug
 		panic("invalid type")
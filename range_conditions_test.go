@@ -305,3 +305,66 @@ func TestEnsureValidRangeConditions(t *testing.T) {
 		}
 	}
 }
+
+func TestEnsureValidRangeConditionsDecimalDate(t *testing.T) {
+	ledger := &dosa.EntityDefinition{
+		Name: "ledger",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys: []string{"account"},
+			ClusteringKeys: []*dosa.ClusteringKey{
+				{Name: "day"},
+				{Name: "amount"},
+			},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "account", Type: dosa.String},
+			{Name: "day", Type: dosa.TDate},
+			{Name: "amount", Type: dosa.TDecimal},
+		},
+	}
+	identity := func(x string) string { return x }
+	account := []*dosa.Condition{{dosa.Eq, "savings"}}
+
+	assert.NoError(t, dosa.EnsureValidRangeConditions(ledger, map[string][]*dosa.Condition{
+		"account": account,
+		"day": {
+			{dosa.GtOrEq, dosa.NewDate(2017, time.January, 1)},
+			{dosa.Lt, dosa.NewDate(2017, time.February, 1)},
+		},
+	}, identity))
+	assert.NoError(t, dosa.EnsureValidRangeConditions(ledger, map[string][]*dosa.Condition{
+		"account": account,
+		"day":     {{dosa.Eq, dosa.NewDate(2017, time.January, 1)}},
+		"amount":  {{dosa.GtOrEq, dosa.Decimal("1.5")}, {dosa.LtOrEq, dosa.Decimal("1.50")}},
+	}, identity))
+
+	// 1.5 and 1.50 are the same number, so this range is empty
+	err := dosa.EnsureValidRangeConditions(ledger, map[string][]*dosa.Condition{
+		"account": account,
+		"day":     {{dosa.Eq, dosa.NewDate(2017, time.January, 1)}},
+		"amount":  {{dosa.Gt, dosa.Decimal("1.5")}, {dosa.LtOrEq, dosa.Decimal("1.50")}},
+	}, identity)
+	assert.Contains(t, err.Error(), "invalid range")
+
+	err = dosa.EnsureValidRangeConditions(ledger, map[string][]*dosa.Condition{
+		"account": account,
+		"day": {
+			{dosa.Gt, dosa.NewDate(2017, time.February, 1)},
+			{dosa.Lt, dosa.NewDate(2017, time.January, 1)},
+		},
+	}, identity)
+	assert.Contains(t, err.Error(), "invalid range")
+
+	err = dosa.EnsureValidRangeConditions(ledger, map[string][]*dosa.Condition{
+		"account": account,
+		"day":     {{dosa.Eq, "2017-01-01"}},
+	}, identity)
+	assert.Contains(t, err.Error(), "invalid value for date type")
+
+	err = dosa.EnsureValidRangeConditions(ledger, map[string][]*dosa.Condition{
+		"account": account,
+		"day":     {{dosa.Eq, dosa.NewDate(2017, time.January, 1)}},
+		"amount":  {{dosa.Eq, dosa.Decimal("1,5")}},
+	}, identity)
+	assert.Contains(t, err.Error(), "invalid decimal")
+}
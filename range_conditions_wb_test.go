@@ -55,6 +55,9 @@ func TestEnsureTypeMatch(t *testing.T) {
 		{Double, 1, true},
 		{Timestamp, time.Now(), false},
 		{Timestamp, "Fri Feb 24 15:43:46 PST 2017", true},
+		{ListOf(String), []string{"a"}, true},
+		{SetOf(Int64), map[int64]Set{1: {}}, true},
+		{MapOf(String, String), map[string]string{"a": "b"}, true},
 	}
 
 	for _, c := range cases {
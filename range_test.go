@@ -125,6 +125,15 @@ func TestConvertRangeOpConditions(t *testing.T) {
 	}
 }
 
+func TestConvertRangeOpConditionsCollection(t *testing.T) {
+	table, err := TableFromInstance(&CollectionTypes{})
+	assert.NoError(t, err)
+	_, err = convertRangeOpConditions(NewRangeOp(&CollectionTypes{}).Eq("Tags", []string{"a"}), table)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "conditions are not supported on List<String> columns")
+	}
+}
+
 func TestRangeOpMatcher(t *testing.T) {
 	RangeOp0 := NewRangeOp(&AllTypes{}).Eq("StringType", "Hello")
 	RangeOp1 := NewRangeOp(&AllTypes{}).Eq("StringType", "Hello")
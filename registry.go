@@ -86,15 +86,19 @@ func (e *RegisteredEntity) EntityDefinition() *EntityDefinition {
 }
 
 // KeyFieldValues is a helper for generating a map of field values to be used in a query.
-func (e *RegisteredEntity) KeyFieldValues(entity DomainObject) map[string]FieldValue {
+// An error is returned when the value of a custom type field cannot be marshaled.
+func (e *RegisteredEntity) KeyFieldValues(entity DomainObject) (map[string]FieldValue, error) {
 	v := reflect.ValueOf(entity).Elem()
 	fieldValues := make(map[string]FieldValue)
 
 	// populate partition key values
 	for _, pk := range e.table.Key.PartitionKeys {
 		fieldName := e.table.ColToField[pk]
-		value := v.FieldByName(fieldName)
-		fieldValues[pk] = value.Interface()
+		value, err := marshalField(v.FieldByName(fieldName))
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", fieldName)
+		}
+		fieldValues[pk] = value
 	}
 
 	// populate clustering key values
@@ -105,10 +109,14 @@ func (e *RegisteredEntity) KeyFieldValues(entity DomainObject) map[string]FieldV
 			// this should never happen
 			panic("Field " + fieldName + " is not a valid field for " + e.table.StructName)
 		}
-		fieldValues[ck.Name] = value.Interface()
+		fieldValue, err := marshalField(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", fieldName)
+		}
+		fieldValues[ck.Name] = fieldValue
 	}
 
-	return fieldValues
+	return fieldValues, nil
 }
 
 // OnlyFieldValues is a helper for generating a map of field values for a
@@ -133,7 +141,11 @@ func (e *RegisteredEntity) OnlyFieldValues(entity DomainObject, fieldNames []str
 			// this should never happen
 			panic("Field " + fieldName + " is not a valid field for " + e.table.StructName)
 		}
-		fieldValues[columnName] = value.Interface()
+		fieldValue, err := marshalField(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", fieldName)
+		}
+		fieldValues[columnName] = fieldValue
 	}
 	return fieldValues, nil
 }
@@ -157,8 +169,9 @@ func (e *RegisteredEntity) ColumnNames(fieldNames []string) ([]string, error) {
 }
 
 // SetFieldValues is a helper for populating a DOSA entity with the given
-// fieldName->value map
-func (e *RegisteredEntity) SetFieldValues(entity DomainObject, fieldValues map[string]FieldValue, fieldsToRead []string) {
+// fieldName->value map. An error is returned when a value cannot be
+// unmarshaled into a custom type field.
+func (e *RegisteredEntity) SetFieldValues(entity DomainObject, fieldValues map[string]FieldValue, fieldsToRead []string) error {
 	r := reflect.ValueOf(entity).Elem()
 	if fieldsToRead == nil {
 		for columnName := range fieldValues {
@@ -178,8 +191,11 @@ func (e *RegisteredEntity) SetFieldValues(entity DomainObject, fieldValues map[s
 		if !val.IsValid() {
 			panic("Field " + fieldName + " is is not a valid field for " + e.table.StructName)
 		}
-		val.Set(reflect.ValueOf(fieldValue))
+		if err := setField(val, fieldValue); err != nil {
+			return errors.Wrapf(err, "field %s", fieldName)
+		}
 	}
+	return nil
 }
 
 // Registrar is the interface to register DOSA entities.
@@ -88,7 +88,8 @@ func TestRegisteredEntity_KeyFieldValues(t *testing.T) {
 	})
 
 	// valid
-	fieldValues := re.KeyFieldValues(entity)
+	fieldValues, err := re.KeyFieldValues(entity)
+	assert.NoError(t, err)
 	expected := map[string]dosa.FieldValue{
 		"id":   int64(1),
 		"name": "foo",
@@ -157,13 +158,13 @@ func TestRegisteredEntity_SetFieldValues(t *testing.T) {
 	})
 
 	// invalid values are skipped
-	re.SetFieldValues(entity, invalidFieldValues, []string{"id", "name", "invalid"})
+	assert.NoError(t, re.SetFieldValues(entity, invalidFieldValues, []string{"id", "name", "invalid"}))
 	assert.Equal(t, entity.ID, invalidFieldValues["id"])
 	assert.Equal(t, entity.Name, invalidFieldValues["name"])
 	assert.Equal(t, entity.Email, "foo@email.com")
 
 	// valid
-	re.SetFieldValues(entity, validFieldValues, []string{"id", "name", "email"})
+	assert.NoError(t, re.SetFieldValues(entity, validFieldValues, []string{"id", "name", "email"}))
 	assert.Equal(t, entity.ID, validFieldValues["id"])
 	assert.Equal(t, entity.Name, validFieldValues["name"])
 	assert.Equal(t, entity.Email, validFieldValues["email"])
@@ -35,6 +35,9 @@ const (
 	nameKey        = "Name"
 	descendingKey  = "Descending"
 	dosaTypeKey    = "dosaType"
+	logicalTypeKey = "logicalType"
+	tagsKey        = "tags"
+	indexesKey     = "indexes"
 )
 
 // map from dosa type to avro type
@@ -47,6 +50,55 @@ var avroTypes = map[dosa.Type]gv.Schema{
 	dosa.Int64:     &gv.LongSchema{},
 	dosa.Timestamp: &gv.LongSchema{},
 	dosa.TUUID:     &gv.StringSchema{},
+	dosa.Counter:   &gv.LongSchema{},
+	// avro decimals need a fixed precision and scale, which dosa decimals do not
+	// have, so they are strings; readers ignore the decimal logical type of strings
+	dosa.TDecimal: &logicalSchema{Schema: &gv.StringSchema{}, logicalType: "decimal"},
+	dosa.TDate:    &logicalSchema{Schema: &gv.IntSchema{}, logicalType: "date"},
+}
+
+// logicalSchema is an avro primitive type annotated with a logical type
+type logicalSchema struct {
+	gv.Schema
+	logicalType string
+}
+
+// Prop returns the logical type for the logicalType property
+func (s *logicalSchema) Prop(key string) (interface{}, bool) {
+	if key == logicalTypeKey {
+		return s.logicalType, true
+	}
+	return s.Schema.Prop(key)
+}
+
+// String returns a JSON representation of the logical type.
+func (s *logicalSchema) String() string {
+	bytes, err := s.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+	return string(bytes)
+}
+
+// MarshalJSON serializes the primitive type with its logical type as JSON.
+func (s *logicalSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"type":         s.GetName(),
+		logicalTypeKey: s.logicalType,
+	})
+}
+
+// avroType returns the avro type of a dosa type. Lists and sets are arrays, and
+// maps are avro maps, whose keys are strings; the dosaType property of each field
+// keeps the exact dosa type.
+func avroType(t dosa.Type) gv.Schema {
+	switch {
+	case t.IsList(), t.IsSet():
+		return &gv.ArraySchema{Items: avroTypes[t.Elem()]}
+	case t.IsMap():
+		return &gv.MapSchema{Values: avroTypes[t.Elem()]}
+	}
+	return avroTypes[t]
 }
 
 // Record implements Schema and represents Avro record type.
@@ -103,7 +155,7 @@ type Field struct {
 	Doc        string      `json:"doc,omitempty"`
 	Default    interface{} `json:"default"`
 	Type       gv.Schema   `json:"type,omitempty"`
-	Properties map[string]string
+	Properties map[string]interface{}
 }
 
 // MarshalJSON serializes the given schema field as JSON.
@@ -129,16 +181,25 @@ func (s *Field) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
-// ToAvro converts dosa entity definition to avro schema
-func ToAvro(fqn dosa.FQN, ed *dosa.EntityDefinition) ([]byte, error) {
+// ToAvro converts dosa entity definition to avro schema. The record is
+// named after the entity, in the fqn namespace. Nullable columns are unions
+// with null, and the column tags, primary key and indexes are properties,
+// so that FromAvro gets all of them back.
+func ToAvro(fqn dosa.FQN, ed *dosa.EntityDefinition, indexes ...*dosa.IndexDefinition) ([]byte, error) {
 	fields := make([]*Field, len(ed.Columns))
 	for i, c := range ed.Columns {
-		props := make(map[string]string)
+		props := make(map[string]interface{})
 		props[dosaTypeKey] = c.Type.String()
-		// TODO add tags
+		if len(c.Tags) > 0 {
+			props[tagsKey] = c.Tags
+		}
+		typ := avroType(c.Type)
+		if c.IsPointer {
+			typ = &gv.UnionSchema{Types: []gv.Schema{&gv.NullSchema{}, typ}}
+		}
 		fields[i] = &Field{
 			Name:       c.Name,
-			Type:       avroTypes[c.Type],
+			Type:       typ,
 			Properties: props,
 			Default:    nil,
 		}
@@ -147,6 +208,9 @@ func ToAvro(fqn dosa.FQN, ed *dosa.EntityDefinition) ([]byte, error) {
 	meta := make(map[string]interface{})
 	meta[partitionKeys] = ed.Key.PartitionKeys
 	meta[clusteringKeys] = ed.Key.ClusteringKeys
+	if len(indexes) > 0 {
+		meta[indexesKey] = indexes
+	}
 
 	ar := &Record{
 		Name:       ed.Name,
@@ -164,29 +228,41 @@ func ToAvro(fqn dosa.FQN, ed *dosa.EntityDefinition) ([]byte, error) {
 
 // FromAvro converts avro schema to dosa entity definition
 func FromAvro(data string) (*dosa.EntityDefinition, error) {
+	ed, _, err := FromAvroWithIndexes(data)
+	return ed, err
+}
+
+// FromAvroWithIndexes converts avro schema written by ToAvro to the dosa
+// entity definition and indexes it was written from
+func FromAvroWithIndexes(data string) (*dosa.EntityDefinition, []*dosa.IndexDefinition, error) {
 	schema, err := gv.ParseSchema(data)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse avro schema from json")
+		return nil, nil, errors.Wrap(err, "failed to parse avro schema from json")
 	}
 
 	pks, err := decodePartitionKeys(schema)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse avro schema for partition keys")
+		return nil, nil, errors.Wrap(err, "failed to parse avro schema for partition keys")
 	}
 
 	cks, err := decodeClusteringKeys(schema)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse avro schema for clustering keys")
+		return nil, nil, errors.Wrap(err, "failed to parse avro schema for clustering keys")
 	}
 
 	rs, ok := schema.(*gv.RecordSchema)
 	if !ok {
-		return nil, errors.New("fail to parse avro schema")
+		return nil, nil, errors.New("fail to parse avro schema")
 	}
 
 	cols, err := decodeFields(rs.Fields)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse avro schema for fields")
+		return nil, nil, errors.Wrap(err, "failed to parse avro schema for fields")
+	}
+
+	indexes, err := decodeIndexes(schema)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse avro schema for indexes")
 	}
 
 	return &dosa.EntityDefinition{
@@ -196,7 +272,7 @@ func FromAvro(data string) (*dosa.EntityDefinition, error) {
 			ClusteringKeys: cks,
 		},
 		Columns: cols,
-	}, nil
+	}, indexes, nil
 }
 
 func decodeFields(fields []*gv.SchemaField) ([]*dosa.ColumnDefinition, error) {
@@ -213,14 +289,51 @@ func decodeFields(fields []*gv.SchemaField) ([]*dosa.ColumnDefinition, error) {
 		}
 
 		col := &dosa.ColumnDefinition{
-			Name: f.Name,
-			Type: dosa.FromString(t),
+			Name:      f.Name,
+			Type:      dosa.FromString(t),
+			IsPointer: isNullable(f.Type),
+		}
+		if tags, ok := f.Prop(tagsKey); ok {
+			realTags, ok := tags.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("failed to parse tags of field %s: %v", f.Name, tags)
+			}
+			col.Tags = make(map[string]string, len(realTags))
+			for name, value := range realTags {
+				if col.Tags[name], ok = value.(string); !ok {
+					return nil, fmt.Errorf("failed to convert tag %s of field %s to string: %v", name, f.Name, value)
+				}
+			}
 		}
 		cols[i] = col
 	}
 	return cols, nil
 }
 
+// isNullable checks if a field type is a union with null, as ToAvro writes
+// the types of nullable columns
+func isNullable(schema gv.Schema) bool {
+	union, ok := schema.(*gv.UnionSchema)
+	return ok && len(union.Types) > 0 && union.Types[0].Type() == gv.Null
+}
+
+// decodeIndexes reads back the indexes, which are encoded as json by ToAvro
+func decodeIndexes(schema gv.Schema) ([]*dosa.IndexDefinition, error) {
+	prop, ok := schema.Prop(indexesKey)
+	if !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(prop)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []*dosa.IndexDefinition
+	if err := json.Unmarshal(data, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to parse indexes: %v", prop)
+	}
+	return indexes, nil
+}
+
 func decodePartitionKeys(schema gv.Schema) ([]string, error) {
 	if prop, ok := schema.Prop(partitionKeys); ok {
 		realPks, ok := prop.([]interface{})
@@ -242,10 +355,17 @@ func decodePartitionKeys(schema gv.Schema) ([]string, error) {
 
 func decodeClusteringKeys(schema gv.Schema) ([]*dosa.ClusteringKey, error) {
 	if prop, ok := schema.Prop(clusteringKeys); ok {
+		// entities without clustering keys have null or no clustering keys
+		if prop == nil {
+			return nil, nil
+		}
 		realCks, ok := prop.([]interface{})
 		if !ok {
 			return nil, fmt.Errorf("failed to parse clustering keys: %v", prop)
 		}
+		if len(realCks) == 0 {
+			return nil, nil
+		}
 
 		cks := make([]*dosa.ClusteringKey, len(realCks))
 		for i, v := range realCks {
@@ -21,8 +21,13 @@
 package avro
 
 import (
+	"fmt"
+	"math/rand"
+	"reflect"
 	"testing"
+	"testing/quick"
 
+	gv "github.com/elodina/go-avro"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/uber-go/dosa"
@@ -64,6 +69,26 @@ func createEntityDefinition() *dosa.EntityDefinition {
 				Name: "timestampcol",
 				Type: dosa.Timestamp,
 			},
+			{
+				Name: "listcol",
+				Type: dosa.ListOf(dosa.String),
+			},
+			{
+				Name: "setcol",
+				Type: dosa.SetOf(dosa.Int64),
+			},
+			{
+				Name: "mapcol",
+				Type: dosa.MapOf(dosa.TUUID, dosa.Double),
+			},
+			{
+				Name: "decimalcol",
+				Type: dosa.TDecimal,
+			},
+			{
+				Name: "datecol",
+				Type: dosa.TDate,
+			},
 		},
 		Key: &dosa.PrimaryKey{
 			PartitionKeys: []string{
@@ -90,11 +115,133 @@ func TestToAvroSchema(t *testing.T) {
 	assert.NoError(t, err)
 	av, err := ToAvro(fqn, ed)
 	assert.NoError(t, err)
+	schema, err := gv.ParseSchema(string(av))
+	assert.NoError(t, err)
+	fields := schema.(*gv.RecordSchema).Fields
+	assert.IsType(t, &gv.ArraySchema{}, fields[8].Type)
+	assert.IsType(t, &gv.ArraySchema{}, fields[9].Type)
+	assert.IsType(t, &gv.MapSchema{}, fields[10].Type)
+	assert.Equal(t, gv.String, fields[11].Type.Type())
+	assert.Equal(t, gv.Int, fields[12].Type.Type())
+	assert.Contains(t, string(av), `"logicalType":"decimal"`)
+	assert.Contains(t, string(av), `"logicalType":"date"`)
 	ed1, err := FromAvro(string(av))
 	assert.NoError(t, err)
 	assert.Equal(t, ed, ed1)
 }
 
+func TestToAvroSchema_TagsNullableIndexes(t *testing.T) {
+	ed := createEntityDefinition()
+	ed.Columns[0].Tags = map[string]string{"pii": "", "searchable": "true"}
+	ed.Columns[3].IsPointer = true
+	ed.Columns[11].IsPointer = true
+	indexes := []*dosa.IndexDefinition{
+		{Name: "by_long", Key: &dosa.PrimaryKey{PartitionKeys: []string{"longcol"}}},
+		{Name: "by_int", Key: &dosa.PrimaryKey{PartitionKeys: []string{"int32col"}, ClusteringKeys: []*dosa.ClusteringKey{{Name: "datecol", Descending: true}}}},
+	}
+	av, err := ToAvro("", ed, indexes...)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(av), "namespace")
+
+	schema, err := gv.ParseSchema(string(av))
+	assert.NoError(t, err)
+	fields := schema.(*gv.RecordSchema).Fields
+	assert.IsType(t, &gv.UnionSchema{}, fields[3].Type)
+	assert.Equal(t, gv.Null, fields[3].Type.(*gv.UnionSchema).Types[0].Type())
+	assert.Nil(t, fields[3].Default)
+	tags, ok := fields[0].Prop("tags")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"pii": "", "searchable": "true"}, tags)
+
+	ed1, indexes1, err := FromAvroWithIndexes(string(av))
+	assert.NoError(t, err)
+	assert.Equal(t, ed, ed1)
+	assert.Equal(t, indexes, indexes1)
+}
+
+// randomEntity generates entity definitions and indexes for property tests
+type randomEntity struct {
+	ed      *dosa.EntityDefinition
+	indexes []*dosa.IndexDefinition
+}
+
+var primitiveTypes = []dosa.Type{dosa.TUUID, dosa.String, dosa.Int32, dosa.Int64, dosa.Double,
+	dosa.Blob, dosa.Timestamp, dosa.Bool, dosa.Counter, dosa.TDecimal, dosa.TDate}
+
+// randomType returns a primitive type, or a list, set or map of them
+func randomType(r *rand.Rand) dosa.Type {
+	// counters cannot be elements, and blobs cannot be keys
+	elem := func(key bool) dosa.Type {
+		for {
+			t := primitiveTypes[r.Intn(len(primitiveTypes))]
+			if t != dosa.Counter && !(key && t == dosa.Blob) {
+				return t
+			}
+		}
+	}
+	switch r.Intn(5) {
+	case 0:
+		return dosa.ListOf(elem(false))
+	case 1:
+		return dosa.SetOf(elem(true))
+	case 2:
+		return dosa.MapOf(elem(true), elem(false))
+	}
+	return primitiveTypes[r.Intn(len(primitiveTypes))]
+}
+
+// randomKey returns a key of random columns, with at least one partition key
+func randomKey(r *rand.Rand, columns []*dosa.ColumnDefinition) *dosa.PrimaryKey {
+	key := &dosa.PrimaryKey{}
+	for i, j := range r.Perm(len(columns))[:1+r.Intn(len(columns))] {
+		if i == 0 || r.Intn(2) == 0 && len(key.ClusteringKeys) == 0 {
+			key.PartitionKeys = append(key.PartitionKeys, columns[j].Name)
+			continue
+		}
+		key.ClusteringKeys = append(key.ClusteringKeys, &dosa.ClusteringKey{Name: columns[j].Name, Descending: r.Intn(2) == 0})
+	}
+	return key
+}
+
+// Generate implements quick.Generator
+func (randomEntity) Generate(r *rand.Rand, size int) reflect.Value {
+	ed := &dosa.EntityDefinition{Name: fmt.Sprintf("entity%d", r.Intn(1000))}
+	for i := 0; i < 1+r.Intn(size+1); i++ {
+		cd := &dosa.ColumnDefinition{Name: fmt.Sprintf("col%d", i), Type: randomType(r), IsPointer: r.Intn(2) == 0}
+		if n := r.Intn(3); n > 0 {
+			cd.Tags = make(map[string]string, n)
+			for j := 0; j < n; j++ {
+				cd.Tags[fmt.Sprintf("tag%d", r.Intn(10))] = fmt.Sprintf("value%d", r.Intn(3))
+			}
+		}
+		ed.Columns = append(ed.Columns, cd)
+	}
+	ed.Key = randomKey(r, ed.Columns)
+
+	e := randomEntity{ed: ed}
+	for i := 0; i < r.Intn(3); i++ {
+		e.indexes = append(e.indexes, &dosa.IndexDefinition{Name: fmt.Sprintf("index%d", i), Key: randomKey(r, ed.Columns)})
+	}
+	return reflect.ValueOf(e)
+}
+
+func TestAvroRoundTrip(t *testing.T) {
+	roundTrip := func(e randomEntity) bool {
+		av, err := ToAvro("scope.prefix", e.ed, e.indexes...)
+		if err != nil {
+			t.Logf("ToAvro: %v", err)
+			return false
+		}
+		ed, indexes, err := FromAvroWithIndexes(string(av))
+		if err != nil {
+			t.Logf("FromAvroWithIndexes: %v\n%s", err, av)
+			return false
+		}
+		return assert.Equal(t, e.ed, ed) && assert.Equal(t, e.indexes, indexes)
+	}
+	assert.NoError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 500}))
+}
+
 func TestDecodeFailure(t *testing.T) {
 	data := []struct {
 		Schema string
@@ -249,3 +396,52 @@ func TestDecodeFailure(t *testing.T) {
 		assert.Contains(t, err.Error(), d.Err.Error())
 	}
 }
+
+func TestDecodeFailure_TagsIndexes(t *testing.T) {
+	data := []struct {
+		Schema string
+		Err    error
+	}{
+		{
+			Schema: `{
+				"clusteringKeys":null,
+				"fields":[
+					{"dosaType":"String","name":"stringcol","type":"string","tags":["pii"]}
+				],
+				"name":"test",
+				"partitionKeys":["stringcol"],
+				"type":"record"
+			}`,
+			Err: errors.New("failed to parse tags of field stringcol"),
+		},
+		{
+			Schema: `{
+				"clusteringKeys":null,
+				"fields":[
+					{"dosaType":"String","name":"stringcol","type":"string","tags":{"pii":true}}
+				],
+				"name":"test",
+				"partitionKeys":["stringcol"],
+				"type":"record"
+			}`,
+			Err: errors.New("failed to convert tag pii of field stringcol to string"),
+		},
+		{
+			Schema: `{
+				"clusteringKeys":null,
+				"fields":[
+					{"dosaType":"String","name":"stringcol","type":"string"}
+				],
+				"indexes":{"Name":"by_string"},
+				"name":"test",
+				"partitionKeys":["stringcol"],
+				"type":"record"
+			}`,
+			Err: errors.New("failed to parse indexes"),
+		},
+	}
+	for _, d := range data {
+		_, _, err := FromAvroWithIndexes(d.Schema)
+		assert.Contains(t, err.Error(), d.Err.Error())
+	}
+}
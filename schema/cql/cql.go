@@ -30,6 +30,14 @@ import (
 // typeMap returns the CQL type associated with the given dosa.Type,
 // used in the template
 func typeMap(t dosa.Type) string {
+	switch {
+	case t.IsList():
+		return "list<" + typeMap(t.Elem()) + ">"
+	case t.IsSet():
+		return "set<" + typeMap(t.Elem()) + ">"
+	case t.IsMap():
+		return "map<" + typeMap(t.Key()) + ", " + typeMap(t.Elem()) + ">"
+	}
 	switch t {
 	case dosa.String:
 		return "text"
@@ -47,6 +55,12 @@ func typeMap(t dosa.Type) string {
 		return "timestamp"
 	case dosa.TUUID:
 		return "uuid"
+	case dosa.Counter:
+		return "counter"
+	case dosa.TDecimal:
+		return "decimal"
+	case dosa.TDate:
+		return "date"
 	}
 	return "unknown"
 }
@@ -48,6 +48,27 @@ type SinglePrimaryKey struct {
 	Data        string
 }
 
+type Counters struct {
+	dosa.Entity `dosa:"primaryKey=(PrimaryKey)"`
+	PrimaryKey  string
+	Hits        dosa.CounterValue
+}
+
+type Collections struct {
+	dosa.Entity `dosa:"primaryKey=(PrimaryKey)"`
+	PrimaryKey  string
+	Tags        []string
+	Labels      map[dosa.UUID]dosa.Set
+	Attrs       map[string]int64
+}
+
+type Ledger struct {
+	dosa.Entity `dosa:"primaryKey=(Account, Day)"`
+	Account     string
+	Day         dosa.Date
+	Balance     dosa.Decimal
+}
+
 func TestCQL(t *testing.T) {
 	data := []struct {
 		Instance  dosa.DomainObject
@@ -61,6 +82,18 @@ func TestCQL(t *testing.T) {
 			Instance:  &AllTypes{},
 			Statement: `create table "alltypes" ("booltype" boolean, "int32type" int, "int64type" bigint, "doubletype" double, "stringtype" text, "blobtype" blob, "timetype" timestamp, "uuidtype" uuid, primary key (booltype));`,
 		},
+		{
+			Instance:  &Counters{},
+			Statement: `create table "counters" ("primarykey" text, "hits" counter, primary key (primarykey));`,
+		},
+		{
+			Instance:  &Collections{},
+			Statement: `create table "collections" ("primarykey" text, "tags" list<text>, "labels" set<uuid>, "attrs" map<text, bigint>, primary key (primarykey));`,
+		},
+		{
+			Instance:  &Ledger{},
+			Statement: `create table "ledger" ("account" text, "day" date, "balance" decimal, primary key (account, day ASC));`,
+		},
 		// TODO: Add more test cases
 	}
 
@@ -69,6 +102,11 @@ func TestCQL(t *testing.T) {
 		assert.Nil(t, err) // this code does not test TableFromInstance
 		statement := ToCQL(&table.EntityDefinition)
 		assert.Equal(t, statement, d.Statement, fmt.Sprintf("Instance: %T", d.Instance))
+
+		// the statement parses back to the same entity
+		eds, err := FromCQL(statement)
+		assert.NoError(t, err, statement)
+		assert.Equal(t, []*dosa.EntityDefinition{&table.EntityDefinition}, eds)
 	}
 }
 
@@ -0,0 +1,629 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/uber-go/dosa"
+)
+
+// cqlTypes maps the CQL native types to the primitive dosa types. Types
+// that can hold values the dosa type cannot, or the other way around, like
+// float or varint, are not supported.
+var cqlTypes = map[string]dosa.Type{
+	"ascii":     dosa.String,
+	"text":      dosa.String,
+	"varchar":   dosa.String,
+	"blob":      dosa.Blob,
+	"boolean":   dosa.Bool,
+	"double":    dosa.Double,
+	"int":       dosa.Int32,
+	"bigint":    dosa.Int64,
+	"timestamp": dosa.Timestamp,
+	"uuid":      dosa.TUUID,
+	"timeuuid":  dosa.TUUID,
+	"counter":   dosa.Counter,
+	"decimal":   dosa.TDecimal,
+	"date":      dosa.TDate,
+}
+
+// ParseError is an error found at a line and column of the parsed CQL,
+// both starting at 1
+type ParseError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+// Error returns the position and message of the error, e.g.
+// "3:12: unsupported type varint of column "total""
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// FromCQL parses the CREATE TABLE statements of a CQL script, such as the
+// statements written by ToCQL or the output of cqlsh DESCRIBE KEYSPACE,
+// into entity definitions. Other CREATE statements, like keyspaces,
+// indexes and types, are skipped. Errors are *ParseError values.
+func FromCQL(data string) ([]*dosa.EntityDefinition, error) {
+	tokens, err := lex(data)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var eds []*dosa.EntityDefinition
+	for !p.at(tokEOF) {
+		if p.is(";") {
+			p.next()
+			continue
+		}
+		if !p.is("create") {
+			return nil, p.errorf("expected CREATE, found %s", p.tok())
+		}
+		if kind := p.peek(1); !kind.is("table") && !kind.is("columnfamily") {
+			p.skipStatement()
+			continue
+		}
+		ed, err := p.createTable()
+		if err != nil {
+			return nil, err
+		}
+		eds = append(eds, ed)
+	}
+	return eds, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokIdent            // unquoted identifier or keyword
+	tokQuoted           // "quoted identifier"
+	tokString           // 'string constant'
+	tokNumber           // numeric constant
+	tokPunct            // any other single character
+)
+
+type token struct {
+	kind      tokenKind
+	text      string // the unquoted text of quoted identifiers and strings
+	line, col int
+}
+
+// is checks if the token is a keyword or punctuation, ignoring case
+func (t token) is(text string) bool {
+	return (t.kind == tokIdent || t.kind == tokPunct) && strings.EqualFold(t.text, text)
+}
+
+// String describes the token for error messages
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokQuoted:
+		return `"` + t.text + `"`
+	case tokString:
+		return "'" + t.text + "'"
+	}
+	return t.text
+}
+
+// lex splits CQL into tokens, skipping whitespace and the three kinds of comments
+func lex(data string) ([]token, error) {
+	runes := []rune(data)
+	line, col := 1, 1
+	var tokens []token
+	advance := func(n int) {
+		for _, r := range runes[:n] {
+			if r == '\n' {
+				line, col = line+1, 1
+			} else {
+				col++
+			}
+		}
+		runes = runes[n:]
+	}
+	// until returns the length up to and including the closing delimiter,
+	// or -1 if it is missing
+	until := func(start int, delim string) int {
+		for i := start; i+len(delim) <= len(runes); i++ {
+			if string(runes[i:i+len(delim)]) == delim {
+				return i + len(delim)
+			}
+		}
+		return -1
+	}
+
+	for len(runes) > 0 {
+		r := runes[0]
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+		case r == '-' && len(runes) > 1 && runes[1] == '-', r == '/' && len(runes) > 1 && runes[1] == '/':
+			n := until(2, "\n")
+			if n < 0 {
+				n = len(runes)
+			}
+			advance(n)
+		case r == '/' && len(runes) > 1 && runes[1] == '*':
+			n := until(2, "*/")
+			if n < 0 {
+				return nil, &ParseError{Line: line, Column: col, Msg: "unterminated comment"}
+			}
+			advance(n)
+		case r == '"' || r == '\'':
+			// quotes are escaped by doubling them
+			var text []rune
+			n := 1
+			for ; n < len(runes); n++ {
+				if runes[n] == r {
+					if n+1 < len(runes) && runes[n+1] == r {
+						n++
+					} else {
+						break
+					}
+				}
+				text = append(text, runes[n])
+			}
+			if n == len(runes) {
+				return nil, &ParseError{Line: line, Column: col, Msg: "unterminated quoted text"}
+			}
+			kind := tokQuoted
+			if r == '\'' {
+				kind = tokString
+			}
+			tokens = append(tokens, token{kind: kind, text: string(text), line: line, col: col})
+			advance(n + 1)
+		case unicode.IsDigit(r):
+			n := 1
+			for n < len(runes) && (isIdentRune(runes[n]) || runes[n] == '.') {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: string(runes[:n]), line: line, col: col})
+			advance(n)
+		case isIdentRune(r):
+			n := 1
+			for n < len(runes) && isIdentRune(runes[n]) {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[:n]), line: line, col: col})
+			advance(n)
+		default:
+			tokens = append(tokens, token{kind: tokPunct, text: string(r), line: line, col: col})
+			advance(1)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, line: line, col: col}), nil
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// parser walks the tokens of CQL statements
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) tok() token {
+	return p.tokens[p.i]
+}
+
+// peek returns a following token, or the final EOF token
+func (p *parser) peek(n int) token {
+	if p.i+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.i+n]
+}
+
+func (p *parser) next() token {
+	t := p.tok()
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) at(kind tokenKind) bool {
+	return p.tok().kind == kind
+}
+
+func (p *parser) is(text string) bool {
+	return p.tok().is(text)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errorAt(p.tok(), format, args...)
+}
+
+func errorAt(t token, format string, args ...interface{}) error {
+	return &ParseError{Line: t.line, Column: t.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// expect consumes a keyword or punctuation
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf("expected %s, found %s", strings.ToUpper(text), p.tok())
+	}
+	p.next()
+	return nil
+}
+
+// accept consumes a keyword or punctuation if it is next
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// skipStatement skips to the end of the current statement
+func (p *parser) skipStatement() {
+	depth := 0
+	for !p.at(tokEOF) {
+		t := p.next()
+		switch {
+		case t.is("(") || t.is("{") || t.is("["):
+			depth++
+		case t.is(")") || t.is("}") || t.is("]"):
+			depth--
+		case t.is(";") && depth <= 0:
+			return
+		}
+	}
+}
+
+// name parses an identifier. Unquoted identifiers are case insensitive, but
+// quoted ones are not, so they must already be valid lower case names.
+func (p *parser) name() (string, error) {
+	t := p.tok()
+	if t.kind != tokIdent && t.kind != tokQuoted {
+		return "", p.errorf("expected a name, found %s", t)
+	}
+	name, err := dosa.NormalizeName(t.text)
+	if err != nil {
+		return "", p.errorf("invalid name %s: %s", t, err)
+	}
+	if t.kind == tokQuoted && name != t.text {
+		return "", p.errorf("case sensitive name %s is not supported", t)
+	}
+	p.next()
+	return name, nil
+}
+
+// createTable parses
+//
+//	CREATE TABLE [IF NOT EXISTS] [keyspace.]name (
+//	    column type [PRIMARY KEY], ...
+//	    [PRIMARY KEY (key)]
+//	) [WITH CLUSTERING ORDER BY (column ASC|DESC, ...) [AND option = value ...]];
+func (p *parser) createTable() (*dosa.EntityDefinition, error) {
+	start := p.next()
+	p.next() // TABLE or COLUMNFAMILY
+	if p.accept("if") {
+		if err := p.expect("not"); err != nil {
+			return nil, err
+		}
+		if err := p.expect("exists"); err != nil {
+			return nil, err
+		}
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept(".") {
+		// the keyspace is not part of the entity
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	ed := &dosa.EntityDefinition{Name: name}
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for {
+		if p.is("primary") {
+			keyToken := p.next()
+			if err := p.expect("key"); err != nil {
+				return nil, err
+			}
+			if ed.Key != nil {
+				return nil, errorAt(keyToken, "table %q has more than one primary key", name)
+			}
+			if ed.Key, err = p.primaryKey(); err != nil {
+				return nil, err
+			}
+		} else {
+			cd, inlineKey, err := p.columnDefinition()
+			if err != nil {
+				return nil, err
+			}
+			ed.Columns = append(ed.Columns, cd)
+			if inlineKey {
+				if ed.Key != nil {
+					return nil, p.errorf("table %q has more than one primary key", name)
+				}
+				ed.Key = &dosa.PrimaryKey{PartitionKeys: []string{cd.Name}}
+			}
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if ed.Key == nil {
+		return nil, errorAt(start, "table %q has no primary key", name)
+	}
+
+	if p.accept("with") {
+		if err := p.tableOptions(ed); err != nil {
+			return nil, err
+		}
+	}
+	if !p.at(tokEOF) {
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ed.EnsureValid(); err != nil {
+		return nil, errorAt(start, "invalid table %q: %s", name, err)
+	}
+	return ed, nil
+}
+
+// columnDefinition parses a column and its type, and reports whether it is
+// declared as the primary key
+func (p *parser) columnDefinition() (*dosa.ColumnDefinition, bool, error) {
+	column := p.tok()
+	name, err := p.name()
+	if err != nil {
+		return nil, false, err
+	}
+	typeToken := p.tok()
+	typ, text, err := p.columnType()
+	if err != nil {
+		return nil, false, err
+	}
+	if typ == dosa.Invalid {
+		return nil, false, errorAt(typeToken, "unsupported type %s of column %s", text, column)
+	}
+	if p.is("static") {
+		return nil, false, p.errorf("static column %s is not supported", column)
+	}
+	inlineKey := false
+	if p.accept("primary") {
+		if err := p.expect("key"); err != nil {
+			return nil, false, err
+		}
+		inlineKey = true
+	}
+	return &dosa.ColumnDefinition{Name: name, Type: typ}, inlineKey, nil
+}
+
+// columnType parses a type, returning its text and dosa type, which is
+// Invalid when it is not supported
+func (p *parser) columnType() (dosa.Type, string, error) {
+	t := p.tok()
+	if t.kind != tokIdent && t.kind != tokQuoted {
+		return dosa.Invalid, "", p.errorf("expected a type, found %s", t)
+	}
+	p.next()
+	name := strings.ToLower(t.text)
+	if t.kind == tokQuoted || !p.is("<") {
+		// quoted names are user defined types
+		if t.kind == tokQuoted {
+			return dosa.Invalid, t.String(), nil
+		}
+		return cqlTypes[name], name, nil
+	}
+
+	// parameterized types: list<t>, set<t>, map<k, v>, frozen<t>, tuple<t, ...>
+	p.next()
+	var params []dosa.Type
+	var texts []string
+	for {
+		param, text, err := p.columnType()
+		if err != nil {
+			return dosa.Invalid, "", err
+		}
+		params = append(params, param)
+		texts = append(texts, text)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(">"); err != nil {
+		return dosa.Invalid, "", err
+	}
+	text := name + "<" + strings.Join(texts, ", ") + ">"
+	switch {
+	case name == "list" && len(params) == 1:
+		return dosa.ListOf(params[0]), text, nil
+	case name == "set" && len(params) == 1:
+		return dosa.SetOf(params[0]), text, nil
+	case name == "map" && len(params) == 2:
+		return dosa.MapOf(params[0], params[1]), text, nil
+	}
+	return dosa.Invalid, text, nil
+}
+
+// primaryKey parses (pk, ck, ...) or ((pk1, pk2), ck, ...). ToCQL writes
+// the order of the clustering keys in the key, e.g. (pk, ck DESC), which is
+// accepted as well.
+func (p *parser) primaryKey() (*dosa.PrimaryKey, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	key := &dosa.PrimaryKey{}
+	if p.accept("(") {
+		for {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			key.PartitionKeys = append(key.PartitionKeys, name)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		key.PartitionKeys = []string{name}
+	}
+	for p.accept(",") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ck := &dosa.ClusteringKey{Name: name}
+		if p.accept("desc") {
+			ck.Descending = true
+		} else {
+			p.accept("asc")
+		}
+		key.ClusteringKeys = append(key.ClusteringKeys, ck)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// tableOptions parses the options following WITH. Only the clustering order
+// matters to dosa, the other options are skipped.
+func (p *parser) tableOptions(ed *dosa.EntityDefinition) error {
+	for {
+		switch {
+		case p.is("clustering"):
+			if err := p.clusteringOrder(ed.Key); err != nil {
+				return err
+			}
+		case p.accept("compact"):
+			if err := p.expect("storage"); err != nil {
+				return err
+			}
+		default:
+			if !p.at(tokIdent) {
+				return p.errorf("expected a table option, found %s", p.tok())
+			}
+			p.next()
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		if !p.accept("and") {
+			return nil
+		}
+	}
+}
+
+// clusteringOrder parses CLUSTERING ORDER BY (ck ASC|DESC, ...), which must
+// list the clustering keys in order
+func (p *parser) clusteringOrder(key *dosa.PrimaryKey) error {
+	for _, kw := range []string{"clustering", "order", "by", "("} {
+		if err := p.expect(kw); err != nil {
+			return err
+		}
+	}
+	for i := 0; ; i++ {
+		t := p.tok()
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if i >= len(key.ClusteringKeys) || key.ClusteringKeys[i].Name != name {
+			return errorAt(t, "clustering order of %s does not follow the clustering keys %s",
+				t, formatClusteringKeys(key.ClusteringKeys))
+		}
+		switch {
+		case p.accept("desc"):
+			key.ClusteringKeys[i].Descending = true
+		case p.accept("asc"):
+			key.ClusteringKeys[i].Descending = false
+		default:
+			return p.errorf("expected ASC or DESC, found %s", p.tok())
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	return p.expect(")")
+}
+
+// skipValue skips an option value: a constant, or a map or list of them
+func (p *parser) skipValue() error {
+	t := p.tok()
+	switch {
+	case t.kind == tokString, t.kind == tokNumber, t.kind == tokIdent:
+		p.next()
+		return nil
+	case t.is("-"):
+		p.next()
+		return p.skipValue()
+	case t.is("{"), t.is("["):
+		closing := "}"
+		if t.is("[") {
+			closing = "]"
+		}
+		p.next()
+		for depth := 1; depth > 0; {
+			switch {
+			case p.at(tokEOF):
+				return p.errorf("expected %s, found %s", closing, p.tok())
+			case p.is("{"), p.is("["):
+				depth++
+			case p.is("}"), p.is("]"):
+				depth--
+			}
+			p.next()
+		}
+		return nil
+	}
+	return p.errorf("expected an option value, found %s", t)
+}
+
+func formatClusteringKeys(cks []*dosa.ClusteringKey) string {
+	names := make([]string, len(cks))
+	for i, ck := range cks {
+		names[i] = ck.Name
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
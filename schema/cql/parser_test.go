@@ -0,0 +1,192 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+const keyspaceDump = `
+CREATE KEYSPACE ledger WITH replication = {'class': 'SimpleStrategy', 'replication_factor': '1'}  AND durable_writes = true;
+
+/* accounts, one row per account */
+CREATE TABLE IF NOT EXISTS ledger."account" (
+    id uuid PRIMARY KEY,
+    "owner" text, -- display name
+    balance decimal,
+    opened date
+) WITH bloom_filter_fp_chance = 0.01
+    AND caching = {'keys': 'ALL', 'rows_per_partition': 'NONE'}
+    AND comment = 'it''s an account'
+    AND default_time_to_live = 0
+    AND read_repair_chance = 0.0;
+
+CREATE INDEX account_owner ON ledger.account (owner);
+
+// entries of an account, newest first
+CREATE TABLE ledger.entry (
+    account uuid,
+    day date,
+    seq bigint,
+    amount decimal,
+    memo varchar,
+    cleared boolean,
+    PRIMARY KEY ((account, day), seq)
+) WITH CLUSTERING ORDER BY (seq DESC)
+    AND compaction = {'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'};
+`
+
+func TestFromCQL_Dump(t *testing.T) {
+	eds, err := FromCQL(keyspaceDump)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.EntityDefinition{
+		{
+			Name: "account",
+			Key: &dosa.PrimaryKey{
+				PartitionKeys: []string{"id"},
+			},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "id", Type: dosa.TUUID},
+				{Name: "owner", Type: dosa.String},
+				{Name: "balance", Type: dosa.TDecimal},
+				{Name: "opened", Type: dosa.TDate},
+			},
+		},
+		{
+			Name: "entry",
+			Key: &dosa.PrimaryKey{
+				PartitionKeys: []string{"account", "day"},
+				ClusteringKeys: []*dosa.ClusteringKey{
+					{Name: "seq", Descending: true},
+				},
+			},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "account", Type: dosa.TUUID},
+				{Name: "day", Type: dosa.TDate},
+				{Name: "seq", Type: dosa.Int64},
+				{Name: "amount", Type: dosa.TDecimal},
+				{Name: "memo", Type: dosa.String},
+				{Name: "cleared", Type: dosa.Bool},
+			},
+		},
+	}, eds)
+}
+
+func TestFromCQL_InlineClusteringOrder(t *testing.T) {
+	eds, err := FromCQL(`create table t (a int, b timeuuid, c counter, primary key (a, b desc))`)
+	assert.NoError(t, err)
+	if assert.Len(t, eds, 1) {
+		assert.Equal(t, []*dosa.ClusteringKey{{Name: "b", Descending: true}}, eds[0].Key.ClusteringKeys)
+		assert.Equal(t, dosa.Counter, eds[0].FindColumnDefinition("c").Type)
+	}
+}
+
+func TestFromCQL_Empty(t *testing.T) {
+	eds, err := FromCQL("-- nothing here\n;")
+	assert.NoError(t, err)
+	assert.Empty(t, eds)
+}
+
+func TestFromCQL_Errors(t *testing.T) {
+	data := []struct {
+		cql    string
+		errmsg string
+	}{
+		{
+			cql:    "create table t (\n    a varint, primary key (a));",
+			errmsg: `2:7: unsupported type varint of column a`,
+		},
+		{
+			cql:    "create table t (\n  a float,\n  b text, primary key (b));",
+			errmsg: `2:5: unsupported type float of column a`,
+		},
+		{
+			cql:    `create table t (a text, b list<frozen<"MyType">>, primary key (a));`,
+			errmsg: `1:27: unsupported type list<frozen<"MyType">> of column b`,
+		},
+		{
+			cql:    `create table t (a text, b map<text, tuple<int, int>>, primary key (a));`,
+			errmsg: `1:27: unsupported type map<text, tuple<int, int>> of column b`,
+		},
+		{
+			cql:    `create table t (a text, b text static, primary key (a));`,
+			errmsg: `1:32: static column b is not supported`,
+		},
+		{
+			cql:    `create table t ("a" text primary key (a));`,
+			errmsg: `1:38: expected ), found (`,
+		},
+		{
+			cql:    `create table "T" (a text primary key);`,
+			errmsg: `1:14: case sensitive name "T" is not supported`,
+		},
+		{
+			cql:    `create table t (a text primary key, b text primary key);`,
+			errmsg: `1:55: table "t" has more than one primary key`,
+		},
+		{
+			cql:    `create table t (a text);`,
+			errmsg: `1:1: table "t" has no primary key`,
+		},
+		{
+			cql:    `create table t (a text, primary key (b));`,
+			errmsg: `1:1: invalid table "t"`,
+		},
+		{
+			cql:    `create table t (a int, b int, c int, primary key (a, b, c)) with clustering order by (c asc);`,
+			errmsg: `clustering order of c does not follow the clustering keys (b, c)`,
+		},
+		{
+			cql:    `create table t (a int, b int, primary key (a, b)) with clustering order by (b);`,
+			errmsg: `expected ASC or DESC`,
+		},
+		{
+			cql:    `create table t (a int primary key) with = 1;`,
+			errmsg: `expected a table option, found =`,
+		},
+		{
+			cql:    `create table t (a int primary key) garbage`,
+			errmsg: `expected ;, found garbage`,
+		},
+		{
+			cql:    `drop table t;`,
+			errmsg: `1:1: expected CREATE, found drop`,
+		},
+		{
+			cql:    `create table t (a text /* never closed`,
+			errmsg: `1:24: unterminated comment`,
+		},
+		{
+			cql:    `create table t (a text) with comment = 'never closed`,
+			errmsg: `1:40: unterminated quoted text`,
+		},
+	}
+	for _, d := range data {
+		_, err := FromCQL(d.cql)
+		if assert.Error(t, err, d.cql) {
+			assert.Contains(t, err.Error(), d.errmsg, d.cql)
+			assert.IsType(t, &ParseError{}, err, d.cql)
+		}
+	}
+}
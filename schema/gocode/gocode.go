@@ -0,0 +1,239 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package gocode generates the Go entity structs of entity definitions, as a
+// starting point for the teams adopting DOSA on existing tables.
+package gocode
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// Options controls the generated code
+type Options struct {
+	// Package is the name of the package of the generated file
+	Package string
+	// JSONTags adds json tags with the column names to the fields
+	JSONTags bool
+}
+
+// initialisms are the parts of names written in upper case in Go
+var initialisms = map[string]bool{
+	"api":  true,
+	"http": true,
+	"id":   true,
+	"ip":   true,
+	"json": true,
+	"sql":  true,
+	"ttl":  true,
+	"uid":  true,
+	"uri":  true,
+	"url":  true,
+	"uuid": true,
+	"xml":  true,
+}
+
+// goTypes are the Go types of primitive dosa types, as typify maps them back
+var goTypes = map[dosa.Type]string{
+	dosa.String:    "string",
+	dosa.Blob:      "[]byte",
+	dosa.Bool:      "bool",
+	dosa.Double:    "float64",
+	dosa.Int32:     "int32",
+	dosa.Int64:     "int64",
+	dosa.Timestamp: "time.Time",
+	dosa.TUUID:     "dosa.UUID",
+	dosa.Counter:   "dosa.CounterValue",
+	dosa.TDecimal:  "dosa.Decimal",
+	dosa.TDate:     "dosa.Date",
+}
+
+// goType returns the Go type of a dosa type
+func goType(t dosa.Type) (string, error) {
+	switch {
+	case t.IsList():
+		elem, err := goType(t.Elem())
+		return "[]" + elem, err
+	case t.IsSet():
+		elem, err := goType(t.Elem())
+		return "map[" + elem + "]dosa.Set", err
+	case t.IsMap():
+		key, err := goType(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := goType(t.Elem())
+		return "map[" + key + "]" + elem, err
+	}
+	if name, ok := goTypes[t]; ok {
+		return name, nil
+	}
+	return "", errors.Errorf("unsupported type %v", t)
+}
+
+// GoName returns the exported Go name of a DOSA name, in camel case with
+// the usual initialisms, e.g. user_id becomes UserID
+func GoName(name string) string {
+	var b bytes.Buffer
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if initialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	if b.Len() == 0 || (b.Bytes()[0] >= '0' && b.Bytes()[0] <= '9') {
+		return "Field" + b.String()
+	}
+	return b.String()
+}
+
+type field struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+type entity struct {
+	Name   string
+	Table  string
+	Tag    string
+	Fields []*field
+}
+
+type file struct {
+	Package  string
+	Time     bool
+	Entities []*entity
+}
+
+var fileTemplate = template.Must(template.New("gocode").Parse(`package {{.Package}}
+
+import (
+{{- if .Time}}
+	"time"
+{{end}}
+	"github.com/uber-go/dosa"
+)
+{{range .Entities}}
+// {{.Name}} is the entity of the {{.Table}} table
+type {{.Name}} struct {
+	dosa.Entity ` + "`{{.Tag}}`" + `
+{{- range .Fields}}
+	{{.Name}} {{.Type}}{{if .Tag}} ` + "`{{.Tag}}`" + `{{end}}
+{{- end}}
+}
+{{end}}`))
+
+// ToGo generates a gofmt-ed Go file declaring one entity struct per entity
+// definition. Fields and structs are named after their columns and entities,
+// with a name tag when the normalized Go name differs.
+func ToGo(eds []*dosa.EntityDefinition, opts Options) ([]byte, error) {
+	f := &file{Package: opts.Package}
+	if f.Package == "" {
+		f.Package = "entities"
+	}
+	structs := make(map[string]string, len(eds))
+	for _, ed := range eds {
+		if err := ed.EnsureValid(); err != nil {
+			return nil, errors.Wrapf(err, "invalid entity definition %q", ed.Name)
+		}
+		e, err := toEntity(ed, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "entity %q", ed.Name)
+		}
+		if other, ok := structs[e.Name]; ok {
+			return nil, errors.Errorf("entities %q and %q would both be named %s", other, ed.Name, e.Name)
+		}
+		structs[e.Name] = ed.Name
+		for _, field := range e.Fields {
+			f.Time = f.Time || strings.Contains(field.Type, "time.Time")
+		}
+		f.Entities = append(f.Entities, e)
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return nil, errors.Wrap(err, "failed to execute Go template; this is most likely a DOSA bug")
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format generated Go code; this is most likely a DOSA bug")
+	}
+	return source, nil
+}
+
+// toEntity names the struct and fields of an entity definition
+func toEntity(ed *dosa.EntityDefinition, opts Options) (*entity, error) {
+	e := &entity{Name: GoName(ed.Name), Table: ed.Name}
+	fieldNames := make(map[string]string, len(ed.Columns))
+	columns := make(map[string]string, len(ed.Columns))
+	for _, cd := range ed.Columns {
+		typ, err := goType(cd.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q", cd.Name)
+		}
+		if cd.IsPointer {
+			typ = "*" + typ
+		}
+		f := &field{Name: GoName(cd.Name), Type: typ}
+		if other, ok := fieldNames[f.Name]; ok {
+			return nil, errors.Errorf("columns %q and %q would both be named %s", other, cd.Name, f.Name)
+		}
+		fieldNames[f.Name] = cd.Name
+		columns[cd.Name] = f.Name
+
+		var tags []string
+		if strings.ToLower(f.Name) != cd.Name {
+			tags = append(tags, fmt.Sprintf(`dosa:"name=%s"`, cd.Name))
+		}
+		if opts.JSONTags {
+			tags = append(tags, fmt.Sprintf(`json:"%s"`, cd.Name))
+		}
+		f.Tag = strings.Join(tags, " ")
+		e.Fields = append(e.Fields, f)
+	}
+
+	// the primary key of the entity tag refers to the fields
+	key := &dosa.PrimaryKey{}
+	for _, pk := range ed.Key.PartitionKeys {
+		key.PartitionKeys = append(key.PartitionKeys, columns[pk])
+	}
+	for _, ck := range ed.Key.ClusteringKeys {
+		key.ClusteringKeys = append(key.ClusteringKeys, &dosa.ClusteringKey{Name: columns[ck.Name], Descending: ck.Descending})
+	}
+	tag := "primaryKey=" + key.String()
+	if strings.ToLower(e.Name) != ed.Name {
+		tag = "name=" + ed.Name + ", " + tag
+	}
+	e.Tag = fmt.Sprintf(`dosa:"%s"`, tag)
+	return e, nil
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gocode
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+var userEntity = &dosa.EntityDefinition{
+	Name: "user_event",
+	Key: &dosa.PrimaryKey{
+		PartitionKeys:  []string{"user_id"},
+		ClusteringKeys: []*dosa.ClusteringKey{{Name: "created", Descending: true}},
+	},
+	Columns: []*dosa.ColumnDefinition{
+		{Name: "user_id", Type: dosa.TUUID},
+		{Name: "created", Type: dosa.Timestamp},
+		{Name: "payload", Type: dosa.Blob},
+		{Name: "tags", Type: dosa.SetOf(dosa.String)},
+	},
+}
+
+var allTypesEntity = &dosa.EntityDefinition{
+	Name: "alltypes",
+	Key: &dosa.PrimaryKey{
+		PartitionKeys:  []string{"a", "b"},
+		ClusteringKeys: []*dosa.ClusteringKey{{Name: "c"}},
+	},
+	Columns: []*dosa.ColumnDefinition{
+		{Name: "a", Type: dosa.String},
+		{Name: "b", Type: dosa.Int32},
+		{Name: "c", Type: dosa.Int64},
+		{Name: "bool_type", Type: dosa.Bool},
+		{Name: "double_type", Type: dosa.Double},
+		{Name: "price", Type: dosa.TDecimal},
+		{Name: "day", Type: dosa.TDate},
+		{Name: "history", Type: dosa.ListOf(dosa.TDate)},
+		{Name: "scores", Type: dosa.MapOf(dosa.String, dosa.Double)},
+		{Name: "email", Type: dosa.String, IsPointer: true},
+	},
+}
+
+func TestGoName(t *testing.T) {
+	data := map[string]string{
+		"foo":          "Foo",
+		"user_id":      "UserID",
+		"an_uuid_key":  "AnUUIDKey",
+		"_private":     "Private",
+		"url2":         "Url2",
+		"http_api_url": "HTTPAPIURL",
+	}
+	for name, expected := range data {
+		assert.Equal(t, expected, GoName(name), name)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	source, err := ToGo([]*dosa.EntityDefinition{userEntity}, Options{Package: "events", JSONTags: true})
+	assert.NoError(t, err)
+	assert.Equal(t, `package events
+
+import (
+	"time"
+
+	"github.com/uber-go/dosa"
+)
+
+// UserEvent is the entity of the user_event table
+type UserEvent struct {
+	dosa.Entity `+"`"+`dosa:"name=user_event, primaryKey=(UserID, Created DESC)"`+"`"+`
+	UserID      dosa.UUID           `+"`"+`dosa:"name=user_id" json:"user_id"`+"`"+`
+	Created     time.Time           `+"`"+`json:"created"`+"`"+`
+	Payload     []byte              `+"`"+`json:"payload"`+"`"+`
+	Tags        map[string]dosa.Set `+"`"+`json:"tags"`+"`"+`
+}
+`, string(source))
+}
+
+func TestToGo_Errors(t *testing.T) {
+	_, err := ToGo([]*dosa.EntityDefinition{{Name: "nokey"}}, Options{})
+	assert.Contains(t, err.Error(), `invalid entity definition "nokey"`)
+
+	collision := &dosa.EntityDefinition{
+		Name: "collision",
+		Key:  &dosa.PrimaryKey{PartitionKeys: []string{"foo_bar"}},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "foo_bar", Type: dosa.String},
+			{Name: "foo__bar", Type: dosa.String},
+		},
+	}
+	_, err = ToGo([]*dosa.EntityDefinition{collision}, Options{})
+	assert.Contains(t, err.Error(), `columns "foo_bar" and "foo__bar" would both be named FooBar`)
+
+	other := *userEntity
+	other.Name = "user__event"
+	_, err = ToGo([]*dosa.EntityDefinition{userEntity, &other}, Options{})
+	assert.Contains(t, err.Error(), `entities "user_event" and "user__event" would both be named UserEvent`)
+}
+
+// TestToGo_Finder checks that the finder reads back the same entity
+// definitions from the generated code
+func TestToGo_Finder(t *testing.T) {
+	source, err := ToGo([]*dosa.EntityDefinition{userEntity, allTypesEntity}, Options{})
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "gocode")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "entities.go"), source, 0644))
+
+	tables, errs, err := dosa.FindEntities([]string{dir}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+	if assert.Len(t, tables, 2) {
+		assert.Equal(t, userEntity, &tables[0].EntityDefinition)
+		assert.Equal(t, allTypesEntity, &tables[1].EntityDefinition)
+	}
+}
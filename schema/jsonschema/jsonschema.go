@@ -0,0 +1,175 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package jsonschema generates the JSON Schema of the JSON objects of
+// entities, for API documentation. The dosa types and primary keys are kept
+// as x-dosa annotations.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// Draft is the JSON Schema version of the generated schemas
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the subset of JSON Schema used to describe entities
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Maximum              *int64             `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	DosaType             string             `json:"x-dosa-type,omitempty"`
+	PrimaryKey           *PrimaryKey        `json:"x-dosa-primary-key,omitempty"`
+}
+
+// PrimaryKey annotates the schema of an entity with its primary key
+type PrimaryKey struct {
+	PartitionKeys  []string         `json:"partitionKeys"`
+	ClusteringKeys []*ClusteringKey `json:"clusteringKeys,omitempty"`
+}
+
+// ClusteringKey is a clustering key of a PrimaryKey
+type ClusteringKey struct {
+	Name       string `json:"name"`
+	Descending bool   `json:"descending"`
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// decimalPattern matches the decimal strings accepted by dosa.ParseDecimal
+const decimalPattern = `^[-+]?[0-9]+(\.[0-9]+)?$`
+
+// valueSchema returns the schema of the JSON values of a primitive dosa type
+func valueSchema(t dosa.Type) (*Schema, error) {
+	switch t {
+	case dosa.String:
+		return &Schema{Type: "string"}, nil
+	case dosa.Blob:
+		return &Schema{Type: "string", ContentEncoding: "base64"}, nil
+	case dosa.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case dosa.Double:
+		return &Schema{Type: "number"}, nil
+	case dosa.Int32:
+		return &Schema{Type: "integer", Minimum: int64Ptr(-1 << 31), Maximum: int64Ptr(1<<31 - 1)}, nil
+	case dosa.Int64, dosa.Counter:
+		return &Schema{Type: "integer"}, nil
+	case dosa.Timestamp:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case dosa.TUUID:
+		return &Schema{Type: "string", Format: "uuid"}, nil
+	case dosa.TDecimal:
+		return &Schema{Type: "string", Pattern: decimalPattern}, nil
+	case dosa.TDate:
+		return &Schema{Type: "string", Format: "date"}, nil
+	}
+	return nil, errors.Errorf("unsupported type %v", t)
+}
+
+// columnSchema returns the schema of a column. Lists and sets are arrays,
+// and maps are objects, whose keys are strings.
+func columnSchema(cd *dosa.ColumnDefinition) (*Schema, error) {
+	var s *Schema
+	var err error
+	switch t := cd.Type; {
+	case t.IsList(), t.IsSet():
+		var items *Schema
+		if items, err = valueSchema(t.Elem()); err == nil {
+			s = &Schema{Type: "array", Items: items, UniqueItems: t.IsSet()}
+		}
+	case t.IsMap():
+		var values *Schema
+		if values, err = valueSchema(t.Elem()); err == nil {
+			s = &Schema{Type: "object", AdditionalProperties: values}
+		}
+	default:
+		s, err = valueSchema(t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cd.IsPointer {
+		s.Type = []string{s.Type.(string), "null"}
+	}
+	s.DosaType = cd.Type.String()
+	return s, nil
+}
+
+// ToSchema returns the JSON Schema of an entity. Only the primary key
+// columns are required.
+func ToSchema(ed *dosa.EntityDefinition) (*Schema, error) {
+	if err := ed.EnsureValid(); err != nil {
+		return nil, errors.Wrapf(err, "invalid entity definition %q", ed.Name)
+	}
+	s := &Schema{
+		Schema:               Draft,
+		Title:                ed.Name,
+		Type:                 "object",
+		Properties:           make(map[string]*Schema, len(ed.Columns)),
+		AdditionalProperties: false,
+		PrimaryKey:           &PrimaryKey{PartitionKeys: ed.Key.PartitionKeys},
+	}
+	for _, cd := range ed.Columns {
+		column, err := columnSchema(cd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q", cd.Name)
+		}
+		s.Properties[cd.Name] = column
+	}
+	s.Required = append(s.Required, ed.Key.PartitionKeys...)
+	for _, ck := range ed.Key.ClusteringKeys {
+		s.Required = append(s.Required, ck.Name)
+		s.PrimaryKey.ClusteringKeys = append(s.PrimaryKey.ClusteringKeys, &ClusteringKey{Name: ck.Name, Descending: ck.Descending})
+	}
+	return s, nil
+}
+
+// ToJSONSchema returns the JSON Schema of an entity as indented JSON
+func ToJSONSchema(ed *dosa.EntityDefinition) ([]byte, error) {
+	s, err := ToSchema(ed)
+	if err != nil {
+		return nil, err
+	}
+	// keep the < and > of collection types readable
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+var accountEntity = &dosa.EntityDefinition{
+	Name: "account",
+	Key: &dosa.PrimaryKey{
+		PartitionKeys:  []string{"id"},
+		ClusteringKeys: []*dosa.ClusteringKey{{Name: "day", Descending: true}},
+	},
+	Columns: []*dosa.ColumnDefinition{
+		{Name: "id", Type: dosa.TUUID},
+		{Name: "day", Type: dosa.TDate},
+		{Name: "balance", Type: dosa.TDecimal, IsPointer: true},
+		{Name: "seq", Type: dosa.Int32},
+		{Name: "photo", Type: dosa.Blob},
+		{Name: "labels", Type: dosa.SetOf(dosa.String)},
+		{Name: "history", Type: dosa.ListOf(dosa.Timestamp)},
+		{Name: "limits", Type: dosa.MapOf(dosa.String, dosa.Double)},
+	},
+}
+
+const accountSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "account",
+  "type": "object",
+  "properties": {
+    "balance": {
+      "type": [
+        "string",
+        "null"
+      ],
+      "pattern": "^[-+]?[0-9]+(\\.[0-9]+)?$",
+      "x-dosa-type": "Decimal"
+    },
+    "day": {
+      "type": "string",
+      "format": "date",
+      "x-dosa-type": "Date"
+    },
+    "history": {
+      "type": "array",
+      "items": {
+        "type": "string",
+        "format": "date-time"
+      },
+      "x-dosa-type": "List<Timestamp>"
+    },
+    "id": {
+      "type": "string",
+      "format": "uuid",
+      "x-dosa-type": "TUUID"
+    },
+    "labels": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      },
+      "uniqueItems": true,
+      "x-dosa-type": "Set<String>"
+    },
+    "limits": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "number"
+      },
+      "x-dosa-type": "Map<String,Double>"
+    },
+    "photo": {
+      "type": "string",
+      "contentEncoding": "base64",
+      "x-dosa-type": "Blob"
+    },
+    "seq": {
+      "type": "integer",
+      "minimum": -2147483648,
+      "maximum": 2147483647,
+      "x-dosa-type": "Int32"
+    }
+  },
+  "additionalProperties": false,
+  "required": [
+    "id",
+    "day"
+  ],
+  "x-dosa-primary-key": {
+    "partitionKeys": [
+      "id"
+    ],
+    "clusteringKeys": [
+      {
+        "name": "day",
+        "descending": true
+      }
+    ]
+  }
+}`
+
+func TestToJSONSchema(t *testing.T) {
+	data, err := ToJSONSchema(accountEntity)
+	assert.NoError(t, err)
+	assert.Equal(t, accountSchema, string(data))
+
+	var s Schema
+	assert.NoError(t, json.Unmarshal(data, &s))
+	assert.Equal(t, "account", s.Title)
+	assert.Equal(t, []string{"id"}, s.PrimaryKey.PartitionKeys)
+}
+
+func TestToSchema_PrimitiveTypes(t *testing.T) {
+	data := []struct {
+		typ    dosa.Type
+		schema *Schema
+	}{
+		{dosa.String, &Schema{Type: "string"}},
+		{dosa.Bool, &Schema{Type: "boolean"}},
+		{dosa.Int64, &Schema{Type: "integer"}},
+		{dosa.Counter, &Schema{Type: "integer"}},
+		{dosa.Double, &Schema{Type: "number"}},
+	}
+	for _, d := range data {
+		s, err := valueSchema(d.typ)
+		assert.NoError(t, err, d.typ.String())
+		assert.Equal(t, d.schema, s, d.typ.String())
+	}
+}
+
+func TestToSchema_Errors(t *testing.T) {
+	_, err := ToJSONSchema(&dosa.EntityDefinition{Name: "empty"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `invalid entity definition "empty"`)
+	}
+
+	_, err = valueSchema(dosa.Invalid)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unsupported type Invalid")
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package proto generates proto3 messages of entity definitions. Field
+// numbers never change once assigned: they are kept in a Numbers value,
+// which callers persist next to the generated files.
+package proto
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/schema/gocode"
+)
+
+// Options controls the generated file
+type Options struct {
+	// Package is the proto package of the messages, none when empty
+	Package string
+	// Numbers are the field numbers assigned by earlier runs; the numbers of
+	// new columns are added to it. When nil, fields are numbered in column order.
+	Numbers Numbers
+}
+
+// Numbers holds the field number of each column of each entity, including
+// the columns removed since, whose numbers are reserved
+type Numbers map[string]map[string]int
+
+// ReadNumbers reads field numbers written by WriteTo
+func ReadNumbers(r io.Reader) (Numbers, error) {
+	numbers := make(Numbers)
+	if err := json.NewDecoder(r).Decode(&numbers); err != nil {
+		return nil, errors.Wrap(err, "invalid field numbers")
+	}
+	return numbers, nil
+}
+
+// WriteTo writes the field numbers as indented JSON
+func (n Numbers) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	written, err := w.Write(append(data, '\n'))
+	return int64(written), err
+}
+
+// assign returns the field number of each column of an entity, numbering
+// the new columns after the highest number ever used by the entity
+func (n Numbers) assign(ed *dosa.EntityDefinition) map[string]int {
+	fields, ok := n[ed.Name]
+	if !ok {
+		fields = make(map[string]int, len(ed.Columns))
+		n[ed.Name] = fields
+	}
+	highest := 0
+	for _, number := range fields {
+		if number > highest {
+			highest = number
+		}
+	}
+	for _, cd := range ed.Columns {
+		if _, ok := fields[cd.Name]; !ok {
+			highest++
+			fields[cd.Name] = highest
+		}
+	}
+	return fields
+}
+
+// protoTypes are the proto3 types of the primitive dosa types. Types without
+// a proto3 equivalent are strings; the dosa_type option of each field keeps
+// the exact dosa type.
+var protoTypes = map[dosa.Type]string{
+	dosa.String:    "string",
+	dosa.Blob:      "bytes",
+	dosa.Bool:      "bool",
+	dosa.Double:    "double",
+	dosa.Int32:     "int32",
+	dosa.Int64:     "int64",
+	dosa.Timestamp: "google.protobuf.Timestamp",
+	dosa.TUUID:     "string",
+	dosa.Counter:   "int64",
+	dosa.TDecimal:  "string",
+	dosa.TDate:     "string",
+}
+
+// protoType returns the proto3 type of a dosa type, with the repeated label
+// of lists and sets
+func protoType(t dosa.Type) (string, error) {
+	switch {
+	case t.IsList(), t.IsSet():
+		elem, err := protoType(t.Elem())
+		return "repeated " + elem, err
+	case t.IsMap():
+		key, err := protoType(t.Key())
+		if err != nil {
+			return "", err
+		}
+		// map keys can only be integral or string types
+		switch key {
+		case "string", "bool", "int32", "int64":
+		default:
+			return "", errors.Errorf("unsupported map key type %v", t.Key())
+		}
+		elem, err := protoType(t.Elem())
+		return "map<" + key + ", " + elem + ">", err
+	}
+	if name, ok := protoTypes[t]; ok {
+		return name, nil
+	}
+	return "", errors.Errorf("unsupported type %v", t)
+}
+
+type field struct {
+	Label    string
+	Type     string
+	Name     string
+	Number   int
+	DosaType string
+}
+
+type message struct {
+	Name       string
+	Entity     string
+	PrimaryKey string
+	Fields     []*field
+	Reserved   []int
+	Names      []string
+}
+
+type file struct {
+	Package   string
+	Timestamp bool
+	Messages  []*message
+}
+
+// the dosa options are extensions of the descriptor options, numbered in the
+// range left for use within an organization
+var fileTemplate = template.Must(template.New("proto").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`syntax = "proto3";
+{{if .Package}}
+package {{.Package}};
+{{end}}
+import "google/protobuf/descriptor.proto";
+{{- if .Timestamp}}
+import "google/protobuf/timestamp.proto";
+{{- end}}
+
+extend google.protobuf.MessageOptions {
+  // the name of the entity
+  string dosa_entity = 50100;
+  // the primary key of the entity, as in the dosa struct tag
+  string dosa_primary_key = 50101;
+}
+
+extend google.protobuf.FieldOptions {
+  // the dosa type of the column
+  string dosa_type = 50102;
+}
+{{range .Messages}}
+message {{.Name}} {
+  option (dosa_entity) = {{quote .Entity}};
+  option (dosa_primary_key) = {{quote .PrimaryKey}};
+{{- if .Reserved}}
+
+  reserved {{range $i, $n := .Reserved}}{{if $i}}, {{end}}{{$n}}{{end}};
+  reserved {{range $i, $n := .Names}}{{if $i}}, {{end}}{{quote $n}}{{end}};
+{{- end}}
+{{range .Fields}}
+  {{if .Label}}{{.Label}} {{end}}{{.Type}} {{.Name}} = {{.Number}} [(dosa_type) = {{quote .DosaType}}];
+{{- end}}
+}
+{{end}}`))
+
+// ToProto generates a proto3 file declaring one message per entity
+// definition, with the entity name and primary key as message options and
+// the dosa type of each column as a field option. The numbers of removed
+// columns are reserved. Nullable scalar columns are optional fields.
+func ToProto(eds []*dosa.EntityDefinition, opts Options) ([]byte, error) {
+	numbers := opts.Numbers
+	if numbers == nil {
+		numbers = make(Numbers)
+	}
+	f := &file{Package: opts.Package}
+	messages := make(map[string]string, len(eds))
+	for _, ed := range eds {
+		if err := ed.EnsureValid(); err != nil {
+			return nil, errors.Wrapf(err, "invalid entity definition %q", ed.Name)
+		}
+		m, err := toMessage(ed, numbers.assign(ed))
+		if err != nil {
+			return nil, errors.Wrapf(err, "entity %q", ed.Name)
+		}
+		if other, ok := messages[m.Name]; ok {
+			return nil, errors.Errorf("entities %q and %q would both be named %s", other, ed.Name, m.Name)
+		}
+		messages[m.Name] = ed.Name
+		for _, field := range m.Fields {
+			f.Timestamp = f.Timestamp || strings.Contains(field.Type, protoTypes[dosa.Timestamp])
+		}
+		f.Messages = append(f.Messages, m)
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return nil, errors.Wrap(err, "failed to execute proto template; this is most likely a DOSA bug")
+	}
+	return buf.Bytes(), nil
+}
+
+// toMessage builds the message of an entity definition from the field numbers of its columns
+func toMessage(ed *dosa.EntityDefinition, fields map[string]int) (*message, error) {
+	m := &message{Name: gocode.GoName(ed.Name), Entity: ed.Name, PrimaryKey: ed.Key.String()}
+	columns := make(map[string]bool, len(ed.Columns))
+	for _, cd := range ed.Columns {
+		typ, err := protoType(cd.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q", cd.Name)
+		}
+		f := &field{Type: typ, Name: cd.Name, Number: fields[cd.Name], DosaType: cd.Type.String()}
+		// only scalar fields can track presence
+		if cd.IsPointer && !cd.Type.IsList() && !cd.Type.IsSet() && !cd.Type.IsMap() {
+			f.Label = "optional"
+		}
+		columns[cd.Name] = true
+		m.Fields = append(m.Fields, f)
+	}
+	for name, number := range fields {
+		if !columns[name] {
+			m.Reserved = append(m.Reserved, number)
+			m.Names = append(m.Names, name)
+		}
+	}
+	sort.Ints(m.Reserved)
+	sort.Strings(m.Names)
+	return m, nil
+}
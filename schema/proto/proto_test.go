@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func userEntity() *dosa.EntityDefinition {
+	return &dosa.EntityDefinition{
+		Name: "user_event",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"id"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "at", Descending: true}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "id", Type: dosa.TUUID},
+			{Name: "at", Type: dosa.Timestamp},
+			{Name: "amount", Type: dosa.TDecimal, IsPointer: true},
+			{Name: "tags", Type: dosa.SetOf(dosa.String)},
+			{Name: "counts", Type: dosa.MapOf(dosa.String, dosa.Int64)},
+		},
+	}
+}
+
+const userProto = `syntax = "proto3";
+
+package events;
+
+import "google/protobuf/descriptor.proto";
+import "google/protobuf/timestamp.proto";
+
+extend google.protobuf.MessageOptions {
+  // the name of the entity
+  string dosa_entity = 50100;
+  // the primary key of the entity, as in the dosa struct tag
+  string dosa_primary_key = 50101;
+}
+
+extend google.protobuf.FieldOptions {
+  // the dosa type of the column
+  string dosa_type = 50102;
+}
+
+message UserEvent {
+  option (dosa_entity) = "user_event";
+  option (dosa_primary_key) = "(id, at DESC)";
+
+  string id = 1 [(dosa_type) = "TUUID"];
+  google.protobuf.Timestamp at = 2 [(dosa_type) = "Timestamp"];
+  optional string amount = 3 [(dosa_type) = "Decimal"];
+  repeated string tags = 4 [(dosa_type) = "Set<String>"];
+  map<string, int64> counts = 5 [(dosa_type) = "Map<String,Int64>"];
+}
+`
+
+func TestToProto(t *testing.T) {
+	data, err := ToProto([]*dosa.EntityDefinition{userEntity()}, Options{Package: "events"})
+	assert.NoError(t, err)
+	assert.Equal(t, userProto, string(data))
+}
+
+func TestToProto_StableNumbers(t *testing.T) {
+	numbers := make(Numbers)
+	_, err := ToProto([]*dosa.EntityDefinition{userEntity()}, Options{Numbers: numbers})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"id": 1, "at": 2, "amount": 3, "tags": 4, "counts": 5}, numbers["user_event"])
+
+	// remove two columns and add one in the middle
+	ed := userEntity()
+	ed.Columns = []*dosa.ColumnDefinition{ed.Columns[0], ed.Columns[1], {Name: "note", Type: dosa.String}, ed.Columns[4]}
+	data, err := ToProto([]*dosa.EntityDefinition{ed}, Options{Numbers: numbers})
+	assert.NoError(t, err)
+	output := string(data)
+	assert.NotContains(t, output, "package")
+	assert.Contains(t, output, "  reserved 3, 4;\n  reserved \"amount\", \"tags\";\n")
+	assert.Contains(t, output, `string note = 6 [(dosa_type) = "String"];`)
+	assert.Contains(t, output, `map<string, int64> counts = 5`)
+	assert.Equal(t, 6, numbers["user_event"]["note"])
+}
+
+func TestToProto_NoTimestamp(t *testing.T) {
+	ed := userEntity()
+	ed.Key.ClusteringKeys = nil
+	ed.Columns = append(ed.Columns[:1:1], ed.Columns[2:]...)
+	data, err := ToProto([]*dosa.EntityDefinition{ed}, Options{})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "timestamp.proto")
+	assert.Contains(t, string(data), `optional string amount = 2`)
+}
+
+func TestToProto_Errors(t *testing.T) {
+	invalid := userEntity()
+	invalid.Key.PartitionKeys = []string{"missing"}
+	badMapKey := userEntity()
+	badMapKey.Columns[4].Type = dosa.MapOf(dosa.Double, dosa.String)
+	collision := userEntity()
+	collision.Name = "user__event"
+	data := []struct {
+		eds    []*dosa.EntityDefinition
+		errmsg string
+	}{
+		{[]*dosa.EntityDefinition{invalid}, `invalid entity definition "user_event"`},
+		{[]*dosa.EntityDefinition{badMapKey}, `entity "user_event": column "counts": unsupported map key type Double`},
+		{[]*dosa.EntityDefinition{userEntity(), collision}, `entities "user_event" and "user__event" would both be named UserEvent`},
+	}
+	for _, d := range data {
+		_, err := ToProto(d.eds, Options{})
+		if assert.Error(t, err, d.errmsg) {
+			assert.Contains(t, err.Error(), d.errmsg)
+		}
+	}
+}
+
+func TestNumbers_ReadWrite(t *testing.T) {
+	numbers := Numbers{"user_event": {"id": 1, "at": 2}}
+	var buf bytes.Buffer
+	n, err := numbers.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	read, err := ReadNumbers(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, numbers, read)
+
+	_, err = ReadNumbers(bytes.NewBufferString("[1, 2]"))
+	assert.Error(t, err)
+}
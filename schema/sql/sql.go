@@ -0,0 +1,206 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package sql generates the CREATE TABLE statements of entities for
+// relational databases. The partition and clustering keys of an entity make
+// up a composite primary key, and the clustering order and the indexes of
+// the entity become secondary indexes.
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/dosa"
+)
+
+// Dialect holds what differs between the SQL of two databases
+type Dialect struct {
+	// Name is the name of the database, as in the dump formats
+	Name string
+	// quote is the identifier quote character
+	quote string
+	// types are the column types of the primitive dosa types
+	types map[dosa.Type]string
+	// keyTypes override types for the columns of primary keys and
+	// indexes, when the type cannot be indexed
+	keyTypes map[dosa.Type]string
+	// collection returns the column type of a list, set or map
+	collection func(d *Dialect, t dosa.Type) string
+}
+
+// MySQL is the dialect of MySQL 8, which supports descending indexes.
+// Decimals get the largest precision MySQL has; collections are JSON.
+var MySQL = &Dialect{
+	Name:  "mysql",
+	quote: "`",
+	types: map[dosa.Type]string{
+		dosa.String:    "TEXT",
+		dosa.Blob:      "LONGBLOB",
+		dosa.Bool:      "BOOLEAN",
+		dosa.Double:    "DOUBLE",
+		dosa.Int32:     "INT",
+		dosa.Int64:     "BIGINT",
+		dosa.Timestamp: "DATETIME(6)",
+		dosa.TUUID:     "CHAR(36)",
+		dosa.Counter:   "BIGINT",
+		dosa.TDecimal:  "DECIMAL(65, 30)",
+		dosa.TDate:     "DATE",
+	},
+	// TEXT and BLOB columns can only be indexed by prefix
+	keyTypes: map[dosa.Type]string{
+		dosa.String: "VARCHAR(255)",
+		dosa.Blob:   "VARBINARY(255)",
+	},
+	collection: func(d *Dialect, t dosa.Type) string {
+		return "JSON"
+	},
+}
+
+// Postgres is the dialect of PostgreSQL. Lists and sets are arrays, and
+// maps are JSONB.
+var Postgres = &Dialect{
+	Name:  "postgres",
+	quote: `"`,
+	types: map[dosa.Type]string{
+		dosa.String:    "TEXT",
+		dosa.Blob:      "BYTEA",
+		dosa.Bool:      "BOOLEAN",
+		dosa.Double:    "DOUBLE PRECISION",
+		dosa.Int32:     "INTEGER",
+		dosa.Int64:     "BIGINT",
+		dosa.Timestamp: "TIMESTAMP WITH TIME ZONE",
+		dosa.TUUID:     "UUID",
+		dosa.Counter:   "BIGINT",
+		dosa.TDecimal:  "NUMERIC",
+		dosa.TDate:     "DATE",
+	},
+	collection: func(d *Dialect, t dosa.Type) string {
+		if t.IsMap() {
+			return "JSONB"
+		}
+		return d.types[t.Elem()] + "[]"
+	},
+}
+
+// Dialects are the supported dialects by name
+var Dialects = map[string]*Dialect{
+	MySQL.Name:    MySQL,
+	Postgres.Name: Postgres,
+}
+
+// Quote escapes a name as an identifier of the dialect
+func (d *Dialect) Quote(name string) string {
+	return d.quote + strings.Replace(name, d.quote, d.quote+d.quote, -1) + d.quote
+}
+
+// columnType returns the column type of a dosa type, and whether it is
+// supported by the dialect
+func (d *Dialect) columnType(t dosa.Type, key bool) (string, bool) {
+	if t.IsList() || t.IsSet() || t.IsMap() {
+		return d.collection(d, t), true
+	}
+	if typ, ok := d.keyTypes[t]; ok && key {
+		return typ, true
+	}
+	typ, ok := d.types[t]
+	return typ, ok
+}
+
+// columnList formats the columns of a key for an index or primary key,
+// with the order of descending clustering keys when desc is set
+func (d *Dialect) columnList(key *dosa.PrimaryKey, desc bool) string {
+	names := make([]string, 0, len(key.PartitionKeys)+len(key.ClusteringKeys))
+	for _, pk := range key.PartitionKeys {
+		names = append(names, d.Quote(pk))
+	}
+	for _, ck := range key.ClusteringKeys {
+		name := d.Quote(ck.Name)
+		if desc && ck.Descending {
+			name += " DESC"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ToSQL generates the CREATE TABLE statement of an entity, followed by the
+// CREATE INDEX statements of its clustering order, when it has descending
+// clustering keys, and of its indexes. The key columns are NOT NULL.
+func ToSQL(d *Dialect, ed *dosa.EntityDefinition, indexes []*dosa.IndexDefinition) (string, error) {
+	if err := ed.EnsureValid(); err != nil {
+		return "", errors.Wrapf(err, "invalid entity definition %q", ed.Name)
+	}
+
+	// columns used in any key get the indexable types
+	columns := ed.ColumnTypes()
+	keys := ed.KeySet()
+	indexed := make(map[string]struct{}, len(keys))
+	for name := range keys {
+		indexed[name] = struct{}{}
+	}
+	for _, index := range indexes {
+		if index.Key == nil || len(index.Key.PartitionKeys) == 0 {
+			return "", errors.Errorf("index %q of entity %q has no partition key", index.Name, ed.Name)
+		}
+		names := append([]string{}, index.Key.PartitionKeys...)
+		for _, ck := range index.Key.ClusteringKeys {
+			names = append(names, ck.Name)
+		}
+		for _, name := range names {
+			if _, ok := columns[name]; !ok {
+				return "", errors.Errorf("index %q of entity %q refers to unknown column %q", index.Name, ed.Name, name)
+			}
+			indexed[name] = struct{}{}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "CREATE TABLE %s (\n", d.Quote(ed.Name))
+	for _, cd := range ed.Columns {
+		_, isIndexed := indexed[cd.Name]
+		typ, ok := d.columnType(cd.Type, isIndexed)
+		if !ok {
+			return "", errors.Errorf("unsupported type %v of column %q of entity %q", cd.Type, cd.Name, ed.Name)
+		}
+		fmt.Fprintf(&buf, "  %s %s", d.Quote(cd.Name), typ)
+		if _, ok := keys[cd.Name]; ok {
+			buf.WriteString(" NOT NULL")
+		}
+		buf.WriteString(",\n")
+	}
+	fmt.Fprintf(&buf, "  PRIMARY KEY (%s)\n);\n", d.columnList(ed.Key, false))
+
+	for _, ck := range ed.Key.ClusteringKeys {
+		if ck.Descending {
+			fmt.Fprintf(&buf, "CREATE INDEX %s ON %s (%s);\n",
+				d.Quote(ed.Name+"_clustering_order"), d.Quote(ed.Name), d.columnList(ed.Key, true))
+			break
+		}
+	}
+
+	for _, index := range indexes {
+		fmt.Fprintf(&buf, "CREATE INDEX %s ON %s (%s);\n",
+			d.Quote(ed.Name+"_"+index.Name), d.Quote(ed.Name), d.columnList(index.Key, true))
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func orderEntity() *dosa.EntityDefinition {
+	return &dosa.EntityDefinition{
+		Name: "order",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys: []string{"customer"},
+			ClusteringKeys: []*dosa.ClusteringKey{
+				{Name: "placed", Descending: true},
+				{Name: "id"},
+			},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "customer", Type: dosa.String},
+			{Name: "placed", Type: dosa.Timestamp},
+			{Name: "id", Type: dosa.TUUID},
+			{Name: "total", Type: dosa.TDecimal, IsPointer: true},
+			{Name: "status", Type: dosa.String},
+			{Name: "items", Type: dosa.ListOf(dosa.Int64)},
+			{Name: "notes", Type: dosa.MapOf(dosa.String, dosa.String)},
+		},
+	}
+}
+
+var byStatus = &dosa.IndexDefinition{
+	Name: "by_status",
+	Key: &dosa.PrimaryKey{
+		PartitionKeys:  []string{"status"},
+		ClusteringKeys: []*dosa.ClusteringKey{{Name: "placed", Descending: true}},
+	},
+}
+
+func TestToSQL_MySQL(t *testing.T) {
+	ddl, err := ToSQL(MySQL, orderEntity(), []*dosa.IndexDefinition{byStatus})
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE `order` (\n"+
+		"  `customer` VARCHAR(255) NOT NULL,\n"+
+		"  `placed` DATETIME(6) NOT NULL,\n"+
+		"  `id` CHAR(36) NOT NULL,\n"+
+		"  `total` DECIMAL(65, 30),\n"+
+		"  `status` VARCHAR(255),\n"+
+		"  `items` JSON,\n"+
+		"  `notes` JSON,\n"+
+		"  PRIMARY KEY (`customer`, `placed`, `id`)\n"+
+		");\n"+
+		"CREATE INDEX `order_clustering_order` ON `order` (`customer`, `placed` DESC, `id`);\n"+
+		"CREATE INDEX `order_by_status` ON `order` (`status`, `placed` DESC);\n", ddl)
+}
+
+func TestToSQL_Postgres(t *testing.T) {
+	ddl, err := ToSQL(Postgres, orderEntity(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `CREATE TABLE "order" (
+  "customer" TEXT NOT NULL,
+  "placed" TIMESTAMP WITH TIME ZONE NOT NULL,
+  "id" UUID NOT NULL,
+  "total" NUMERIC,
+  "status" TEXT,
+  "items" BIGINT[],
+  "notes" JSONB,
+  PRIMARY KEY ("customer", "placed", "id")
+);
+CREATE INDEX "order_clustering_order" ON "order" ("customer", "placed" DESC, "id");
+`, ddl)
+}
+
+func TestToSQL_AscendingOnly(t *testing.T) {
+	ed := orderEntity()
+	ed.Key.ClusteringKeys[0].Descending = false
+	ddl, err := ToSQL(Postgres, ed, nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, ddl, "CREATE INDEX")
+}
+
+func TestToSQL_Errors(t *testing.T) {
+	invalid := orderEntity()
+	invalid.Key.PartitionKeys = nil
+	data := []struct {
+		ed      *dosa.EntityDefinition
+		indexes []*dosa.IndexDefinition
+		errmsg  string
+	}{
+		{invalid, nil, `invalid entity definition "order"`},
+		{orderEntity(), []*dosa.IndexDefinition{{Name: "empty"}}, `index "empty" of entity "order" has no partition key`},
+		{orderEntity(), []*dosa.IndexDefinition{{Name: "bad", Key: &dosa.PrimaryKey{PartitionKeys: []string{"missing"}}}},
+			`index "bad" of entity "order" refers to unknown column "missing"`},
+	}
+	for _, d := range data {
+		_, err := ToSQL(MySQL, d.ed, d.indexes)
+		if assert.Error(t, err, d.errmsg) {
+			assert.Contains(t, err.Error(), d.errmsg)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "`a``b`", MySQL.Quote("a`b"))
+	assert.Equal(t, `"a""b"`, Postgres.Quote(`a"b`))
+	assert.Equal(t, MySQL, Dialects["mysql"])
+	assert.Equal(t, Postgres, Dialects["postgres"])
+}
@@ -22,7 +22,10 @@ package uql
 
 import (
 	"bytes"
-
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
 	"text/template"
 
 	"github.com/pkg/errors"
@@ -40,14 +43,29 @@ var (
 		dosa.Int64:     "int64",
 		dosa.Timestamp: "timestamp",
 		dosa.TUUID:     "uuid",
+		dosa.Counter:   "counter",
+		dosa.TDecimal:  "decimal",
+		dosa.TDate:     "date",
 	}
 
 	funcMap = template.FuncMap{
-		"toUqlType": func(t dosa.Type) string {
-			return uqlTypes[t]
-		}}
+		"toUqlType": toUqlType,
+	}
 )
 
+// toUqlType returns the UQL type string of a dosa type
+func toUqlType(t dosa.Type) string {
+	switch {
+	case t.IsList():
+		return "list<" + uqlTypes[t.Elem()] + ">"
+	case t.IsSet():
+		return "set<" + uqlTypes[t.Elem()] + ">"
+	case t.IsMap():
+		return "map<" + uqlTypes[t.Key()] + ", " + uqlTypes[t.Elem()] + ">"
+	}
+	return uqlTypes[t]
+}
+
 const createStmt = "CREATE TABLE {{.Name}} (\n" +
 	"{{range .Columns}}  {{.Name}} {{(toUqlType .Type)}};\n{{end}}" +
 	") PRIMARY KEY {{(.Key)}};\n"
@@ -67,3 +85,248 @@ func ToUQL(e *dosa.EntityDefinition) (string, error) {
 	}
 	return buf.String(), nil
 }
+
+// uqlTypeNames maps the UQL type names to the primitive dosa types
+var uqlTypeNames = func() map[string]dosa.Type {
+	m := make(map[string]dosa.Type, len(uqlTypes))
+	for t, name := range uqlTypes {
+		m[name] = t
+	}
+	return m
+}()
+
+// FromUQL parses the CREATE TABLE statements written by ToUQL into entity
+// definitions. Errors report the line and column they were found at.
+func FromUQL(data string) ([]*dosa.EntityDefinition, error) {
+	p := newParser(data)
+	var eds []*dosa.EntityDefinition
+	for p.tok != scanner.EOF {
+		ed, err := p.createTable()
+		if err != nil {
+			return nil, err
+		}
+		eds = append(eds, ed)
+	}
+	return eds, nil
+}
+
+// parser reads UQL statements one token at a time
+type parser struct {
+	s    scanner.Scanner
+	tok  rune
+	text string
+	pos  scanner.Position
+	err  error
+}
+
+func newParser(data string) *parser {
+	p := &parser{}
+	p.s.Init(strings.NewReader(data))
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanComments | scanner.SkipComments
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		if p.err == nil {
+			p.err = errors.Errorf("%s: %s", s.Position, msg)
+		}
+	}
+	p.next()
+	return p
+}
+
+func (p *parser) next() {
+	p.tok = p.s.Scan()
+	p.text = p.s.TokenText()
+	p.pos = p.s.Position
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return errors.Errorf("%s: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) describe() string {
+	if p.tok == scanner.EOF {
+		return "end of input"
+	}
+	return strconv.Quote(p.text)
+}
+
+// keyword consumes a case insensitive keyword
+func (p *parser) keyword(kw string) error {
+	if p.tok != scanner.Ident || !strings.EqualFold(p.text, kw) {
+		return p.errorf("expected %s, found %s", kw, p.describe())
+	}
+	p.next()
+	return nil
+}
+
+// is checks if the current token is a case insensitive keyword
+func (p *parser) is(kw string) bool {
+	return p.tok == scanner.Ident && strings.EqualFold(p.text, kw)
+}
+
+func (p *parser) expect(r rune) error {
+	if p.tok != r {
+		return p.errorf("expected %q, found %s", r, p.describe())
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok != scanner.Ident {
+		return "", p.errorf("expected a name, found %s", p.describe())
+	}
+	name, err := dosa.NormalizeName(p.text)
+	if err != nil {
+		return "", p.errorf("invalid name %q: %s", p.text, err)
+	}
+	p.next()
+	return name, nil
+}
+
+// createTable parses CREATE TABLE name (column type; ...) PRIMARY KEY key;
+func (p *parser) createTable() (*dosa.EntityDefinition, error) {
+	start := p.pos
+	if err := p.keyword("CREATE"); err != nil {
+		return nil, err
+	}
+	if err := p.keyword("TABLE"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	ed := &dosa.EntityDefinition{Name: name}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	for p.tok != ')' {
+		column, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		typ, err := p.columnType()
+		if err != nil {
+			return nil, err
+		}
+		ed.Columns = append(ed.Columns, &dosa.ColumnDefinition{Name: column, Type: typ})
+		if err := p.expect(';'); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	if err := p.keyword("PRIMARY"); err != nil {
+		return nil, err
+	}
+	if err := p.keyword("KEY"); err != nil {
+		return nil, err
+	}
+	if ed.Key, err = p.primaryKey(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(';'); err != nil {
+		return nil, err
+	}
+	if err := ed.EnsureValid(); err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid table %q", start, name)
+	}
+	return ed, nil
+}
+
+// columnType parses a primitive type, or a list<t>, set<t> or map<k, v>
+func (p *parser) columnType() (dosa.Type, error) {
+	pos, name := p.pos, strings.ToLower(p.text)
+	if p.tok != scanner.Ident {
+		return dosa.Invalid, p.errorf("expected a type, found %s", p.describe())
+	}
+	p.next()
+	var typ dosa.Type
+	switch name {
+	case "list", "set", "map":
+		if err := p.expect('<'); err != nil {
+			return dosa.Invalid, err
+		}
+		elem, err := p.columnType()
+		if err != nil {
+			return dosa.Invalid, err
+		}
+		switch name {
+		case "list":
+			typ = dosa.ListOf(elem)
+		case "set":
+			typ = dosa.SetOf(elem)
+		default:
+			if err := p.expect(','); err != nil {
+				return dosa.Invalid, err
+			}
+			value, err := p.columnType()
+			if err != nil {
+				return dosa.Invalid, err
+			}
+			typ = dosa.MapOf(elem, value)
+		}
+		if err := p.expect('>'); err != nil {
+			return dosa.Invalid, err
+		}
+	default:
+		typ = uqlTypeNames[name]
+	}
+	if typ == dosa.Invalid {
+		return dosa.Invalid, errors.Errorf("%s: unsupported type %q", pos, name)
+	}
+	return typ, nil
+}
+
+// primaryKey parses (pk, ck ASC, ...) or ((pk1, pk2), ck DESC, ...)
+func (p *parser) primaryKey() (*dosa.PrimaryKey, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	key := &dosa.PrimaryKey{}
+	if p.tok == '(' {
+		p.next()
+		for {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			key.PartitionKeys = append(key.PartitionKeys, name)
+			if p.tok != ',' {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		key.PartitionKeys = []string{name}
+	}
+	for p.tok == ',' {
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ck := &dosa.ClusteringKey{Name: name}
+		switch {
+		case p.is("DESC"):
+			ck.Descending = true
+			p.next()
+		case p.is("ASC"):
+			p.next()
+		}
+		key.ClusteringKeys = append(key.ClusteringKeys, ck)
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -64,6 +64,26 @@ func TestToUql(t *testing.T) {
 			Name: "pop",
 			Type: dosa.Bool,
 		},
+		{
+			Name: "tags",
+			Type: dosa.ListOf(dosa.String),
+		},
+		{
+			Name: "ids",
+			Type: dosa.SetOf(dosa.TUUID),
+		},
+		{
+			Name: "attrs",
+			Type: dosa.MapOf(dosa.String, dosa.Int64),
+		},
+		{
+			Name: "price",
+			Type: dosa.TDecimal,
+		},
+		{
+			Name: "day",
+			Type: dosa.TDate,
+		},
 	}
 
 	singleKeyEntity := &dosa.EntityDefinition{
@@ -120,6 +140,11 @@ func TestToUql(t *testing.T) {
 	  cat timestamp;
 	  tap double;
 	  pop bool;
+	  tags list<string>;
+	  ids set<uuid>;
+	  attrs map<string, int64>;
+	  price decimal;
+	  day date;
 	) PRIMARY KEY %s;
 	`
 
@@ -172,5 +197,86 @@ func TestToUql(t *testing.T) {
 			assert.Equal(t, strings.TrimSpace(expectedLines[i]), strings.TrimSpace(actualLine), caseName,
 				fmt.Sprintf("output line %d does not match expected", i))
 		}
+		// the statement parses back to the same entity
+		parsed, err := uql.FromUQL(actual)
+		assert.NoError(t, err, caseName)
+		assert.Equal(t, []*dosa.EntityDefinition{testdata.e}, parsed, caseName)
+	}
+}
+
+func TestFromUQL(t *testing.T) {
+	eds, err := uql.FromUQL(`
+		// two tables
+		create table users (id uuid; Name string;) primary key (id);
+		CREATE TABLE events (
+		  user_id uuid;
+		  ts timestamp;
+		  tags map<string, int64>;
+		) PRIMARY KEY (user_id, ts desc);
+	`)
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.EntityDefinition{
+		{
+			Name: "users",
+			Key:  &dosa.PrimaryKey{PartitionKeys: []string{"id"}},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "id", Type: dosa.TUUID},
+				{Name: "name", Type: dosa.String},
+			},
+		},
+		{
+			Name: "events",
+			Key: &dosa.PrimaryKey{
+				PartitionKeys:  []string{"user_id"},
+				ClusteringKeys: []*dosa.ClusteringKey{{Name: "ts", Descending: true}},
+			},
+			Columns: []*dosa.ColumnDefinition{
+				{Name: "user_id", Type: dosa.TUUID},
+				{Name: "ts", Type: dosa.Timestamp},
+				{Name: "tags", Type: dosa.MapOf(dosa.String, dosa.Int64)},
+			},
+		},
+	}, eds)
+}
+
+func TestFromUQL_Errors(t *testing.T) {
+	data := []struct {
+		uql    string
+		errmsg string
+	}{
+		{
+			uql:    "CREATE TABLE t (\n  a varchar;\n) PRIMARY KEY (a);",
+			errmsg: `2:5: unsupported type "varchar"`,
+		},
+		{
+			uql:    "CREATE TABLE t (a map<string, list<string>>;) PRIMARY KEY (a);",
+			errmsg: `1:19: unsupported type "map"`,
+		},
+		{
+			uql:    "CREATE TABLE t (a string) PRIMARY KEY (a);",
+			errmsg: `1:25: expected ';', found ")"`,
+		},
+		{
+			uql:    "CREATE TABLE t (a string;) PRIMARY KEY (a)",
+			errmsg: `expected ';', found end of input`,
+		},
+		{
+			uql:    "CREATE VIEW t",
+			errmsg: `1:8: expected TABLE, found "VIEW"`,
+		},
+		{
+			uql:    "CREATE TABLE t (a string;) PRIMARY KEY (b);",
+			errmsg: `1:1: invalid table "t"`,
+		},
+		{
+			uql:    "CREATE TABLE 9t",
+			errmsg: `1:14: expected a name, found "9"`,
+		},
+	}
+	for _, d := range data {
+		_, err := uql.FromUQL(d.uql)
+		if assert.Error(t, err, d.uql) {
+			assert.Contains(t, err.Error(), d.errmsg, d.uql)
+		}
 	}
 }
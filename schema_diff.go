@@ -0,0 +1,272 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LatestVersion asks Connector.GetSchema for the most recent version of a schema
+const LatestVersion int32 = 0
+
+// SchemaChangeKind is the kind of a change between two schemas
+type SchemaChangeKind string
+
+// The kinds of schema changes
+const (
+	EntityAdded          SchemaChangeKind = "entity added"
+	EntityRemoved        SchemaChangeKind = "entity removed"
+	PartitionKeyChanged  SchemaChangeKind = "partition key changed"
+	ClusteringKeyChanged SchemaChangeKind = "clustering key changed"
+	ColumnAdded          SchemaChangeKind = "column added"
+	ColumnRemoved        SchemaChangeKind = "column removed"
+	ColumnRetyped        SchemaChangeKind = "column retyped"
+	TagChanged           SchemaChangeKind = "tag changed"
+)
+
+// SchemaChange is one difference between two schemas. Old and New describe
+// the changed element before and after the change, and are empty when it
+// did not exist.
+type SchemaChange struct {
+	Entity   string           `json:"entity"`
+	Kind     SchemaChangeKind `json:"kind"`
+	Name     string           `json:"name,omitempty"` // column or tag name
+	Old      string           `json:"old,omitempty"`
+	New      string           `json:"new,omitempty"`
+	Breaking bool             `json:"breaking"`
+}
+
+// String describes the change on one line, e.g.
+// "breaking: user: column retyped age: Int32 -> Int64"
+func (c *SchemaChange) String() string {
+	var b bytes.Buffer
+	if c.Breaking {
+		b.WriteString("breaking: ")
+	} else {
+		b.WriteString("compatible: ")
+	}
+	fmt.Fprintf(&b, "%s: %s", c.Entity, c.Kind)
+	if c.Name != "" {
+		fmt.Fprintf(&b, " %s", c.Name)
+	}
+	from, to := c.Old, c.New
+	if c.Kind == TagChanged {
+		// an absent tag is not the same as a tag without a value
+		if from == "" {
+			from = "none"
+		}
+		if to == "" {
+			to = "none"
+		}
+	}
+	switch {
+	case from != "" && to != "":
+		fmt.Fprintf(&b, ": %s -> %s", from, to)
+	case from != "":
+		fmt.Fprintf(&b, ": %s", from)
+	case to != "":
+		fmt.Fprintf(&b, ": %s", to)
+	}
+	return b.String()
+}
+
+// SchemaDiff lists all the changes between two schemas, ordered by entity
+type SchemaDiff struct {
+	Changes []*SchemaChange `json:"changes"`
+}
+
+// Breaking returns true if any change is breaking
+func (d *SchemaDiff) Breaking() bool {
+	for _, c := range d.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the changes one per line, or a single line saying the
+// schemas are the same
+func (d *SchemaDiff) WriteText(w io.Writer) error {
+	if len(d.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+	for _, c := range d.Changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the changes as an indented JSON object
+func (d *SchemaDiff) WriteJSON(w io.Writer) error {
+	changes := d.Changes
+	if changes == nil {
+		changes = []*SchemaChange{}
+	}
+	data, err := json.MarshalIndent(&SchemaDiff{Changes: changes}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode schema diff")
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// DiffEntityDefinitions lists the changes needed to go from one set of entity
+// definitions to another. The classification follows IsCompatible:
+// adding entities and columns is compatible, as are tag changes, while
+// removing or retyping columns, removing entities and changing keys are
+// breaking. Entity definitions carry no indexes, so indexes are not compared.
+func DiffEntityDefinitions(from, to []*EntityDefinition) *SchemaDiff {
+	olds := make(map[string]*EntityDefinition, len(from))
+	for _, ed := range from {
+		olds[ed.Name] = ed
+	}
+	news := make(map[string]*EntityDefinition, len(to))
+	for _, ed := range to {
+		news[ed.Name] = ed
+	}
+
+	names := make([]string, 0, len(olds)+len(news))
+	for name := range olds {
+		names = append(names, name)
+	}
+	for name := range news {
+		if _, ok := olds[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diff := &SchemaDiff{}
+	for _, name := range names {
+		e1, e2 := olds[name], news[name]
+		switch {
+		case e1 == nil:
+			diff.Changes = append(diff.Changes, &SchemaChange{Entity: name, Kind: EntityAdded, New: keyString(e2.Key)})
+		case e2 == nil:
+			diff.Changes = append(diff.Changes, &SchemaChange{Entity: name, Kind: EntityRemoved, Old: keyString(e1.Key), Breaking: true})
+		default:
+			diff.Changes = append(diff.Changes, diffEntity(e1, e2)...)
+		}
+	}
+	return diff
+}
+
+// diffEntity compares two definitions of the same entity: keys first, then
+// columns in the order of the new definition followed by the removed ones
+func diffEntity(e1, e2 *EntityDefinition) []*SchemaChange {
+	var changes []*SchemaChange
+	if pk1, pk2 := partitionKeyString(e1.Key), partitionKeyString(e2.Key); pk1 != pk2 {
+		changes = append(changes, &SchemaChange{Entity: e1.Name, Kind: PartitionKeyChanged, Old: pk1, New: pk2, Breaking: true})
+	}
+	if ck1, ck2 := clusteringKeyString(e1.Key), clusteringKeyString(e2.Key); ck1 != ck2 {
+		changes = append(changes, &SchemaChange{Entity: e1.Name, Kind: ClusteringKeyChanged, Old: ck1, New: ck2, Breaking: true})
+	}
+
+	for _, c2 := range e2.Columns {
+		c1 := e1.FindColumnDefinition(c2.Name)
+		if c1 == nil {
+			changes = append(changes, &SchemaChange{Entity: e1.Name, Kind: ColumnAdded, Name: c2.Name, New: c2.Type.String()})
+			continue
+		}
+		if c1.Type != c2.Type {
+			changes = append(changes, &SchemaChange{Entity: e1.Name, Kind: ColumnRetyped, Name: c2.Name,
+				Old: c1.Type.String(), New: c2.Type.String(), Breaking: true})
+		}
+		changes = append(changes, diffTags(e1.Name, c2.Name, c1.Tags, c2.Tags)...)
+	}
+	for _, c1 := range e1.Columns {
+		if e2.FindColumnDefinition(c1.Name) == nil {
+			changes = append(changes, &SchemaChange{Entity: e1.Name, Kind: ColumnRemoved, Name: c1.Name, Old: c1.Type.String(), Breaking: true})
+		}
+	}
+	return changes
+}
+
+// diffTags compares the tags of a column, in tag name order. Tags are
+// named "column.tag" in the changes.
+func diffTags(entity, column string, from, to map[string]string) []*SchemaChange {
+	names := make([]string, 0, len(from)+len(to))
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []*SchemaChange
+	for _, name := range names {
+		v1, ok1 := from[name]
+		v2, ok2 := to[name]
+		if ok1 && ok2 && v1 == v2 {
+			continue
+		}
+		changes = append(changes, &SchemaChange{Entity: entity, Kind: TagChanged, Name: column + "." + name,
+			Old: tagString(v1, ok1), New: tagString(v2, ok2)})
+	}
+	return changes
+}
+
+// tagString shows tags without a value as "set", since they have no value
+// to show but are not absent either
+func tagString(value string, ok bool) string {
+	switch {
+	case !ok:
+		return ""
+	case value == "":
+		return "set"
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+func keyString(pk *PrimaryKey) string {
+	if pk == nil || len(pk.PartitionKeys) == 0 {
+		return ""
+	}
+	return pk.String()
+}
+
+func partitionKeyString(pk *PrimaryKey) string {
+	if pk == nil {
+		return ""
+	}
+	return strings.Join(pk.PartitionKeys, ", ")
+}
+
+func clusteringKeyString(pk *PrimaryKey) string {
+	if pk == nil {
+		return ""
+	}
+	return formatClusteringKeys(pk.ClusteringKeys)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func diffTestEntity() *dosa.EntityDefinition {
+	return &dosa.EntityDefinition{
+		Name: "user",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"id"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "created", Descending: true}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "id", Type: dosa.TUUID},
+			{Name: "created", Type: dosa.Timestamp},
+			{Name: "email", Type: dosa.String, Tags: map[string]string{"pii": ""}},
+			{Name: "age", Type: dosa.Int32},
+		},
+	}
+}
+
+func TestDiffEntityDefinitions_Same(t *testing.T) {
+	diff := dosa.DiffEntityDefinitions([]*dosa.EntityDefinition{diffTestEntity()}, []*dosa.EntityDefinition{diffTestEntity()})
+	assert.Empty(t, diff.Changes)
+	assert.False(t, diff.Breaking())
+
+	var b bytes.Buffer
+	assert.NoError(t, diff.WriteText(&b))
+	assert.Equal(t, "no changes\n", b.String())
+	b.Reset()
+	assert.NoError(t, diff.WriteJSON(&b))
+	assert.JSONEq(t, `{"changes": []}`, b.String())
+}
+
+func TestDiffEntityDefinitions_Compatible(t *testing.T) {
+	to := diffTestEntity()
+	to.Columns = append(to.Columns, &dosa.ColumnDefinition{Name: "nickname", Type: dosa.String})
+	to.Columns[2].Tags = map[string]string{"pii": "", "searchable": "true"}
+	other := &dosa.EntityDefinition{
+		Name:    "session",
+		Key:     &dosa.PrimaryKey{PartitionKeys: []string{"token"}},
+		Columns: []*dosa.ColumnDefinition{{Name: "token", Type: dosa.String}},
+	}
+
+	diff := dosa.DiffEntityDefinitions([]*dosa.EntityDefinition{diffTestEntity()}, []*dosa.EntityDefinition{other, to})
+	assert.False(t, diff.Breaking())
+	assert.Equal(t, []*dosa.SchemaChange{
+		{Entity: "session", Kind: dosa.EntityAdded, New: "(token)"},
+		{Entity: "user", Kind: dosa.TagChanged, Name: "email.searchable", New: `"true"`},
+		{Entity: "user", Kind: dosa.ColumnAdded, Name: "nickname", New: "String"},
+	}, diff.Changes)
+}
+
+func TestDiffEntityDefinitions_Breaking(t *testing.T) {
+	to := diffTestEntity()
+	to.Key = &dosa.PrimaryKey{
+		PartitionKeys:  []string{"id", "age"},
+		ClusteringKeys: []*dosa.ClusteringKey{{Name: "created"}},
+	}
+	to.Columns = []*dosa.ColumnDefinition{
+		{Name: "id", Type: dosa.TUUID},
+		{Name: "created", Type: dosa.Timestamp},
+		{Name: "age", Type: dosa.Int64},
+	}
+	removed := &dosa.EntityDefinition{
+		Name:    "audit",
+		Key:     &dosa.PrimaryKey{PartitionKeys: []string{"id"}},
+		Columns: []*dosa.ColumnDefinition{{Name: "id", Type: dosa.Int64}},
+	}
+
+	diff := dosa.DiffEntityDefinitions([]*dosa.EntityDefinition{diffTestEntity(), removed}, []*dosa.EntityDefinition{to})
+	assert.True(t, diff.Breaking())
+	assert.Equal(t, []*dosa.SchemaChange{
+		{Entity: "audit", Kind: dosa.EntityRemoved, Old: "(id)", Breaking: true},
+		{Entity: "user", Kind: dosa.PartitionKeyChanged, Old: "id", New: "id, age", Breaking: true},
+		{Entity: "user", Kind: dosa.ClusteringKeyChanged, Old: "created DESC", New: "created ASC", Breaking: true},
+		{Entity: "user", Kind: dosa.ColumnRetyped, Name: "age", Old: "Int32", New: "Int64", Breaking: true},
+		{Entity: "user", Kind: dosa.ColumnRemoved, Name: "email", Old: "String", Breaking: true},
+	}, diff.Changes)
+
+	var b bytes.Buffer
+	assert.NoError(t, diff.WriteText(&b))
+	assert.Equal(t, `breaking: audit: entity removed: (id)
+breaking: user: partition key changed: id -> id, age
+breaking: user: clustering key changed: created DESC -> created ASC
+breaking: user: column retyped age: Int32 -> Int64
+breaking: user: column removed email: String
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, diff.WriteJSON(&b))
+	var decoded dosa.SchemaDiff
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, diff, &decoded)
+	assert.Contains(t, b.String(), `"kind": "column retyped"`)
+}
+
+func TestDiffEntityDefinitions_Tags(t *testing.T) {
+	to := diffTestEntity()
+	to.Columns[2].Tags = map[string]string{"owner": "team"}
+
+	diff := dosa.DiffEntityDefinitions([]*dosa.EntityDefinition{diffTestEntity()}, []*dosa.EntityDefinition{to})
+	assert.Equal(t, []*dosa.SchemaChange{
+		{Entity: "user", Kind: dosa.TagChanged, Name: "email.owner", New: `"team"`},
+		{Entity: "user", Kind: dosa.TagChanged, Name: "email.pii", Old: "set"},
+	}, diff.Changes)
+	assert.Equal(t, "compatible: user: tag changed email.pii: set -> none", diff.Changes[1].String())
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LintSeverity is how serious a lint finding is
+type LintSeverity string
+
+// The lint severities; only errors make a lint fail by default
+const (
+	LintOff     LintSeverity = "off"
+	LintWarning LintSeverity = "warning"
+	LintError   LintSeverity = "error"
+)
+
+// ParseLintSeverity returns the severity of a name, as in rule flags
+func ParseLintSeverity(s string) (LintSeverity, error) {
+	switch severity := LintSeverity(strings.ToLower(s)); severity {
+	case LintOff, LintWarning, LintError:
+		return severity, nil
+	}
+	return "", errors.Errorf("invalid severity %q, must be off, warning or error", s)
+}
+
+// LintRule is a check of an entity definition for a risky design
+type LintRule struct {
+	Name        string
+	Description string
+	Severity    LintSeverity // default severity
+	check       func(e *EntityDefinition) []string
+}
+
+const (
+	// lintNameMargin is how close to maxNameLen a name must be to be flagged
+	lintNameMargin = 4
+	// lintMaxColumns is the number of columns above which an entity is flagged
+	lintMaxColumns = 50
+)
+
+// lowCardinalityWords are name parts of columns with few distinct values
+var lowCardinalityWords = map[string]bool{
+	"active": true, "category": true, "country": true, "deleted": true,
+	"enabled": true, "flag": true, "gender": true, "kind": true,
+	"state": true, "status": true, "type": true,
+}
+
+// timeSeriesWords are name parts of entities holding series of events
+var timeSeriesWords = map[string]bool{
+	"activity": true, "audit": true, "event": true, "events": true,
+	"history": true, "log": true, "logs": true, "metric": true,
+	"metrics": true, "timeline": true,
+}
+
+// hasNamePart checks if any underscore separated part of a name is a word
+func hasNamePart(name string, words map[string]bool) bool {
+	for _, part := range strings.Split(name, "_") {
+		if words[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// LintRules are all the lint rules, in the order they are checked
+var LintRules = []*LintRule{
+	{
+		Name:        "bool-partition",
+		Description: "partition keys made only of booleans put all the rows in at most a few partitions",
+		Severity:    LintError,
+		check: func(e *EntityDefinition) []string {
+			types := e.ColumnTypes()
+			for _, pk := range e.Key.PartitionKeys {
+				if types[pk] != Bool {
+					return nil
+				}
+			}
+			return []string{fmt.Sprintf("partition key %s only has booleans", formatPartitionKeys(e.Key.PartitionKeys))}
+		},
+	},
+	{
+		// the names only hint at the values, so this rule is not as sure as bool-partition
+		Name:        "low-cardinality-partition",
+		Description: "partition keys made only of status-like columns, and booleans, put all the rows in a few partitions",
+		Severity:    LintWarning,
+		check: func(e *EntityDefinition) []string {
+			types := e.ColumnTypes()
+			named := false
+			for _, pk := range e.Key.PartitionKeys {
+				if types[pk] == Bool {
+					continue
+				}
+				if !hasNamePart(pk, lowCardinalityWords) {
+					return nil
+				}
+				named = true
+			}
+			if !named {
+				// booleans only are flagged by bool-partition
+				return nil
+			}
+			return []string{fmt.Sprintf("partition key %s has few distinct values", formatPartitionKeys(e.Key.PartitionKeys))}
+		},
+	},
+	{
+		Name:        "time-series-clustering",
+		Description: "entities that look like time series need clustering keys to keep more than one row per partition",
+		Severity:    LintWarning,
+		check: func(e *EntityDefinition) []string {
+			if len(e.Key.ClusteringKeys) > 0 || !hasNamePart(e.Name, timeSeriesWords) {
+				return nil
+			}
+			for _, c := range e.Columns {
+				if c.Type == Timestamp {
+					return []string{fmt.Sprintf("looks like a time series of %s but has no clustering key", c.Name)}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "ascending-time-clustering",
+		Description: "timestamp and date clustering keys are usually read newest first, and should be DESC",
+		Severity:    LintWarning,
+		check: func(e *EntityDefinition) []string {
+			var messages []string
+			types := e.ColumnTypes()
+			for _, ck := range e.Key.ClusteringKeys {
+				if t := types[ck.Name]; (t == Timestamp || t == TDate) && !ck.Descending {
+					messages = append(messages, fmt.Sprintf("clustering key %s is not descending", ck.Name))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		Name:        "long-name",
+		Description: fmt.Sprintf("names within %d characters of the %d character limit leave no room for renames", lintNameMargin, maxNameLen),
+		Severity:    LintWarning,
+		check: func(e *EntityDefinition) []string {
+			var messages []string
+			if len(e.Name) > maxNameLen-lintNameMargin {
+				messages = append(messages, fmt.Sprintf("entity name has %d characters", len(e.Name)))
+			}
+			for _, c := range e.Columns {
+				if len(c.Name) > maxNameLen-lintNameMargin {
+					messages = append(messages, fmt.Sprintf("column name %s has %d characters", c.Name, len(c.Name)))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		Name:        "blob-key",
+		Description: "blob keys are compared byte by byte and are hard to query",
+		Severity:    LintError,
+		check: func(e *EntityDefinition) []string {
+			var messages []string
+			types := e.ColumnTypes()
+			for _, pk := range e.Key.PartitionKeys {
+				if types[pk] == Blob {
+					messages = append(messages, fmt.Sprintf("partition key %s is a blob", pk))
+				}
+			}
+			for _, ck := range e.Key.ClusteringKeys {
+				if types[ck.Name] == Blob {
+					messages = append(messages, fmt.Sprintf("clustering key %s is a blob", ck.Name))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		Name:        "many-columns",
+		Description: fmt.Sprintf("entities with more than %d columns are better split", lintMaxColumns),
+		Severity:    LintWarning,
+		check: func(e *EntityDefinition) []string {
+			if len(e.Columns) > lintMaxColumns {
+				return []string{fmt.Sprintf("has %d columns", len(e.Columns))}
+			}
+			return nil
+		},
+	},
+}
+
+// LintFinding is a risky design found by a lint rule
+type LintFinding struct {
+	Entity   string       `json:"entity"`
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	Message  string       `json:"message"`
+}
+
+// String describes the finding on one line, e.g.
+// "error: user: blob-key: partition key photo is a blob"
+func (f *LintFinding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", f.Severity, f.Entity, f.Rule, f.Message)
+}
+
+// LintReport lists the findings of all the rules, ordered by entity
+type LintReport struct {
+	Findings []*LintFinding `json:"findings"`
+}
+
+// Count returns the number of findings of a severity
+func (r *LintReport) Count(severity LintSeverity) int {
+	count := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteText writes the findings one per line, or a single line saying
+// there are none
+func (r *LintReport) WriteText(w io.Writer) error {
+	if len(r.Findings) == 0 {
+		_, err := fmt.Fprintln(w, "no findings")
+		return err
+	}
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintln(w, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the findings as an indented JSON object
+func (r *LintReport) WriteJSON(w io.Writer) error {
+	findings := r.Findings
+	if findings == nil {
+		findings = []*LintFinding{}
+	}
+	data, err := json.MarshalIndent(&LintReport{Findings: findings}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode lint report")
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// LintEntityDefinitions checks entity definitions with all the lint rules.
+// Severities override the default severity of rules by name, and rules
+// turned off are skipped.
+func LintEntityDefinitions(eds []*EntityDefinition, severities map[string]LintSeverity) (*LintReport, error) {
+	known := make(map[string]bool, len(LintRules))
+	for _, rule := range LintRules {
+		known[rule.Name] = true
+	}
+	for name := range severities {
+		if !known[name] {
+			return nil, errors.Errorf("unknown lint rule %q", name)
+		}
+	}
+
+	report := &LintReport{}
+	for _, e := range eds {
+		if err := e.EnsureValid(); err != nil {
+			return nil, errors.Wrapf(err, "invalid entity definition %q", e.Name)
+		}
+		for _, rule := range LintRules {
+			severity, ok := severities[rule.Name]
+			if !ok {
+				severity = rule.Severity
+			}
+			if severity == LintOff {
+				continue
+			}
+			for _, message := range rule.check(e) {
+				report.Findings = append(report.Findings, &LintFinding{Entity: e.Name, Rule: rule.Name, Severity: severity, Message: message})
+			}
+		}
+	}
+	return report, nil
+}
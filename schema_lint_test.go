@@ -0,0 +1,196 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func lintTestEntity() *dosa.EntityDefinition {
+	return &dosa.EntityDefinition{
+		Name: "user",
+		Key: &dosa.PrimaryKey{
+			PartitionKeys:  []string{"id"},
+			ClusteringKeys: []*dosa.ClusteringKey{{Name: "created", Descending: true}},
+		},
+		Columns: []*dosa.ColumnDefinition{
+			{Name: "id", Type: dosa.TUUID},
+			{Name: "created", Type: dosa.Timestamp},
+			{Name: "status", Type: dosa.String},
+			{Name: "photo", Type: dosa.Blob},
+		},
+	}
+}
+
+func TestLintEntityDefinitions_Clean(t *testing.T) {
+	report, err := dosa.LintEntityDefinitions([]*dosa.EntityDefinition{lintTestEntity()}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Findings)
+}
+
+func TestLintEntityDefinitions_Rules(t *testing.T) {
+	data := []struct {
+		change   func(e *dosa.EntityDefinition)
+		findings []string
+	}{
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Key.PartitionKeys = []string{"status"}
+			},
+			findings: []string{"warning: user: low-cardinality-partition: partition key status has few distinct values"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Columns = append(e.Columns, &dosa.ColumnDefinition{Name: "deleted", Type: dosa.Bool})
+				e.Key.PartitionKeys = []string{"deleted", "status"}
+			},
+			findings: []string{"warning: user: low-cardinality-partition: partition key (deleted, status) has few distinct values"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Columns = append(e.Columns, &dosa.ColumnDefinition{Name: "archived", Type: dosa.Bool})
+				e.Key.PartitionKeys = []string{"archived"}
+			},
+			findings: []string{"error: user: bool-partition: partition key archived only has booleans"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Name = "user_events"
+				e.Key.ClusteringKeys = nil
+			},
+			findings: []string{"warning: user_events: time-series-clustering: looks like a time series of created but has no clustering key"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Key.ClusteringKeys[0].Descending = false
+			},
+			findings: []string{"warning: user: ascending-time-clustering: clustering key created is not descending"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Name = strings.Repeat("u", 29)
+				e.Columns[3].Name = strings.Repeat("p", 32)
+			},
+			findings: []string{
+				"warning: " + strings.Repeat("u", 29) + ": long-name: entity name has 29 characters",
+				"warning: " + strings.Repeat("u", 29) + ": long-name: column name " + strings.Repeat("p", 32) + " has 32 characters",
+			},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Key.PartitionKeys = []string{"photo"}
+				e.Key.ClusteringKeys = append(e.Key.ClusteringKeys, &dosa.ClusteringKey{Name: "id"})
+			},
+			findings: []string{"error: user: blob-key: partition key photo is a blob"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				e.Key.ClusteringKeys = append(e.Key.ClusteringKeys, &dosa.ClusteringKey{Name: "photo", Descending: true})
+			},
+			findings: []string{"error: user: blob-key: clustering key photo is a blob"},
+		},
+		{
+			change: func(e *dosa.EntityDefinition) {
+				for i := 0; i < 50; i++ {
+					e.Columns = append(e.Columns, &dosa.ColumnDefinition{Name: fmt.Sprintf("col%d", i), Type: dosa.Int64})
+				}
+			},
+			findings: []string{"warning: user: many-columns: has 54 columns"},
+		},
+	}
+	for _, d := range data {
+		e := lintTestEntity()
+		d.change(e)
+		report, err := dosa.LintEntityDefinitions([]*dosa.EntityDefinition{e}, nil)
+		assert.NoError(t, err)
+		var findings []string
+		for _, f := range report.Findings {
+			findings = append(findings, f.String())
+		}
+		assert.Equal(t, d.findings, findings)
+	}
+}
+
+func TestLintEntityDefinitions_Severities(t *testing.T) {
+	e := lintTestEntity()
+	e.Key.PartitionKeys = []string{"photo"}
+	e.Key.ClusteringKeys[0].Descending = false
+
+	report, err := dosa.LintEntityDefinitions([]*dosa.EntityDefinition{e}, map[string]dosa.LintSeverity{
+		"blob-key":                  dosa.LintOff,
+		"ascending-time-clustering": dosa.LintError,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*dosa.LintFinding{
+		{Entity: "user", Rule: "ascending-time-clustering", Severity: dosa.LintError, Message: "clustering key created is not descending"},
+	}, report.Findings)
+	assert.Equal(t, 1, report.Count(dosa.LintError))
+	assert.Equal(t, 0, report.Count(dosa.LintWarning))
+}
+
+func TestLintEntityDefinitions_Errors(t *testing.T) {
+	_, err := dosa.LintEntityDefinitions(nil, map[string]dosa.LintSeverity{"nope": dosa.LintOff})
+	assert.EqualError(t, err, `unknown lint rule "nope"`)
+
+	_, err = dosa.LintEntityDefinitions([]*dosa.EntityDefinition{{Name: "empty"}}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `invalid entity definition "empty"`)
+	}
+}
+
+func TestParseLintSeverity(t *testing.T) {
+	for _, s := range []string{"off", "warning", "ERROR"} {
+		severity, err := dosa.ParseLintSeverity(s)
+		assert.NoError(t, err)
+		assert.Equal(t, dosa.LintSeverity(strings.ToLower(s)), severity)
+	}
+	_, err := dosa.ParseLintSeverity("fatal")
+	assert.EqualError(t, err, `invalid severity "fatal", must be off, warning or error`)
+}
+
+func TestLintReport_Write(t *testing.T) {
+	var buf bytes.Buffer
+	report := &dosa.LintReport{}
+	assert.NoError(t, report.WriteText(&buf))
+	assert.Equal(t, "no findings\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, report.WriteJSON(&buf))
+	assert.JSONEq(t, `{"findings": []}`, buf.String())
+
+	report.Findings = []*dosa.LintFinding{{Entity: "user", Rule: "blob-key", Severity: dosa.LintError, Message: "partition key photo is a blob"}}
+	buf.Reset()
+	assert.NoError(t, report.WriteText(&buf))
+	assert.Equal(t, "error: user: blob-key: partition key photo is a blob\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, report.WriteJSON(&buf))
+	var decoded dosa.LintReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report.Findings, decoded.Findings)
+}
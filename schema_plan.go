@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// operationStages orders the operations of a plan: additions come first,
+// so that nothing is removed before its replacement exists
+var operationStages = map[SchemaChangeKind]int{
+	EntityAdded:          0,
+	ColumnAdded:          1,
+	TagChanged:           2,
+	ColumnRetyped:        3,
+	PartitionKeyChanged:  4,
+	ClusteringKeyChanged: 4,
+	ColumnRemoved:        5,
+	EntityRemoved:        6,
+}
+
+// byStage sorts operations by stage, keeping the entity order within a stage
+type byStage []*SchemaChange
+
+func (s byStage) Len() int           { return len(s) }
+func (s byStage) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byStage) Less(i, j int) bool { return operationStages[s[i].Kind] < operationStages[s[j].Kind] }
+
+// SchemaPlan is the ordered list of operations that upserting entity
+// definitions would perform on the stored schema of a scope and name
+// prefix. Plans are written as JSON to be reviewed, then applied as is.
+type SchemaPlan struct {
+	Scope         string              `json:"scope"`
+	NamePrefix    string              `json:"namePrefix"`
+	AllowBreaking bool                `json:"allowBreaking"`
+	Operations    []*SchemaChange     `json:"operations"`
+	Entities      []*EntityDefinition `json:"entities"`
+}
+
+// PlanSchema plans the operations going from the stored entity definitions
+// of a scope and name prefix to the given ones
+func PlanSchema(scope, namePrefix string, stored, eds []*EntityDefinition) *SchemaPlan {
+	operations := DiffEntityDefinitions(stored, eds).Changes
+	sort.Stable(byStage(operations))
+	return &SchemaPlan{
+		Scope:      scope,
+		NamePrefix: namePrefix,
+		Operations: operations,
+		Entities:   eds,
+	}
+}
+
+// Breaking returns true if any operation is a breaking change
+func (p *SchemaPlan) Breaking() bool {
+	return (&SchemaDiff{Changes: p.Operations}).Breaking()
+}
+
+// Verify checks that the plan can be applied to the stored entity
+// definitions: they must be the ones the plan was made from, and breaking
+// operations must have been allowed
+func (p *SchemaPlan) Verify(stored []*EntityDefinition) error {
+	if p.Breaking() && !p.AllowBreaking {
+		return errors.New("the plan has breaking changes, and was not made with --allow-breaking")
+	}
+	current := PlanSchema(p.Scope, p.NamePrefix, stored, p.Entities)
+	if len(current.Operations) != len(p.Operations) {
+		return errors.Errorf("the stored schema changed since the plan was made: it now needs %d operations instead of %d", len(current.Operations), len(p.Operations))
+	}
+	for i, op := range current.Operations {
+		if op.String() != p.Operations[i].String() {
+			return errors.Errorf("the stored schema changed since the plan was made: operation %d is now %q instead of %q", i+1, op, p.Operations[i])
+		}
+	}
+	return nil
+}
+
+// WriteText writes the numbered operations, one per line, or a single line
+// saying there is nothing to do
+func (p *SchemaPlan) WriteText(w io.Writer) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "plan for prefix %q in scope %q:\n", p.NamePrefix, p.Scope)
+	if len(p.Operations) == 0 {
+		b.WriteString("no changes\n")
+	}
+	for i, op := range p.Operations {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, op)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteJSON writes the plan as an indented JSON object, read by ReadSchemaPlan
+func (p *SchemaPlan) WriteJSON(w io.Writer) error {
+	plan := *p
+	if plan.Operations == nil {
+		plan.Operations = []*SchemaChange{}
+	}
+	data, err := json.MarshalIndent(&plan, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode schema plan")
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// ReadSchemaPlan reads a plan written by WriteJSON
+func ReadSchemaPlan(r io.Reader) (*SchemaPlan, error) {
+	var plan SchemaPlan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, errors.Wrap(err, "could not decode schema plan")
+	}
+	if len(plan.Entities) == 0 {
+		return nil, errors.New("the schema plan has no entities")
+	}
+	for _, ed := range plan.Entities {
+		if err := ed.EnsureValid(); err != nil {
+			return nil, errors.Wrapf(err, "invalid entity definition %q in schema plan", ed.Name)
+		}
+	}
+	return &plan, nil
+}
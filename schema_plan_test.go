@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/dosa"
+)
+
+func TestPlanSchema_Order(t *testing.T) {
+	to := diffTestEntity()
+	to.Columns = append(to.Columns[:3:3], &dosa.ColumnDefinition{Name: "nickname", Type: dosa.String})
+	session := &dosa.EntityDefinition{
+		Name:    "session",
+		Key:     &dosa.PrimaryKey{PartitionKeys: []string{"token"}},
+		Columns: []*dosa.ColumnDefinition{{Name: "token", Type: dosa.String}},
+	}
+
+	plan := dosa.PlanSchema("scope", "prefix", []*dosa.EntityDefinition{diffTestEntity()}, []*dosa.EntityDefinition{to, session})
+	assert.Equal(t, "scope", plan.Scope)
+	assert.Equal(t, "prefix", plan.NamePrefix)
+	assert.Equal(t, []*dosa.EntityDefinition{to, session}, plan.Entities)
+	assert.True(t, plan.Breaking())
+
+	// additions come before removals
+	var b bytes.Buffer
+	assert.NoError(t, plan.WriteText(&b))
+	assert.Equal(t, `plan for prefix "prefix" in scope "scope":
+1. compatible: session: entity added: (token)
+2. compatible: user: column added nickname: String
+3. breaking: user: column removed age: Int32
+`, b.String())
+}
+
+func TestPlanSchema_NoChanges(t *testing.T) {
+	eds := []*dosa.EntityDefinition{diffTestEntity()}
+	plan := dosa.PlanSchema("scope", "prefix", eds, eds)
+	assert.Empty(t, plan.Operations)
+	assert.False(t, plan.Breaking())
+	assert.NoError(t, plan.Verify(eds))
+
+	var b bytes.Buffer
+	assert.NoError(t, plan.WriteText(&b))
+	assert.Equal(t, "plan for prefix \"prefix\" in scope \"scope\":\nno changes\n", b.String())
+}
+
+func TestSchemaPlan_Verify(t *testing.T) {
+	to := diffTestEntity()
+	to.Columns = to.Columns[:3]
+	stored := []*dosa.EntityDefinition{diffTestEntity()}
+	plan := dosa.PlanSchema("scope", "prefix", stored, []*dosa.EntityDefinition{to})
+
+	err := plan.Verify(stored)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not made with --allow-breaking")
+	}
+	plan.AllowBreaking = true
+	assert.NoError(t, plan.Verify(stored))
+
+	// the column was removed by someone else since
+	err = plan.Verify([]*dosa.EntityDefinition{to})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "it now needs 0 operations instead of 1")
+	}
+
+	// the column was retyped by someone else since
+	retyped := diffTestEntity()
+	retyped.Columns[3].Type = dosa.Int64
+	err = plan.Verify([]*dosa.EntityDefinition{retyped})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `operation 1 is now "breaking: user: column removed age: Int64" instead of "breaking: user: column removed age: Int32"`)
+	}
+}
+
+func TestSchemaPlan_ReadWrite(t *testing.T) {
+	plan := dosa.PlanSchema("scope", "prefix", nil, []*dosa.EntityDefinition{diffTestEntity()})
+	plan.AllowBreaking = true
+	var b bytes.Buffer
+	assert.NoError(t, plan.WriteJSON(&b))
+	read, err := dosa.ReadSchemaPlan(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, plan, read)
+	assert.NoError(t, read.Verify(nil))
+
+	data := []struct {
+		json   string
+		errmsg string
+	}{
+		{`[]`, "could not decode schema plan"},
+		{`{"scope": "scope", "namePrefix": "prefix"}`, "the schema plan has no entities"},
+		{`{"entities": [{"Name": "user"}]}`, `invalid entity definition "user" in schema plan`},
+	}
+	for _, d := range data {
+		_, err := dosa.ReadSchemaPlan(bytes.NewBufferString(d.json))
+		if assert.Error(t, err, d.json) {
+			assert.Contains(t, err.Error(), d.errmsg)
+		}
+	}
+}
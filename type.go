@@ -21,13 +21,20 @@
 package dosa
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 )
 
-//go:generate stringer -type=Type
+// The generated String method only names the primitive types, so it is renamed
+// for Type.String to name collection types as well.
+//go:generate stringer -type=Type -linecomment
+//go:generate perl -pi -e "s/func \\(i Type\\) String\\(\\)/func (i Type) primitiveString()/" type_string.go
 
-// Type defines a data type for an entity field
+// Type defines a data type for an entity field. Besides the primitive types
+// below, a Type can be a list, set or map of primitive types; see ListOf,
+// SetOf and MapOf.
 type Type int
 
 const (
@@ -57,6 +64,15 @@ const (
 
 	// Bool is a bool type
 	Bool
+
+	// Counter is a CounterValue, which can only be changed by Increment
+	Counter
+
+	// TDecimal is a Decimal, different from Double
+	TDecimal // Decimal
+
+	// TDate is a Date
+	TDate // Date
 )
 
 // UUID stores a string format of uuid.
@@ -87,26 +103,58 @@ func BytesToUUID(bs []byte) (UUID, error) {
 	return UUID(id.String()), nil
 }
 
+// CounterValue holds the value of a Counter column. Counter columns cannot be
+// part of a primary key, and cannot be mixed with other non-key columns.
+type CounterValue int64
+
+// String returns the name of a type, such as Int64 or Map<String,Int64>
+func (t Type) String() string {
+	switch {
+	case t.IsList():
+		return "List<" + t.Elem().String() + ">"
+	case t.IsSet():
+		return "Set<" + t.Elem().String() + ">"
+	case t.IsMap():
+		return "Map<" + t.Key().String() + "," + t.Elem().String() + ">"
+	}
+	return t.primitiveString()
+}
+
 // FromString converts string to dosa Type
 func FromString(s string) Type {
-	switch s {
-	case TUUID.String():
-		return TUUID
-	case String.String():
-		return String
-	case Int32.String():
-		return Int32
-	case Int64.String():
-		return Int64
-	case Double.String():
-		return Double
-	case Blob.String():
-		return Blob
-	case Timestamp.String():
-		return Timestamp
-	case Bool.String():
-		return Bool
+	if t, ok := collectionFromString(s); ok {
+		return t
+	}
+	for t := TUUID; t.isPrimitive(); t++ {
+		if s == t.String() {
+			return t
+		}
+	}
+	return Invalid
+}
+
+// collectionFromString parses the names of collection types
+func collectionFromString(s string) (Type, bool) {
+	if !strings.HasSuffix(s, ">") {
+		return Invalid, false
+	}
+	var t Type
+	switch {
+	case strings.HasPrefix(s, "List<"):
+		t = ListOf(FromString(s[len("List<") : len(s)-1]))
+	case strings.HasPrefix(s, "Set<"):
+		t = SetOf(FromString(s[len("Set<") : len(s)-1]))
+	case strings.HasPrefix(s, "Map<"):
+		types := strings.Split(s[len("Map<"):len(s)-1], ",")
+		if len(types) != 2 {
+			return Invalid, false
+		}
+		t = MapOf(FromString(types[0]), FromString(types[1]))
 	default:
-		return Invalid
+		return Invalid, false
+	}
+	if !t.isValid() {
+		return Invalid, false
 	}
+	return t, true
 }
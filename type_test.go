@@ -93,6 +93,22 @@ func TestFromString(t *testing.T) {
 			input:    Bool.String(),
 			expected: Bool,
 		},
+		{
+			input:    Counter.String(),
+			expected: Counter,
+		},
+		{
+			input:    "Decimal",
+			expected: TDecimal,
+		},
+		{
+			input:    "Date",
+			expected: TDate,
+		},
+		{
+			input:    "List<Date>",
+			expected: ListOf(TDate),
+		},
 		{
 			input:    "invalid",
 			expected: Invalid,
@@ -0,0 +1,201 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ChangeType is the kind of mutation reported by a change event
+type ChangeType int
+
+const (
+	// Insert means a row was created
+	Insert ChangeType = iota + 1
+	// Update means an existing row was changed
+	Update
+	// Delete means a row was removed
+	Delete
+)
+
+// String satisfies the stringer interface
+func (t ChangeType) String() string {
+	switch t {
+	case Insert:
+		return "Insert"
+	case Update:
+		return "Update"
+	case Delete:
+		return "Delete"
+	}
+	return fmt.Sprintf("ChangeType(%d)", t)
+}
+
+// defaultWatchBufferSize is the number of changes queued for a watcher when
+// no buffer size is provided
+const defaultWatchBufferSize = 128
+
+// WatchOptions configures a watch
+type WatchOptions struct {
+	// BufferSize is the number of changes that can be queued for a watcher that
+	// falls behind. The watch fails once the buffer overflows. Defaults to 128.
+	BufferSize int
+}
+
+// ErrWatchOverflow is reported when a watcher falls too far behind the changes made
+type ErrWatchOverflow struct{}
+
+// Error returns a constant string for this error
+func (*ErrWatchOverflow) Error() string {
+	return "watch buffer overflowed"
+}
+
+// ErrorIsWatchOverflow checks if the error is caused by "ErrWatchOverflow"
+func ErrorIsWatchOverflow(err error) bool {
+	_, ok := errors.Cause(err).(*ErrWatchOverflow)
+	return ok
+}
+
+// RowChange is a change made to a row, as reported by a connector
+type RowChange struct {
+	Type ChangeType
+	// Before holds the row before the change. It is nil for inserts, and may
+	// only hold the primary key when the connector could not read the row.
+	Before map[string]FieldValue
+	// After holds the row after the change. It is nil for deletes.
+	After map[string]FieldValue
+	// Err is set on the last change delivered by a watch that failed
+	Err error
+}
+
+// ChangeEvent is a change made to an entity, as delivered by Client.Watch
+type ChangeEvent struct {
+	Type ChangeType
+	// Before holds the entity before the change. It is nil for inserts, and may
+	// only hold the primary key when the connector could not read the entity.
+	Before DomainObject
+	// After holds the entity after the change. It is nil for deletes.
+	After DomainObject
+	// Err is set on the last event delivered by a watch that failed, and on
+	// the events whose values cannot be set on an entity
+	Err error
+}
+
+// Watcher is implemented by connectors that can stream the changes made to the
+// rows of an entity. Changes are delivered on the returned channel, which is
+// closed once the context is done or the watch fails.
+type Watcher interface {
+	Watch(ctx context.Context, ei *EntityInfo, opts *WatchOptions) (<-chan *RowChange, error)
+}
+
+// watchKey identifies the rows of an entity
+type watchKey struct {
+	scope      string
+	namePrefix string
+	entityName string
+}
+
+func watchKeyOf(ei *EntityInfo) watchKey {
+	return watchKey{scope: ei.Ref.Scope, namePrefix: ei.Ref.NamePrefix, entityName: ei.Def.Name}
+}
+
+type watch struct {
+	changes chan *RowChange
+	size    int
+}
+
+// ChangeFeed fans out the changes made to rows to their watchers. Connectors
+// implementing Watcher can use it to track their watches. Publishing never
+// blocks: a watcher that falls behind receives an ErrWatchOverflow and its
+// watch is closed. The zero value is ready to use, and it is safe for
+// concurrent use.
+type ChangeFeed struct {
+	lock     sync.Mutex
+	watchers map[watchKey]map[*watch]struct{}
+}
+
+// Watch registers a new watcher for the rows of an entity, until ctx is done
+func (f *ChangeFeed) Watch(ctx context.Context, ei *EntityInfo, opts *WatchOptions) (<-chan *RowChange, error) {
+	size := defaultWatchBufferSize
+	if opts != nil && opts.BufferSize > 0 {
+		size = opts.BufferSize
+	}
+	// one extra slot is kept for the overflow error
+	w := &watch{changes: make(chan *RowChange, size+1), size: size}
+	key := watchKeyOf(ei)
+
+	f.lock.Lock()
+	if f.watchers == nil {
+		f.watchers = make(map[watchKey]map[*watch]struct{})
+	}
+	if f.watchers[key] == nil {
+		f.watchers[key] = make(map[*watch]struct{})
+	}
+	f.watchers[key][w] = struct{}{}
+	f.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		f.remove(key, w)
+	}()
+	return w.changes, nil
+}
+
+// Watching returns true if there is at least one watcher for the rows of an
+// entity, so that callers can skip the work of building changes nobody sees
+func (f *ChangeFeed) Watching(ei *EntityInfo) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.watchers[watchKeyOf(ei)]) > 0
+}
+
+// Publish delivers a change made to a row of an entity to all of its watchers
+func (f *ChangeFeed) Publish(ei *EntityInfo, change *RowChange) {
+	key := watchKeyOf(ei)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for w := range f.watchers[key] {
+		if len(w.changes) >= w.size {
+			w.changes <- &RowChange{Err: errors.Wrapf(&ErrWatchOverflow{}, "more than %d changes pending", w.size)}
+			f.remove(key, w)
+			continue
+		}
+		w.changes <- change
+	}
+}
+
+// remove closes a watch, unless it was already closed; the lock must be held
+func (f *ChangeFeed) remove(key watchKey, w *watch) {
+	if _, ok := f.watchers[key][w]; !ok {
+		return
+	}
+	delete(f.watchers[key], w)
+	if len(f.watchers[key]) == 0 {
+		delete(f.watchers, key)
+	}
+	close(w.changes)
+}
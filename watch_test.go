@@ -0,0 +1,154 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dosa_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	dosaRenamed "github.com/uber-go/dosa"
+	"github.com/uber-go/dosa/connectors/memory"
+	"github.com/uber-go/dosa/mocks"
+)
+
+var watchEi = &dosaRenamed.EntityInfo{
+	Ref: &dosaRenamed.SchemaRef{Scope: "scope", NamePrefix: "prefix", EntityName: "e"},
+	Def: &dosaRenamed.EntityDefinition{Name: "e"},
+}
+
+func TestChangeType_String(t *testing.T) {
+	assert.Equal(t, "Insert", dosaRenamed.Insert.String())
+	assert.Equal(t, "Update", dosaRenamed.Update.String())
+	assert.Equal(t, "Delete", dosaRenamed.Delete.String())
+	assert.Equal(t, "ChangeType(42)", dosaRenamed.ChangeType(42).String())
+}
+
+func TestChangeFeed(t *testing.T) {
+	var feed dosaRenamed.ChangeFeed
+	assert.False(t, feed.Watching(watchEi))
+	// publishing without watchers is a no-op
+	feed.Publish(watchEi, &dosaRenamed.RowChange{Type: dosaRenamed.Insert})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := feed.Watch(ctx, watchEi, nil)
+	assert.NoError(t, err)
+	assert.True(t, feed.Watching(watchEi))
+	other := *watchEi
+	other.Def = &dosaRenamed.EntityDefinition{Name: "other"}
+	assert.False(t, feed.Watching(&other))
+
+	change := &dosaRenamed.RowChange{Type: dosaRenamed.Insert, After: map[string]dosaRenamed.FieldValue{"id": int64(1)}}
+	feed.Publish(watchEi, change)
+	feed.Publish(&other, &dosaRenamed.RowChange{Type: dosaRenamed.Delete})
+	assert.Equal(t, change, <-changes)
+
+	// cancelling the watch closes the channel
+	cancel()
+	select {
+	case _, ok := <-changes:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "watch not closed")
+	}
+	assert.False(t, feed.Watching(watchEi))
+}
+
+func TestChangeFeed_Overflow(t *testing.T) {
+	var feed dosaRenamed.ChangeFeed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := feed.Watch(ctx, watchEi, &dosaRenamed.WatchOptions{BufferSize: 2})
+	assert.NoError(t, err)
+	for x := 0; x < 5; x++ {
+		feed.Publish(watchEi, &dosaRenamed.RowChange{Type: dosaRenamed.Update})
+	}
+	assert.False(t, feed.Watching(watchEi))
+
+	var received []*dosaRenamed.RowChange
+	for change := range changes {
+		received = append(received, change)
+	}
+	assert.Len(t, received, 3)
+	assert.Equal(t, dosaRenamed.Update, received[1].Type)
+	assert.True(t, dosaRenamed.ErrorIsWatchOverflow(received[2].Err))
+	assert.False(t, dosaRenamed.ErrorIsWatchOverflow(nil))
+}
+
+func TestClient_Watch(t *testing.T) {
+	reg, err := dosaRenamed.NewRegistrar("test", "myteam.myservice", cte1)
+	assert.NoError(t, err)
+	conn := memory.NewConnector()
+	assert.NoError(t, conn.CreateScope(context.TODO(), "test"))
+	re, err := reg.Find(cte1)
+	assert.NoError(t, err)
+	_, err = conn.UpsertSchema(context.TODO(), "test", "myteam.myservice", []*dosaRenamed.EntityDefinition{re.EntityDefinition()})
+	assert.NoError(t, err)
+
+	c := dosaRenamed.NewClient(reg, conn)
+	_, err = c.Watch(context.TODO(), cte1, nil)
+	assert.IsType(t, &dosaRenamed.ErrNotInitialized{}, err)
+	assert.NoError(t, c.Initialize(context.TODO()))
+	_, err = c.Watch(context.TODO(), cte2, nil)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := c.Watch(ctx, &ClientTestEntity1{}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Upsert(ctx, dosaRenamed.All(), &ClientTestEntity1{ID: 1, Name: "before"}))
+	assert.NoError(t, c.Upsert(ctx, []string{"Name"}, &ClientTestEntity1{ID: 1, Name: "after"}))
+	assert.NoError(t, c.Remove(ctx, &ClientTestEntity1{ID: 1}))
+
+	event := <-events
+	assert.Equal(t, dosaRenamed.Insert, event.Type)
+	assert.Nil(t, event.Before)
+	assert.Equal(t, &ClientTestEntity1{ID: 1, Name: "before"}, event.After)
+	event = <-events
+	assert.Equal(t, dosaRenamed.Update, event.Type)
+	assert.Equal(t, &ClientTestEntity1{ID: 1, Name: "before"}, event.Before)
+	assert.Equal(t, &ClientTestEntity1{ID: 1, Name: "after"}, event.After)
+	event = <-events
+	assert.Equal(t, dosaRenamed.Delete, event.Type)
+	assert.Equal(t, &ClientTestEntity1{ID: 1, Name: "after"}, event.Before)
+	assert.Nil(t, event.After)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestClient_WatchUnsupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	reg, err := dosaRenamed.NewRegistrar("test", "myteam.myservice", cte1)
+	assert.NoError(t, err)
+	mockConn := mocks.NewMockConnector(ctrl)
+	mockConn.EXPECT().CheckSchema(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int32(1), nil)
+	c := dosaRenamed.NewClient(reg, mockConn)
+	assert.NoError(t, c.Initialize(context.TODO()))
+	_, err = c.Watch(context.TODO(), cte1, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "cannot watch")
+	}
+}